@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"kswi-backend/internal/config"
+	"kswi-backend/internal/model"
 	"kswi-backend/internal/router"
+	"kswi-backend/internal/worker"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,6 +27,33 @@ func main() {
 	// Get logger
 	logger := config.GetSugaredLogger()
 
+	// Run schema migrations for the tables owned by this service
+	if config.Get().Database.AutoMigrate {
+		if err := model.AutoMigrate(config.GetDB()); err != nil {
+			logger.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	// The shared oss_base table is only altered when explicitly allowed
+	if config.Get().Database.MigrateOssBase {
+		if err := model.MigrateOssBase(config.GetDB()); err != nil {
+			logger.Fatalf("Failed to migrate oss_base: %v", err)
+		}
+	}
+
+	// Make sure someone can manage roles on a fresh deployment
+	if username := config.Get().RBAC.BootstrapAdmin; username != "" {
+		if err := model.BootstrapAdmin(config.GetDB(), username); err != nil {
+			logger.Fatalf("Failed to bootstrap admin: %v", err)
+		}
+		logger.Infof("✅ Admin role granted to %s", username)
+	}
+
+	// Start background job workers
+	if err := worker.Init(); err != nil {
+		logger.Fatalf("Failed to start job workers: %v", err)
+	}
+
 	// Set Gin mode based on environment
 	if config.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
@@ -35,6 +64,12 @@ func main() {
 	// Setup router with all routes
 	ginRouter := router.SetupRouter()
 
+	// Resolve the jobs a previous run of this instance left unfinished, now
+	// that the modules registered their recovery hooks
+	if err := worker.Recover(context.Background()); err != nil {
+		logger.Warnf("⚠️  Failed to recover unfinished jobs: %v", err)
+	}
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         config.GetServerAddress(),
@@ -71,6 +106,15 @@ func main() {
 		logger.Errorf("Server forced to shutdown: %v", err)
 	}
 
+	// Let running jobs finish before their database connection goes away
+	workerCtx, workerCancel := context.WithTimeout(context.Background(),
+		time.Duration(config.Get().Worker.ShutdownTimeout)*time.Second)
+	defer workerCancel()
+
+	if err := worker.Shutdown(workerCtx); err != nil {
+		logger.Errorf("Background jobs forced to stop: %v", err)
+	}
+
 	// Shutdown application components
 	if err := config.ShutdownApp(); err != nil {
 		logger.Errorf("Error during application shutdown: %v", err)
@@ -0,0 +1,167 @@
+// Command menuctl exports the menu tree to a seed file and imports it back,
+// so menus can be versioned and applied the same way in every environment.
+//
+// Usage:
+//
+//	menuctl export [-format yaml|json] [-o menus.yaml]
+//	menuctl import [-dry-run] [-format yaml|json] menus.yaml
+//
+// An import clears the cached menu tree in Redis. Without Redis each process
+// caches the tree in memory, so running servers keep serving the old tree
+// until their cache expires, up to an hour, or they restart.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/modules/menu"
+	"kswi-backend/internal/shared/cache"
+	"kswi-backend/internal/shared/errors"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := config.InitApp(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize application: %v\n", err)
+		os.Exit(1)
+	}
+	defer config.ShutdownApp()
+
+	svc := menu.NewService(menu.NewRepository(config.GetDB()), cache.Get())
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(svc, os.Args[2:])
+	case "import":
+		err = runImport(svc, os.Args[2:])
+	default:
+		usage()
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		var appErr *errors.AppError
+		if errors.As(err, &appErr) {
+			if details, ok := appErr.Details.([]errors.ValidationError); ok {
+				for _, detail := range details {
+					fmt.Fprintf(os.Stderr, "  %s: %s\n", detail.Field, detail.Message)
+				}
+			}
+		}
+		config.ShutdownApp()
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  menuctl export [-format yaml|json] [-o file]")
+	fmt.Fprintln(os.Stderr, "  menuctl import [-dry-run] [-format yaml|json] file")
+}
+
+func runExport(svc menu.Service, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "", "seed format: yaml or json (default: from -o, else yaml)")
+	output := fs.String("o", "", "output file (default: stdout)")
+	_ = fs.Parse(args)
+
+	if *format == "" && *output == "" {
+		*format = menu.SeedFormatYAML
+	}
+
+	seed, warnings, err := svc.ExportMenus(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+
+	data, err := menu.EncodeMenuSeed(seed, menu.DetectSeedFormat(*format, *output, ""))
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d menus to %s\n", len(seed.Menus), *output)
+	return nil
+}
+
+func runImport(svc menu.Service, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "", "seed format: yaml or json (default: from the file extension)")
+	dryRun := fs.Bool("dry-run", false, "only print the changes")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("import expects exactly one seed file")
+	}
+	file := fs.Arg(0)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	seed, err := menu.DecodeMenuSeed(data, menu.DetectSeedFormat(*format, file, ""))
+	if err != nil {
+		return err
+	}
+
+	result, err := svc.ImportMenus(context.Background(), seed, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range result.Changes {
+		if change.Action == menu.SeedActionUnchanged {
+			continue
+		}
+		fmt.Printf("%-7s %s\n", change.Action, change.Code)
+		for _, field := range change.Fields {
+			fmt.Printf("          %s: %v -> %v\n", field.Field, describe(field.Old), describe(field.New))
+		}
+	}
+
+	prefix := ""
+	if result.DryRun {
+		prefix = "[dry run] "
+	}
+	fmt.Printf("%s%d created, %d updated, %d unchanged\n", prefix, result.Created, result.Updated, result.Unchanged)
+
+	if !result.DryRun && result.Created+result.Updated > 0 && !config.IsRedisEnabled() {
+		fmt.Fprintln(os.Stderr, "warning: Redis is not available, so running servers keep their cached menu tree for up to an hour; restart them to apply the import now")
+	}
+	return nil
+}
+
+func describe(v interface{}) string {
+	switch val := v.(type) {
+	case *string:
+		if val == nil {
+			return "<none>"
+		}
+		return fmt.Sprintf("%q", *val)
+	case string:
+		return fmt.Sprintf("%q", val)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
@@ -11,6 +11,9 @@ type AppConfig struct {
 	Version     string `mapstructure:"version"`
 	Environment string `mapstructure:"environment"`
 	Debug       bool   `mapstructure:"debug"`
+	// Timezone is the IANA zone reports are bucketed and displayed in;
+	// timestamps are stored in UTC
+	Timezone string `mapstructure:"timezone"`
 }
 
 // ServerConfig holds server-specific configuration
@@ -22,6 +25,25 @@ type ServerConfig struct {
 	IdleTimeout  int    `mapstructure:"idle_timeout"`
 }
 
+// WorkerConfig holds background job configuration
+type WorkerConfig struct {
+	Concurrency     int    `mapstructure:"concurrency"`
+	QueueSize       int    `mapstructure:"queue_size"`
+	ShutdownTimeout int    `mapstructure:"shutdown_timeout"`
+	TempDir         string `mapstructure:"temp_dir"`
+	// InstanceID names this process in the jobs it runs, so that after a
+	// restart it can resolve the jobs it left unfinished. It must be unique
+	// and stable per instance; empty means the hostname.
+	InstanceID string `mapstructure:"instance_id"`
+}
+
+// RBACConfig holds role-based access control configuration
+type RBACConfig struct {
+	// BootstrapAdmin is the username granted the admin role at startup, so
+	// a fresh deployment has someone able to assign roles
+	BootstrapAdmin string `mapstructure:"bootstrap_admin"`
+}
+
 // InitApp initializes the entire application
 func InitApp() error {
 	log.Println("🚀 Starting application initialization...")
@@ -45,11 +67,10 @@ func InitApp() error {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 
-	// 4. Initialize Redis
-	// if err := InitRedis(); err != nil {
-	// 	logger.Errorf("Failed to initialize redis: %v", err)
-	// 	return fmt.Errorf("failed to initialize redis: %w", err)
-	// }
+	// 4. Initialize Redis (optional: features fall back to in-memory stores)
+	if err := InitRedis(); err != nil {
+		logger.Warnf("⚠️  Redis unavailable, falling back to in-memory stores: %v", err)
+	}
 
 	// 5. Initialize JWT
 	if err := InitJWT(); err != nil {
@@ -1,6 +1,9 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type Config struct {
 	App      AppConfig      `mapstructure:"app"`
@@ -9,15 +12,25 @@ type Config struct {
 	JWT      JWTConfig      `mapstructure:"jwt"`
 	Server   ServerConfig   `mapstructure:"server"`
 	Log      LogConfig      `mapstructure:"log"`
+	Worker   WorkerConfig   `mapstructure:"worker"`
+	RBAC     RBACConfig     `mapstructure:"rbac"`
 }
 
 var cfg *Config
 
+// location is the loaded app.timezone
+var location = time.UTC
+
 // Get returns the global config instance
 func Get() *Config {
 	return cfg
 }
 
+// Location returns the display timezone set in app.timezone
+func Location() *time.Location {
+	return location
+}
+
 // IsProduction returns true if the environment is production
 func IsProduction() bool {
 	return cfg.App.Environment == "production"
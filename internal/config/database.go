@@ -23,6 +23,8 @@ type DatabaseConfig struct {
 	MaxOpenConns    int    `mapstructure:"max_open_conns"`
 	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
 	ConnMaxLifetime string `mapstructure:"conn_max_lifetime"` // Changed to string for duration parsing
+	AutoMigrate     bool   `mapstructure:"auto_migrate"`
+	MigrateOssBase  bool   `mapstructure:"migrate_oss_base"`
 }
 
 var db *gorm.DB
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// verificationKey is a public key accepted when validating tokens
+type verificationKey struct {
+	method    jwt.SigningMethod
+	publicKey crypto.PublicKey
+}
+
+// JWK is a single public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is the document published at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// loadKeys reads the configured PEM key files. The key matching ActiveKeyID
+// signs new tokens; every key is accepted for verification so that tokens
+// signed before a rotation stay valid until they expire.
+func (j *JWTManager) loadKeys(jwtConfig JWTConfig) error {
+	if len(jwtConfig.Keys) == 0 {
+		return fmt.Errorf("algorithm %s requires at least one key in jwt.keys", jwtConfig.Algorithm)
+	}
+
+	j.verificationKeys = make(map[string]verificationKey, len(jwtConfig.Keys))
+
+	for _, keyConfig := range jwtConfig.Keys {
+		if keyConfig.ID == "" {
+			return fmt.Errorf("every jwt key must have an id")
+		}
+		if _, exists := j.verificationKeys[keyConfig.ID]; exists {
+			return fmt.Errorf("duplicate jwt key id %q", keyConfig.ID)
+		}
+
+		algorithm := keyConfig.Algorithm
+		if algorithm == "" {
+			algorithm = jwtConfig.Algorithm
+		}
+
+		method, err := asymmetricSigningMethod(algorithm)
+		if err != nil {
+			return fmt.Errorf("key %q: %w", keyConfig.ID, err)
+		}
+
+		var privateKey crypto.Signer
+		if keyConfig.PrivateKeyFile != "" {
+			privateKey, err = readPrivateKey(method, keyConfig.PrivateKeyFile)
+			if err != nil {
+				return fmt.Errorf("key %q: %w", keyConfig.ID, err)
+			}
+		}
+
+		var publicKey crypto.PublicKey
+		switch {
+		case keyConfig.PublicKeyFile != "":
+			publicKey, err = readPublicKey(method, keyConfig.PublicKeyFile)
+			if err != nil {
+				return fmt.Errorf("key %q: %w", keyConfig.ID, err)
+			}
+		case privateKey != nil:
+			publicKey = privateKey.Public()
+		default:
+			return fmt.Errorf("key %q: a private or public key file is required", keyConfig.ID)
+		}
+
+		j.verificationKeys[keyConfig.ID] = verificationKey{method: method, publicKey: publicKey}
+
+		if keyConfig.ID == jwtConfig.ActiveKeyID {
+			if privateKey == nil {
+				return fmt.Errorf("active key %q has no private key file", keyConfig.ID)
+			}
+			j.signingMethod = method
+			j.signingKey = privateKey
+			j.signingKeyID = keyConfig.ID
+		}
+	}
+
+	if j.signingKey == nil {
+		return fmt.Errorf("active key %q is not configured in jwt.keys", jwtConfig.ActiveKeyID)
+	}
+
+	return nil
+}
+
+func asymmetricSigningMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch strings.ToUpper(algorithm) {
+	case "RS256":
+		return jwt.SigningMethodRS256, nil
+	case "EDDSA":
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+func readPrivateKey(method jwt.SigningMethod, path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	if method == jwt.SigningMethodRS256 {
+		return jwt.ParseRSAPrivateKeyFromPEM(data)
+	}
+
+	key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key in %s is not an Ed25519 key", path)
+	}
+	return signer, nil
+}
+
+func readPublicKey(method jwt.SigningMethod, path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	if method == jwt.SigningMethodRS256 {
+		return jwt.ParseRSAPublicKeyFromPEM(data)
+	}
+	return jwt.ParseEdPublicKeyFromPEM(data)
+}
+
+// JWKS returns the public verification keys. It is empty when tokens are
+// signed with the shared HS256 secret, which must never be published.
+func (j *JWTManager) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	for kid, key := range j.verificationKeys {
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+
+		switch publicKey := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(a, b int) bool {
+		return set.Keys[a].Kid < set.Keys[b].Kid
+	})
+
+	return set
+}
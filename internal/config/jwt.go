@@ -1,34 +1,60 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 // JWTConfig holds JWT-specific configuration
 type JWTConfig struct {
-	Secret          string `mapstructure:"secret"`
-	AccessTokenTTL  int    `mapstructure:"access_token_ttl"`
-	RefreshTokenTTL int    `mapstructure:"refresh_token_ttl"`
-	Issuer          string `mapstructure:"issuer"`
+	Secret          string         `mapstructure:"secret"`
+	AccessTokenTTL  int            `mapstructure:"access_token_ttl"`
+	RefreshTokenTTL int            `mapstructure:"refresh_token_ttl"`
+	Issuer          string         `mapstructure:"issuer"`
+	Algorithm       string         `mapstructure:"algorithm"`     // HS256, RS256 or EdDSA
+	ActiveKeyID     string         `mapstructure:"active_key_id"` // kid of the key used for signing
+	Keys            []JWTKeyConfig `mapstructure:"keys"`
+}
+
+// JWTKeyConfig describes one asymmetric key pair loaded from PEM files.
+// Keys without a private key file are only used to verify tokens.
+type JWTKeyConfig struct {
+	ID             string `mapstructure:"id"`
+	Algorithm      string `mapstructure:"algorithm"` // defaults to JWTConfig.Algorithm
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
 }
 
 type JWTManager struct {
-	secretKey       []byte
-	accessTokenTTL  time.Duration
-	refreshTokenTTL time.Duration
-	issuer          string
+	secretKey        []byte
+	signingMethod    jwt.SigningMethod
+	signingKey       interface{}
+	signingKeyID     string
+	verificationKeys map[string]verificationKey
+	accessTokenTTL   time.Duration
+	refreshTokenTTL  time.Duration
+	issuer           string
 }
 
 var jwtManager *JWTManager
 
+// Token types carried in the token_type claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
 // Claims represents JWT claims
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	TokenType string `json:"token_type"`
 	jwt.RegisteredClaims
 }
 
@@ -39,24 +65,37 @@ func InitJWT() error {
 	// Get JWT configuration
 	jwtConfig := cfg.JWT
 
-	// Validate JWT secret
-	if jwtConfig.Secret == "" || jwtConfig.Secret == "your-secret-key" {
-		if IsProduction() {
-			GetSugaredLogger().Error("JWT secret must be set in production environment")
-			return fmt.Errorf("JWT secret must be set in production environment")
-		}
-		GetSugaredLogger().Warn("⚠️  Warning: Using default JWT secret. Change this in production!")
-	}
-
-	jwtManager = &JWTManager{
+	manager := &JWTManager{
 		secretKey:       []byte(jwtConfig.Secret),
 		accessTokenTTL:  time.Duration(jwtConfig.AccessTokenTTL) * time.Second,
 		refreshTokenTTL: time.Duration(jwtConfig.RefreshTokenTTL) * time.Second,
 		issuer:          jwtConfig.Issuer,
 	}
 
-	GetSugaredLogger().Infof("✅ JWT manager initialized with TTL: access=%v, refresh=%v",
-		jwtManager.accessTokenTTL, jwtManager.refreshTokenTTL)
+	algorithm := strings.ToUpper(jwtConfig.Algorithm)
+	if algorithm == "" || algorithm == jwt.SigningMethodHS256.Alg() {
+		// Validate JWT secret
+		if jwtConfig.Secret == "" || jwtConfig.Secret == "your-secret-key" {
+			if IsProduction() {
+				GetSugaredLogger().Error("JWT secret must be set in production environment")
+				return fmt.Errorf("JWT secret must be set in production environment")
+			}
+			GetSugaredLogger().Warn("⚠️  Warning: Using default JWT secret. Change this in production!")
+		}
+
+		manager.signingMethod = jwt.SigningMethodHS256
+		manager.signingKey = manager.secretKey
+	} else {
+		if err := manager.loadKeys(jwtConfig); err != nil {
+			GetSugaredLogger().Errorf("Failed to load JWT keys: %v", err)
+			return fmt.Errorf("failed to load JWT keys: %w", err)
+		}
+	}
+
+	jwtManager = manager
+
+	GetSugaredLogger().Infof("✅ JWT manager initialized with %s (kid=%q), TTL: access=%v, refresh=%v",
+		jwtManager.signingMethod.Alg(), jwtManager.signingKeyID, jwtManager.accessTokenTTL, jwtManager.refreshTokenTTL)
 
 	return nil
 }
@@ -73,10 +112,12 @@ func GetJWTManager() *JWTManager {
 func (j *JWTManager) GenerateAccessToken(userID uint, username, role string) (string, error) {
 	now := time.Now()
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		TokenType: TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			Issuer:    j.issuer,
 			Subject:   fmt.Sprintf("%d", userID),
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.accessTokenTTL)),
@@ -85,8 +126,7 @@ func (j *JWTManager) GenerateAccessToken(userID uint, username, role string) (st
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(j.secretKey)
+	tokenString, err := j.sign(claims)
 	if err != nil {
 		GetSugaredLogger().Errorf("Failed to generate access token for user %d: %v", userID, err)
 		return "", fmt.Errorf("failed to generate access token: %w", err)
@@ -96,19 +136,23 @@ func (j *JWTManager) GenerateAccessToken(userID uint, username, role string) (st
 	return tokenString, nil
 }
 
-// GenerateRefreshToken generates a new refresh token
-func (j *JWTManager) GenerateRefreshToken(userID uint) (string, error) {
+// GenerateRefreshToken generates a new refresh token identified by tokenID (jti)
+func (j *JWTManager) GenerateRefreshToken(userID uint, tokenID string) (string, error) {
 	now := time.Now()
-	claims := jwt.RegisteredClaims{
-		Issuer:    j.issuer,
-		Subject:   fmt.Sprintf("%d", userID),
-		ExpiresAt: jwt.NewNumericDate(now.Add(j.refreshTokenTTL)),
-		NotBefore: jwt.NewNumericDate(now),
-		IssuedAt:  jwt.NewNumericDate(now),
+	claims := Claims{
+		UserID:    userID,
+		TokenType: TokenTypeRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    j.issuer,
+			Subject:   fmt.Sprintf("%d", userID),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.refreshTokenTTL)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(j.secretKey)
+	tokenString, err := j.sign(claims)
 	if err != nil {
 		GetSugaredLogger().Errorf("Failed to generate refresh token for user %d: %v", userID, err)
 		return "", fmt.Errorf("failed to generate refresh token: %w", err)
@@ -120,12 +164,7 @@ func (j *JWTManager) GenerateRefreshToken(userID uint) (string, error) {
 
 // ValidateToken validates and parses a JWT token
 func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return j.secretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, j.keyFunc)
 
 	if err != nil {
 		GetSugaredLogger().Debugf("Failed to parse token: %v", err)
@@ -141,6 +180,39 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
+// sign signs the claims with the active key, adding its kid to the header
+func (j *JWTManager) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(j.signingMethod, claims)
+	if j.signingKeyID != "" {
+		token.Header["kid"] = j.signingKeyID
+	}
+	return token.SignedString(j.signingKey)
+}
+
+// keyFunc selects the verification key for a token. With HS256 the shared
+// secret is used; otherwise the key is looked up by the kid header and must
+// match the algorithm of the token.
+func (j *JWTManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	if j.verificationKeys == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return j.secretKey, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.publicKey, nil
+}
+
 // GetTokenTTL returns the access token TTL
 func (j *JWTManager) GetTokenTTL() time.Duration {
 	return j.accessTokenTTL
@@ -150,3 +222,17 @@ func (j *JWTManager) GetTokenTTL() time.Duration {
 func (j *JWTManager) GetRefreshTokenTTL() time.Duration {
 	return j.refreshTokenTTL
 }
+
+// claimsContextKey is the context key under which authenticated claims are stored
+type claimsContextKey struct{}
+
+// ContextWithClaims returns a copy of ctx that carries the authenticated claims
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the authenticated claims stored in ctx, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
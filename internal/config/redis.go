@@ -47,6 +47,8 @@ func InitRedis() error {
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
 		GetSugaredLogger().Errorf("Failed to connect to redis: %v", err)
+		_ = rdb.Close()
+		rdb = nil
 		return fmt.Errorf("failed to connect to redis: %w", err)
 	}
 
@@ -62,6 +64,11 @@ func GetRedis() *redis.Client {
 	return rdb
 }
 
+// IsRedisEnabled returns true if a Redis connection was established
+func IsRedisEnabled() bool {
+	return rdb != nil
+}
+
 // GetRedisAddress returns the formatted Redis address
 func GetRedisAddress() string {
 	return fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port)
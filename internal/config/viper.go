@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
+	_ "time/tzdata" // app.timezone must load on hosts without zoneinfo
 
 	"github.com/spf13/viper"
 )
@@ -43,6 +45,12 @@ func InitViper() error {
 		return fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
+	loc, err := time.LoadLocation(cfg.App.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid app.timezone %q: %w", cfg.App.Timezone, err)
+	}
+	location = loc
+
 	return nil
 }
 
@@ -53,6 +61,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("app.version", "1.0.0")
 	v.SetDefault("app.environment", "development")
 	v.SetDefault("app.debug", true)
+	v.SetDefault("app.timezone", "Asia/Jakarta")
 
 	// Server defaults
 	v.SetDefault("server.host", "localhost")
@@ -73,6 +82,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("database.max_open_conns", 25)
 	v.SetDefault("database.max_idle_conns", 5)
 	v.SetDefault("database.conn_max_lifetime", 300)
+	v.SetDefault("database.auto_migrate", true)
+	v.SetDefault("database.migrate_oss_base", false)
 
 	// Redis defaults
 	v.SetDefault("redis.host", "localhost")
@@ -88,6 +99,17 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("jwt.access_token_ttl", 3600)    // 1 hour
 	v.SetDefault("jwt.refresh_token_ttl", 604800) // 7 days
 	v.SetDefault("jwt.issuer", "kswi-backend")
+	v.SetDefault("jwt.algorithm", "HS256")
+
+	// Worker defaults
+	v.SetDefault("worker.concurrency", 2)
+	v.SetDefault("worker.queue_size", 100)
+	v.SetDefault("worker.shutdown_timeout", 30)
+	v.SetDefault("worker.temp_dir", "")
+	v.SetDefault("worker.instance_id", "")
+
+	// RBAC defaults
+	v.SetDefault("rbac.bootstrap_admin", "")
 
 	// Log defaults
 	v.SetDefault("log.level", "info")
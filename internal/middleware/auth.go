@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/shared/denylist"
+	"kswi-backend/internal/shared/errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ClaimsKey = "claims"
+
+// AuthMiddleware requires a valid bearer access token and exposes its claims to the handlers
+func AuthMiddleware(jwtManager *config.JWTManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := authenticate(c, jwtManager)
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+
+		setClaims(c, claims)
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware exposes the caller's claims when a valid token is sent,
+// but lets anonymous requests through
+func OptionalAuthMiddleware(jwtManager *config.JWTManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			if claims, err := authenticate(c, jwtManager); err == nil {
+				setClaims(c, claims)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// authenticate extracts and validates the bearer token of the request
+func authenticate(c *gin.Context, jwtManager *config.JWTManager) (*config.Claims, error) {
+	header := c.GetHeader("Authorization")
+	if header == "" {
+		return nil, errors.NewAuthError("Missing authorization header")
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return nil, errors.NewAuthError("Authorization header must use the Bearer scheme")
+	}
+
+	claims, err := jwtManager.ValidateToken(strings.TrimSpace(token))
+	if err != nil {
+		return nil, errors.NewAppErrorWithOriginal(errors.TypeAuth, "Invalid or expired token", nil, err)
+	}
+
+	if claims.TokenType != config.TokenTypeAccess {
+		return nil, errors.NewAuthError("Invalid token type")
+	}
+
+	revoked, err := denylist.IsClaimsRevoked(c.Request.Context(), denylist.Get(), claims)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	if revoked {
+		return nil, errors.NewAuthError("Token has been revoked")
+	}
+
+	return claims, nil
+}
+
+// setClaims stores the claims in both the gin context and the request context
+func setClaims(c *gin.Context, claims *config.Claims) {
+	c.Set(ClaimsKey, claims)
+	c.Request = c.Request.WithContext(config.ContextWithClaims(c.Request.Context(), claims))
+}
+
+// GetClaims extracts the authenticated claims from gin context
+func GetClaims(c *gin.Context) (*config.Claims, bool) {
+	if value, exists := c.Get(ClaimsKey); exists {
+		if claims, ok := value.(*config.Claims); ok {
+			return claims, true
+		}
+	}
+	return nil, false
+}
+
+// GetClaimsFromContext extracts the authenticated claims from a standard context
+func GetClaimsFromContext(ctx context.Context) (*config.Claims, bool) {
+	return config.ClaimsFromContext(ctx)
+}
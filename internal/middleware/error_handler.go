@@ -23,13 +23,16 @@ func getLogLevel(appErr *errors.AppError, isProduction bool) string {
 		}
 		return "error"
 
+	case errors.TypeForbidden:
+		return "warn"
+
 	case errors.TypeNotFound:
 		if isProduction {
 			return "info" // 404s are usually not critical in production
 		}
 		return "warn"
 
-	case errors.TypeValidation:
+	case errors.TypeValidation, errors.TypeConflict:
 		if isProduction {
 			return "info" // User input errors are not system errors
 		}
@@ -141,6 +144,11 @@ func ErrorHandler(cfg *config.Config) gin.HandlerFunc {
 				"timestamp", time.Now().Format(time.RFC3339),
 			}
 
+			// Identify the caller when the request was authenticated
+			if claims, ok := GetClaims(c); ok {
+				baseFields = append(baseFields, "user_id", claims.UserID, "username", claims.Username)
+			}
+
 			if !isProduction {
 				// Development: Log with full details
 				allFields := append(baseFields,
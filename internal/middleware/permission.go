@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"kswi-backend/internal/shared/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const PermissionsKey = "permissions"
+
+// PermissionChecker resolves the permission codes granted to a user
+type PermissionChecker interface {
+	GetUserPermissions(ctx context.Context, userID uint) ([]string, error)
+}
+
+var permissionChecker PermissionChecker
+
+// SetPermissionChecker registers the checker used by RequirePermission
+func SetPermissionChecker(checker PermissionChecker) {
+	permissionChecker = checker
+}
+
+// RequirePermission allows the request only when the authenticated caller
+// holds every given permission. It must run after AuthMiddleware.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			_ = c.Error(errors.NewAuthError("Authentication required"))
+			c.Abort()
+			return
+		}
+
+		granted, err := loadPermissions(c, claims.UserID)
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if _, ok := granted[permission]; !ok {
+				_ = c.Error(errors.NewForbiddenErrorWithDetails(
+					"You do not have permission to perform this action",
+					gin.H{"required_permission": permission},
+				))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// GetPermissions returns the permissions of the caller loaded by RequirePermission
+func GetPermissions(c *gin.Context) map[string]struct{} {
+	if value, exists := c.Get(PermissionsKey); exists {
+		if permissions, ok := value.(map[string]struct{}); ok {
+			return permissions
+		}
+	}
+	return nil
+}
+
+// loadPermissions resolves the caller's permissions once per request
+func loadPermissions(c *gin.Context, userID uint) (map[string]struct{}, error) {
+	if permissions := GetPermissions(c); permissions != nil {
+		return permissions, nil
+	}
+
+	if permissionChecker == nil {
+		return nil, errors.NewInternalError(fmt.Errorf("permission checker not configured"))
+	}
+
+	codes, err := permissionChecker.GetUserPermissions(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]struct{}, len(codes))
+	for _, code := range codes {
+		permissions[code] = struct{}{}
+	}
+
+	c.Set(PermissionsKey, permissions)
+	return permissions, nil
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a background job
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a unit of background work such as an OSS import. Progress is
+// written while it runs so clients can poll it.
+type Job struct {
+	ID              string          `json:"id" gorm:"primaryKey;size:36"`
+	Type            string          `json:"type" gorm:"column:type;size:50;not null;index"`
+	Status          string          `json:"status" gorm:"column:status;size:20;not null;index"`
+	TotalCount      int             `json:"total_count" gorm:"column:total_count;not null;default:0"`
+	ProcessedCount  int             `json:"processed_count" gorm:"column:processed_count;not null;default:0"`
+	FailedCount     int             `json:"failed_count" gorm:"column:failed_count;not null;default:0"`
+	Error           *string         `json:"error" gorm:"column:error;type:text"`
+	Result          json.RawMessage `json:"result" gorm:"column:result;type:json"`
+	CancelRequested bool            `json:"cancel_requested" gorm:"column:cancel_requested;not null;default:false"`
+	CreatedBy       int             `json:"created_by" gorm:"column:created_by;not null;index"`
+	Instance        string          `json:"instance" gorm:"column:instance;size:100;not null;default:'';index"`
+	StartedAt       *time.Time      `json:"started_at" gorm:"column:started_at"`
+	FinishedAt      *time.Time      `json:"finished_at" gorm:"column:finished_at"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
+
+// IsFinished reports whether the job reached a final status
+func (j *Job) IsFinished() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed || j.Status == JobCancelled
+}
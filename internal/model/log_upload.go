@@ -0,0 +1,74 @@
+package model
+
+import "time"
+
+// Statuses of an upload
+const (
+	LogUploadQueued      = "queued"
+	LogUploadProcessing  = "processing"
+	LogUploadCompleted   = "completed"
+	LogUploadFailed      = "failed"
+	LogUploadCancelled   = "cancelled"
+	LogUploadRollingBack = "rolling_back"
+	LogUploadRolledBack  = "rolled_back"
+)
+
+// Keys used to match uploaded rows to existing oss_base rows
+const (
+	MatchIDProyek    = "idProyek"
+	MatchIDProyekNIB = "idProyek+nib"
+)
+
+// Rollback modes: soft marks the rows created by the upload as deleted, hard
+// removes them
+const (
+	RollbackSoft = "soft"
+	RollbackHard = "hard"
+)
+
+// LogUpload records an OSS export file loaded into oss_base. Imported rows
+// point back to it through their _log_upload_id column.
+type LogUpload struct {
+	ID            int        `json:"id" gorm:"primaryKey"`
+	FileName      string     `json:"file_name" gorm:"column:file_name;size:255;not null"`
+	FileSize      int64      `json:"file_size" gorm:"column:file_size;not null;default:0"`
+	SheetName     string     `json:"sheet_name" gorm:"column:sheet_name;size:100"`
+	UploadedBy    int        `json:"uploaded_by" gorm:"column:uploaded_by;not null;index"`
+	JobID         *string    `json:"job_id" gorm:"column:job_id;size:36;index"`
+	MatchKey      string     `json:"match_key" gorm:"column:match_key;size:20;not null;default:idProyek"`
+	Status        string     `json:"status" gorm:"column:status;size:20;not null;index"`
+	TotalRows     int        `json:"total_rows" gorm:"column:total_rows;not null;default:0"`
+	SuccessRows   int        `json:"success_rows" gorm:"column:success_rows;not null;default:0"`
+	FailedRows    int        `json:"failed_rows" gorm:"column:failed_rows;not null;default:0"`
+	InsertedRows  int        `json:"inserted_rows" gorm:"column:inserted_rows;not null;default:0"`
+	UpdatedRows   int        `json:"updated_rows" gorm:"column:updated_rows;not null;default:0"`
+	UnchangedRows int        `json:"unchanged_rows" gorm:"column:unchanged_rows;not null;default:0"`
+	ErrorMessage  *string    `json:"error_message" gorm:"column:error_message;type:text"`
+	StartedAt     *time.Time `json:"started_at" gorm:"column:started_at"`
+	FinishedAt    *time.Time `json:"finished_at" gorm:"column:finished_at"`
+	RolledBackAt  *time.Time `json:"rolled_back_at" gorm:"column:rolled_back_at"`
+	RolledBackBy  *int       `json:"rolled_back_by" gorm:"column:rolled_back_by"`
+	RollbackMode  *string    `json:"rollback_mode" gorm:"column:rollback_mode;size:10"`
+	RollbackJobID *string    `json:"rollback_job_id" gorm:"column:rollback_job_id;size:36;index"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (LogUpload) TableName() string {
+	return "log_uploads"
+}
+
+// LogUploadError is a problem found in one cell (or a whole row, when Column
+// is empty) of an uploaded file
+type LogUploadError struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	LogUploadID int    `json:"log_upload_id" gorm:"column:log_upload_id;not null;index"`
+	RowNumber   int    `json:"row" gorm:"column:row_no;not null"`
+	Column      string `json:"column,omitempty" gorm:"column:column_name;size:100"`
+	Value       string `json:"value,omitempty" gorm:"column:value;size:500"`
+	Message     string `json:"message" gorm:"column:message;size:500;not null"`
+}
+
+func (LogUploadError) TableName() string {
+	return "log_upload_errors"
+}
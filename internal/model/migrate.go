@@ -0,0 +1,137 @@
+package model
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AutoMigrate creates or updates the tables owned by this service. Tables that
+// are shared with other systems (users, menus, oss_base) are not migrated; see
+// MigrateOssBase for the columns this service needs on oss_base.
+func AutoMigrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&RefreshToken{},
+		&Role{},
+		&Permission{},
+		&RolePermission{},
+		&UserRole{},
+		&LogUpload{},
+		&LogUploadError{},
+		&Job{},
+		&OssBaseSnapshot{},
+		&OssBaseHistory{},
+	)
+	if err != nil {
+		return err
+	}
+
+	return seedPermissions(db)
+}
+
+// MigrateOssBase adds the columns and indexes this service relies on to the
+// shared oss_base table. Only the listed fields are touched, never the rest of the
+// table, and nothing happens when the table does not exist. It alters a table
+// other systems use, so it only runs when database.migrate_oss_base is set.
+func MigrateOssBase(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&OssBase{}) {
+		return nil
+	}
+
+	for _, field := range []string{"DeletedAt", "DeletedBy", "GeoLat", "GeoLon", "GeoStatus"} {
+		if !migrator.HasColumn(&OssBase{}, field) {
+			if err := migrator.AddColumn(&OssBase{}, field); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, index := range []string{"idx_oss_base_log_upload_id", "idx_oss_base_deleted_at", "ft_oss_base_search", "idx_oss_base_geo_status"} {
+		if !migrator.HasIndex(&OssBase{}, index) {
+			if err := migrator.CreateIndex(&OssBase{}, index); err != nil {
+				return err
+			}
+		}
+	}
+
+	return migrateOssBasePoint(db)
+}
+
+// migrateOssBasePoint adds _geo_point, the coordinates as a POINT generated
+// from _geo_lat and _geo_lon, and its SPATIAL index. A SPATIAL index needs a
+// NOT NULL column, so rows without coordinates hold POINT(0 0); _geo_status
+// tells them apart, as 0,0 is never a valid position. It replaces the
+// idx_oss_base_geo index on the decimal columns.
+func migrateOssBasePoint(db *gorm.DB) error {
+	migrator := db.Migrator()
+	table := OssBase{}.TableName()
+
+	if !migrator.HasColumn(&OssBase{}, "_geo_point") {
+		err := db.Exec(fmt.Sprintf(`
+			ALTER TABLE %s ADD COLUMN _geo_point POINT
+			GENERATED ALWAYS AS (ST_GeomFromText(
+				CONCAT('POINT(', COALESCE(_geo_lon, 0), ' ', COALESCE(_geo_lat, 0), ')'),
+				4326, 'axis-order=long-lat'
+			)) STORED NOT NULL SRID 4326`, table)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if !migrator.HasIndex(&OssBase{}, "idx_oss_base_geo_point") {
+		if err := db.Exec(fmt.Sprintf("CREATE SPATIAL INDEX idx_oss_base_geo_point ON %s (_geo_point)", table)).Error; err != nil {
+			return err
+		}
+	}
+
+	if migrator.HasIndex(&OssBase{}, "idx_oss_base_geo") {
+		return migrator.DropIndex(&OssBase{}, "idx_oss_base_geo")
+	}
+	return nil
+}
+
+// BootstrapAdmin grants the admin role to the user with the given username.
+// It is idempotent and needs the role created by AutoMigrate.
+func BootstrapAdmin(db *gorm.DB, username string) error {
+	var user User
+	if err := db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("bootstrap admin %q: %w", username, err)
+	}
+
+	var admin Role
+	if err := db.Where("code = ?", AdminRoleCode).First(&admin).Error; err != nil {
+		return fmt.Errorf("bootstrap admin: role %q: %w", AdminRoleCode, err)
+	}
+
+	return db.Exec(`
+		INSERT INTO user_roles (user_id, role_id, created_at)
+		VALUES (?, ?, NOW())
+		ON DUPLICATE KEY UPDATE role_id = role_id`,
+		user.ID, admin.ID,
+	).Error
+}
+
+// seedPermissions creates the default permissions and the admin role holding all of them
+func seedPermissions(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, permission := range DefaultPermissions {
+			p := permission
+			if err := tx.Where(Permission{Code: p.Code}).FirstOrCreate(&p).Error; err != nil {
+				return err
+			}
+		}
+
+		admin := Role{Code: AdminRoleCode, Name: "Administrator"}
+		if err := tx.Where(Role{Code: admin.Code}).FirstOrCreate(&admin).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			INSERT INTO role_permissions (role_id, permission_id, created_at)
+			SELECT ?, id, NOW() FROM permissions
+			ON DUPLICATE KEY UPDATE role_id = role_id`,
+			admin.ID,
+		).Error
+	})
+}
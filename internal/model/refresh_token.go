@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Reasons recorded when a refresh token is revoked
+const (
+	RefreshTokenRotated       = "rotated"
+	RefreshTokenReplaced      = "replaced"
+	RefreshTokenReuseDetected = "reuse_detected"
+	RefreshTokenLoggedOut     = "logout"
+)
+
+// RefreshToken is the server-side record of an issued refresh token. Tokens
+// issued from the same login share a FamilyID so that a reused token can
+// revoke the whole chain.
+type RefreshToken struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	TokenID       string     `json:"token_id" gorm:"column:token_id;size:36;not null;uniqueIndex"`
+	FamilyID      string     `json:"family_id" gorm:"column:family_id;size:36;not null;index"`
+	UserID        int        `json:"user_id" gorm:"column:user_id;not null;index:idx_refresh_tokens_user_device"`
+	DeviceID      string     `json:"device_id" gorm:"column:device_id;size:150;not null;index:idx_refresh_tokens_user_device"`
+	UserAgent     string     `json:"user_agent" gorm:"column:user_agent;size:255"`
+	IPAddress     string     `json:"ip_address" gorm:"column:ip_address;size:45"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"column:expires_at;not null"`
+	RevokedAt     *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	RevokedReason *string    `json:"revoked_reason" gorm:"column:revoked_reason;size:50"`
+	ReplacedBy    *string    `json:"replaced_by" gorm:"column:replaced_by;size:36"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
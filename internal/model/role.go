@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+type Role struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Code        string    `json:"code" gorm:"size:100;not null;uniqueIndex"`
+	Name        string    `json:"name" gorm:"size:200;not null"`
+	Description *string   `json:"description" gorm:"size:500"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
+
+type Permission struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Code        string    `json:"code" gorm:"size:100;not null;uniqueIndex"`
+	Name        string    `json:"name" gorm:"size:200;not null"`
+	Description *string   `json:"description" gorm:"size:500"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+type RolePermission struct {
+	RoleID       uint      `json:"role_id" gorm:"primaryKey"`
+	PermissionID uint      `json:"permission_id" gorm:"primaryKey;index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+type UserRole struct {
+	UserID    int       `json:"user_id" gorm:"primaryKey"`
+	RoleID    uint      `json:"role_id" gorm:"primaryKey;index"`
+	CreatedBy *int      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (UserRole) TableName() string {
+	return "user_roles"
+}
+
+// AdminRoleCode is the role that is granted every default permission
+const AdminRoleCode = "admin"
+
+// DefaultPermissions are the permissions checked by the API. They are created
+// on startup so that they can be assigned to roles.
+var DefaultPermissions = []Permission{
+	{Code: "oss.read", Name: "View OSS data"},
+	{Code: "oss.import", Name: "Import OSS export files"},
+	{Code: "oss.rollback", Name: "Roll back OSS imports"},
+	{Code: "oss.edit", Name: "Edit OSS projects"},
+	{Code: "oss.export", Name: "Export OSS data"},
+	{Code: "menu.manage", Name: "Manage menus"},
+	{Code: "user.manage", Name: "Manage users"},
+	{Code: "rbac.manage", Name: "Manage roles and permissions"},
+}
@@ -0,0 +1,30 @@
+package auth
+
+type LoginRequest struct {
+	Username string `json:"username" binding:"required,max=150"`
+	Password string `json:"password" binding:"required"`
+	DeviceID string `json:"device_id" binding:"omitempty,max=150"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token,omitempty"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in,omitempty"`
+}
+
+// ClientInfo describes the device a refresh token is issued to
+type ClientInfo struct {
+	DeviceID  string
+	UserAgent string
+	IPAddress string
+}
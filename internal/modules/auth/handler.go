@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"io"
+	"kswi-backend/internal/middleware"
+	"kswi-backend/internal/shared/api"
+	"kswi-backend/internal/shared/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Login godoc
+// @Summary Sign in with username and password
+// @Description Verifies the credentials and returns an access token and a refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body LoginRequest true "Credentials"
+// @Success 200 {object} api.APIResponse{data=TokenResponse}
+// @Failure 400 {object} api.APIResponse
+// @Failure 401 {object} api.APIResponse
+// @Router /api/auth/login [post]
+func (h *Handler) Login(c *gin.Context) {
+	var req LoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	tokens, err := h.service.Login(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Login successful",
+		Data:    tokens,
+	})
+}
+
+// Refresh godoc
+// @Summary Refresh the access token
+// @Description Rotates a valid refresh token and returns a new access token and refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 200 {object} api.APIResponse{data=TokenResponse}
+// @Failure 400 {object} api.APIResponse
+// @Failure 401 {object} api.APIResponse
+// @Failure 409 {object} api.APIResponse
+// @Router /api/auth/refresh [post]
+func (h *Handler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	tokens, err := h.service.Refresh(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Token refreshed successfully",
+		Data:    tokens,
+	})
+}
+
+// Logout godoc
+// @Summary Sign out of the current session
+// @Description Revokes the current access token and, when given, the refresh token of the session
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body LogoutRequest false "Refresh token of the session"
+// @Success 200 {object} api.APIResponse
+// @Failure 401 {object} api.APIResponse
+// @Router /api/auth/logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	var req LogoutRequest
+
+	// The body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.NewAuthError("Authentication required"))
+		return
+	}
+
+	if err := h.service.Logout(c.Request.Context(), claims, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Logged out successfully",
+	})
+}
+
+// LogoutAll godoc
+// @Summary Sign out of every session
+// @Description Revokes every refresh token and access token of the current user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} api.APIResponse
+// @Failure 401 {object} api.APIResponse
+// @Router /api/auth/logout-all [post]
+func (h *Handler) LogoutAll(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.NewAuthError("Authentication required"))
+		return
+	}
+
+	if err := h.service.LogoutAll(c.Request.Context(), claims); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Logged out of all sessions successfully",
+	})
+}
+
+// clientInfo describes the device that sent the request
+func clientInfo(c *gin.Context) ClientInfo {
+	return ClientInfo{
+		DeviceID:  c.GetHeader("X-Device-ID"),
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"kswi-backend/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, token *model.RefreshToken) error
+	FindByTokenID(ctx context.Context, tokenID string) (*model.RefreshToken, error)
+	Rotate(ctx context.Context, current *model.RefreshToken, next *model.RefreshToken) (bool, error)
+	RevokeFamily(ctx context.Context, familyID, reason string) error
+	RevokeUserDevice(ctx context.Context, userID int, deviceID, reason string) error
+	RevokeUser(ctx context.Context, userID int, reason string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, token *model.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *repository) FindByTokenID(ctx context.Context, tokenID string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+
+	err := r.db.WithContext(ctx).
+		Where("token_id = ?", tokenID).
+		First(&token).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Rotate revokes the current token and stores its successor in one transaction.
+// It returns false when the current token was already revoked by a concurrent request.
+func (r *repository) Rotate(ctx context.Context, current *model.RefreshToken, next *model.RefreshToken) (bool, error) {
+	rotated := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{
+				"revoked_at":     time.Now().UTC(),
+				"revoked_reason": model.RefreshTokenRotated,
+				"replaced_by":    next.TokenID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		rotated = true
+		return nil
+	})
+
+	return rotated, err
+}
+
+func (r *repository) RevokeFamily(ctx context.Context, familyID, reason string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now().UTC(),
+			"revoked_reason": reason,
+		}).Error
+}
+
+func (r *repository) RevokeUserDevice(ctx context.Context, userID int, deviceID, reason string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("user_id = ? AND device_id = ? AND revoked_at IS NULL", userID, deviceID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now().UTC(),
+			"revoked_reason": reason,
+		}).Error
+}
+
+func (r *repository) RevokeUser(ctx context.Context, userID int, reason string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now().UTC(),
+			"revoked_reason": reason,
+		}).Error
+}
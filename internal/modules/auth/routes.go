@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/middleware"
+	"kswi-backend/internal/modules/rbac"
+	"kswi-backend/internal/modules/user"
+	"kswi-backend/internal/shared/denylist"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	db := config.GetDB()
+	repo := NewRepository(db)
+	jwtManager := config.GetJWTManager()
+	svc := NewService(repo, user.NewRepository(db), rbac.NewRepository(db), jwtManager, denylist.Get())
+	handler := NewHandler(svc)
+
+	authRoutes := r.Group("/auth")
+	{
+		authRoutes.POST("/login", handler.Login)
+		authRoutes.POST("/refresh", handler.Refresh)
+
+		authenticated := authRoutes.Group("", middleware.AuthMiddleware(jwtManager))
+		authenticated.POST("/logout", handler.Logout)
+		authenticated.POST("/logout-all", handler.LogoutAll)
+	}
+}
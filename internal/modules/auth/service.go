@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/modules/rbac"
+	"kswi-backend/internal/modules/user"
+	"kswi-backend/internal/shared/denylist"
+	"kswi-backend/internal/shared/errors"
+	"kswi-backend/internal/shared/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const defaultDeviceID = "default"
+
+// rotationGracePeriod is how long after a rotation the rotated refresh token
+// is answered with a conflict instead of revoking its family, when the client
+// it was rotated for presents it again, so that concurrent refreshes of one
+// client, such as two tabs, do not sign it out
+const rotationGracePeriod = 5 * time.Second
+
+type Service interface {
+	Login(ctx context.Context, req *LoginRequest, client ClientInfo) (*TokenResponse, error)
+	Refresh(ctx context.Context, req *RefreshRequest, client ClientInfo) (*TokenResponse, error)
+	Logout(ctx context.Context, claims *config.Claims, req *LogoutRequest) error
+	LogoutAll(ctx context.Context, claims *config.Claims) error
+}
+
+type service struct {
+	repo     Repository
+	userRepo user.Repository
+	roleRepo rbac.Repository
+	jwt      *config.JWTManager
+	denylist denylist.Denylist
+}
+
+func NewService(repo Repository, userRepo user.Repository, roleRepo rbac.Repository, jwt *config.JWTManager, tokenDenylist denylist.Denylist) Service {
+	return &service{repo: repo, userRepo: userRepo, roleRepo: roleRepo, jwt: jwt, denylist: tokenDenylist}
+}
+
+// Login verifies the username and password and issues a new token pair.
+// Any session previously opened on the same device is revoked.
+func (s *service) Login(ctx context.Context, req *LoginRequest, client ClientInfo) (*TokenResponse, error) {
+	u, err := s.userRepo.FindByUsername(req.Username)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NewAuthError("Invalid username or password")
+		}
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find user: %w", err))
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(req.Password)); err != nil {
+		return nil, errors.NewAuthError("Invalid username or password")
+	}
+
+	if req.DeviceID != "" {
+		client.DeviceID = req.DeviceID
+	}
+	if client.DeviceID == "" {
+		client.DeviceID = defaultDeviceID
+	}
+
+	if err := s.repo.RevokeUserDevice(ctx, u.ID, client.DeviceID, model.RefreshTokenReplaced); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to revoke previous session: %w", err))
+	}
+
+	accessToken, err := s.generateAccessToken(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	record := s.newRefreshRecord(u.ID, uuid.NewString(), client)
+	refreshToken, err := s.jwt.GenerateRefreshToken(uint(u.ID), record.TokenID)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	if err := s.repo.Create(ctx, record); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to store refresh token: %w", err))
+	}
+
+	return s.buildTokenResponse(accessToken, refreshToken), nil
+}
+
+// Refresh rotates a refresh token: the presented token is revoked and a new
+// access and refresh token pair is issued. Presenting a token that was already
+// rotated revokes every token of its family, see rejectRevoked.
+func (s *service) Refresh(ctx context.Context, req *RefreshRequest, client ClientInfo) (*TokenResponse, error) {
+	claims, err := s.jwt.ValidateToken(req.RefreshToken)
+	if err != nil || claims.TokenType != config.TokenTypeRefresh || claims.ID == "" {
+		return nil, errors.NewAuthError("Invalid or expired refresh token")
+	}
+
+	current, err := s.repo.FindByTokenID(ctx, claims.ID)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find refresh token: %w", err))
+	}
+	if current == nil || strconv.Itoa(current.UserID) != claims.Subject {
+		return nil, errors.NewAuthError("Invalid or expired refresh token")
+	}
+
+	if current.RevokedAt != nil {
+		return nil, s.rejectRevoked(ctx, current, client)
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, errors.NewAuthError("Invalid or expired refresh token")
+	}
+
+	u, err := s.findTokenOwner(current.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.generateAccessToken(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	client.DeviceID = current.DeviceID
+	next := s.newRefreshRecord(u.ID, current.FamilyID, client)
+	refreshToken, err := s.jwt.GenerateRefreshToken(uint(u.ID), next.TokenID)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	rotated, err := s.repo.Rotate(ctx, current, next)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to rotate refresh token: %w", err))
+	}
+	if !rotated {
+		// Another request revoked the same token first
+		latest, err := s.repo.FindByTokenID(ctx, current.TokenID)
+		if err != nil {
+			return nil, errors.NewDatabaseError(fmt.Errorf("failed to find refresh token: %w", err))
+		}
+		if latest == nil || latest.RevokedAt == nil {
+			return nil, errors.NewAuthError("Invalid or expired refresh token")
+		}
+		return nil, s.rejectRevoked(ctx, latest, client)
+	}
+
+	return s.buildTokenResponse(accessToken, refreshToken), nil
+}
+
+// Logout revokes the access token of the caller and, when given, the refresh
+// token of the same session
+func (s *service) Logout(ctx context.Context, claims *config.Claims, req *LogoutRequest) error {
+	if err := s.revokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+
+	if req.RefreshToken == "" {
+		return nil
+	}
+
+	refreshClaims, err := s.jwt.ValidateToken(req.RefreshToken)
+	if err != nil || refreshClaims.TokenType != config.TokenTypeRefresh || refreshClaims.Subject != claims.Subject {
+		// The access token is already revoked; an unusable refresh token needs no revocation
+		return nil
+	}
+
+	token, err := s.repo.FindByTokenID(ctx, refreshClaims.ID)
+	if err != nil {
+		return errors.NewDatabaseError(fmt.Errorf("failed to find refresh token: %w", err))
+	}
+	if token == nil {
+		return nil
+	}
+
+	if err := s.repo.RevokeFamily(ctx, token.FamilyID, model.RefreshTokenLoggedOut); err != nil {
+		return errors.NewDatabaseError(fmt.Errorf("failed to revoke refresh token: %w", err))
+	}
+
+	return nil
+}
+
+// LogoutAll revokes every refresh token of the caller and every access token
+// issued to them up to now
+func (s *service) LogoutAll(ctx context.Context, claims *config.Claims) error {
+	if err := s.repo.RevokeUser(ctx, int(claims.UserID), model.RefreshTokenLoggedOut); err != nil {
+		return errors.NewDatabaseError(fmt.Errorf("failed to revoke refresh tokens: %w", err))
+	}
+
+	if err := s.denylist.RevokeUser(ctx, claims.UserID, time.Now(), s.jwt.GetTokenTTL()); err != nil {
+		return errors.NewInternalError(err)
+	}
+
+	return s.revokeAccessToken(ctx, claims)
+}
+
+// revokeAccessToken denies the access token until it would have expired anyway
+func (s *service) revokeAccessToken(ctx context.Context, claims *config.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := s.denylist.Revoke(ctx, claims.ID, ttl); err != nil {
+		return errors.NewInternalError(err)
+	}
+
+	return nil
+}
+
+// rejectRevoked answers a refresh with a revoked token. A rotated token
+// presented again is reuse and revokes its family, unless the client it was
+// rotated for presents it within rotationGracePeriod, as concurrent
+// refreshes of one client do; that is a conflict.
+func (s *service) rejectRevoked(ctx context.Context, token *model.RefreshToken, client ClientInfo) error {
+	if token.RevokedReason == nil || *token.RevokedReason != model.RefreshTokenRotated {
+		return errors.NewAuthError("Refresh token has been revoked")
+	}
+
+	if token.ReplacedBy != nil && time.Since(*token.RevokedAt) < rotationGracePeriod {
+		successor, err := s.repo.FindByTokenID(ctx, *token.ReplacedBy)
+		if err != nil {
+			return errors.NewDatabaseError(fmt.Errorf("failed to find refresh token: %w", err))
+		}
+		if successor != nil && successor.UserAgent == truncate(client.UserAgent, 255) && successor.IPAddress == client.IPAddress {
+			return recentlyRotatedError()
+		}
+	}
+
+	return s.handleReuse(ctx, token)
+}
+
+// recentlyRotatedError answers a refresh with a token rotated within
+// rotationGracePeriod
+func recentlyRotatedError() error {
+	return errors.NewConflictError("Refresh token was just rotated by another request, use the new token")
+}
+
+// handleReuse revokes the whole token family after a rotated token was presented again
+func (s *service) handleReuse(ctx context.Context, token *model.RefreshToken) error {
+	logger.FromContext(ctx).
+		WithModule("auth").
+		WithFields("user_id", token.UserID, "device_id", token.DeviceID, "family_id", token.FamilyID).
+		Warn("Refresh token reuse detected, revoking token family")
+
+	if err := s.repo.RevokeFamily(ctx, token.FamilyID, model.RefreshTokenReuseDetected); err != nil {
+		return errors.NewDatabaseError(fmt.Errorf("failed to revoke token family: %w", err))
+	}
+
+	return errors.NewAuthError("Refresh token reuse detected, please sign in again")
+}
+
+// findTokenOwner loads the user a refresh token was issued to
+func (s *service) findTokenOwner(userID int) (*model.User, error) {
+	u, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NewAuthError("User no longer exists")
+		}
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find user: %w", err))
+	}
+
+	return u, nil
+}
+
+// generateAccessToken issues an access token carrying the user's role codes
+func (s *service) generateAccessToken(ctx context.Context, u *model.User) (string, error) {
+	roles, err := s.roleRepo.FindRolesByUserID(ctx, u.ID)
+	if err != nil {
+		return "", errors.NewDatabaseError(fmt.Errorf("failed to get user roles: %w", err))
+	}
+
+	codes := make([]string, 0, len(roles))
+	for _, role := range roles {
+		codes = append(codes, role.Code)
+	}
+
+	accessToken, err := s.jwt.GenerateAccessToken(uint(u.ID), u.Username, strings.Join(codes, ","))
+	if err != nil {
+		return "", errors.NewInternalError(err)
+	}
+
+	return accessToken, nil
+}
+
+func (s *service) newRefreshRecord(userID int, familyID string, client ClientInfo) *model.RefreshToken {
+	return &model.RefreshToken{
+		TokenID:   uuid.NewString(),
+		FamilyID:  familyID,
+		UserID:    userID,
+		DeviceID:  client.DeviceID,
+		UserAgent: truncate(client.UserAgent, 255),
+		IPAddress: client.IPAddress,
+		ExpiresAt: time.Now().UTC().Add(s.jwt.GetRefreshTokenTTL()),
+	}
+}
+
+func (s *service) buildTokenResponse(accessToken, refreshToken string) *TokenResponse {
+	response := &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.jwt.GetTokenTTL().Seconds()),
+	}
+
+	if refreshToken != "" {
+		response.RefreshToken = refreshToken
+		response.RefreshExpiresIn = int64(s.jwt.GetRefreshTokenTTL().Seconds())
+	}
+
+	return response
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/modules/rbac"
+	"kswi-backend/internal/modules/user"
+	"kswi-backend/internal/shared/denylist"
+	"kswi-backend/internal/shared/errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// The service logs through the global logger and signs with the default
+	// development secret
+	if err := config.InitViper(); err != nil {
+		panic(err)
+	}
+	if err := config.InitLogger(); err != nil {
+		panic(err)
+	}
+	if err := config.InitJWT(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// tokenRepo serves refresh tokens from memory and records revoked families;
+// other methods are not used
+type tokenRepo struct {
+	Repository
+	tokens       map[string]*model.RefreshToken
+	revoked      []string
+	revokedUsers []int
+}
+
+func (r *tokenRepo) FindByTokenID(ctx context.Context, tokenID string) (*model.RefreshToken, error) {
+	return r.tokens[tokenID], nil
+}
+
+func (r *tokenRepo) RevokeFamily(ctx context.Context, familyID, reason string) error {
+	r.revoked = append(r.revoked, familyID)
+	return nil
+}
+
+func (r *tokenRepo) Rotate(ctx context.Context, current *model.RefreshToken, next *model.RefreshToken) (bool, error) {
+	stored := r.tokens[current.TokenID]
+	if stored.RevokedAt != nil {
+		return false, nil
+	}
+
+	now, reason := time.Now(), model.RefreshTokenRotated
+	stored.RevokedAt, stored.RevokedReason, stored.ReplacedBy = &now, &reason, &next.TokenID
+	r.tokens[next.TokenID] = next
+	return true, nil
+}
+
+func (r *tokenRepo) RevokeUser(ctx context.Context, userID int, reason string) error {
+	r.revokedUsers = append(r.revokedUsers, userID)
+	return nil
+}
+
+// userRepo finds one user; other methods are not used
+type userRepo struct {
+	user.Repository
+	user model.User
+}
+
+func (r *userRepo) FindByID(id int) (*model.User, error) {
+	u := r.user
+	return &u, nil
+}
+
+// roleRepo gives every user the same roles; other methods are not used
+type roleRepo struct {
+	rbac.Repository
+}
+
+func (r *roleRepo) FindRolesByUserID(ctx context.Context, userID int) ([]model.Role, error) {
+	return []model.Role{{Code: "admin"}}, nil
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	browser := ClientInfo{UserAgent: "browser", IPAddress: "10.0.0.1"}
+	other := ClientInfo{UserAgent: "curl", IPAddress: "10.0.0.2"}
+
+	tests := []struct {
+		name       string
+		replayBy   *ClientInfo
+		wantType   errors.ErrorType
+		wantRevoke bool
+	}{
+		{name: "successor refreshes again"},
+		{name: "replay by the same client", replayBy: &browser, wantType: errors.TypeConflict},
+		{name: "replay by another client", replayBy: &other, wantType: errors.TypeAuth, wantRevoke: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &tokenRepo{tokens: map[string]*model.RefreshToken{}}
+			jwt := config.GetJWTManager()
+			s := &service{repo: repo, userRepo: &userRepo{user: model.User{ID: 7, Username: "budi"}}, roleRepo: &roleRepo{}, jwt: jwt}
+
+			first := s.newRefreshRecord(7, "fam", ClientInfo{DeviceID: "laptop", UserAgent: "browser", IPAddress: "10.0.0.1"})
+			repo.tokens[first.TokenID] = first
+			firstToken, err := jwt.GenerateRefreshToken(7, first.TokenID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response, err := s.Refresh(ctx, &RefreshRequest{RefreshToken: firstToken}, browser)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			claims, err := jwt.ValidateToken(response.RefreshToken)
+			if err != nil {
+				t.Fatalf("invalid rotated token: %v", err)
+			}
+			next := repo.tokens[claims.ID]
+			if next == nil || next.FamilyID != "fam" || next.DeviceID != "laptop" || *first.ReplacedBy != next.TokenID {
+				t.Fatalf("successor = %+v, want a token of the same family and device replacing the first", next)
+			}
+
+			if tt.replayBy == nil {
+				if _, err := s.Refresh(ctx, &RefreshRequest{RefreshToken: response.RefreshToken}, browser); err != nil {
+					t.Errorf("refresh with the successor failed: %v", err)
+				}
+				return
+			}
+
+			_, err = s.Refresh(ctx, &RefreshRequest{RefreshToken: firstToken}, *tt.replayBy)
+			var appErr *errors.AppError
+			if !errors.As(err, &appErr) || appErr.Type != tt.wantType {
+				t.Fatalf("replay error = %v, want %s", err, tt.wantType)
+			}
+			if revoked := len(repo.revoked) > 0; revoked != tt.wantRevoke {
+				t.Errorf("family revoked = %v, want %v", revoked, tt.wantRevoke)
+			}
+		})
+	}
+}
+
+func TestRejectRevoked(t *testing.T) {
+	reason := func(s string) *string { return &s }
+	successorID := "next"
+	successor := &model.RefreshToken{TokenID: successorID, FamilyID: "fam", UserAgent: "browser", IPAddress: "10.0.0.1"}
+	sameClient := ClientInfo{UserAgent: "browser", IPAddress: "10.0.0.1"}
+
+	tests := []struct {
+		name       string
+		reason     string
+		revokedAgo time.Duration
+		client     ClientInfo
+		wantType   errors.ErrorType
+		wantRevoke bool
+	}{
+		{name: "same client within grace", reason: model.RefreshTokenRotated, revokedAgo: time.Second, client: sameClient, wantType: errors.TypeConflict},
+		{name: "other user agent within grace", reason: model.RefreshTokenRotated, revokedAgo: time.Second, client: ClientInfo{UserAgent: "curl", IPAddress: "10.0.0.1"}, wantType: errors.TypeAuth, wantRevoke: true},
+		{name: "other address within grace", reason: model.RefreshTokenRotated, revokedAgo: time.Second, client: ClientInfo{UserAgent: "browser", IPAddress: "10.0.0.2"}, wantType: errors.TypeAuth, wantRevoke: true},
+		{name: "same client after grace", reason: model.RefreshTokenRotated, revokedAgo: time.Minute, client: sameClient, wantType: errors.TypeAuth, wantRevoke: true},
+		{name: "logged out", reason: model.RefreshTokenReuseDetected, revokedAgo: time.Second, client: sameClient, wantType: errors.TypeAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &tokenRepo{tokens: map[string]*model.RefreshToken{successorID: successor}}
+			s := &service{repo: repo}
+			revokedAt := time.Now().Add(-tt.revokedAgo)
+			token := &model.RefreshToken{TokenID: "current", FamilyID: "fam", RevokedAt: &revokedAt, RevokedReason: reason(tt.reason), ReplacedBy: &successorID}
+
+			err := s.rejectRevoked(context.Background(), token, tt.client)
+
+			var appErr *errors.AppError
+			if !errors.As(err, &appErr) || appErr.Type != tt.wantType {
+				t.Fatalf("error = %v, want %s", err, tt.wantType)
+			}
+			if revoked := len(repo.revoked) > 0; revoked != tt.wantRevoke {
+				t.Errorf("family revoked = %v, want %v", revoked, tt.wantRevoke)
+			}
+		})
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	ctx := context.Background()
+	jwt := config.GetJWTManager()
+	repo := &tokenRepo{}
+	tokens := denylist.NewMemoryDenylist()
+	s := &service{repo: repo, jwt: jwt, denylist: tokens}
+
+	issue := func(userID uint) *config.Claims {
+		token, err := jwt.GenerateAccessToken(userID, "budi", "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := jwt.ValidateToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return claims
+	}
+
+	caller, sibling, stranger := issue(7), issue(7), issue(8)
+	if err := s.LogoutAll(ctx, caller); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.revokedUsers) != 1 || repo.revokedUsers[0] != 7 {
+		t.Errorf("refresh tokens revoked for users %v, want 7", repo.revokedUsers)
+	}
+
+	tests := []struct {
+		name   string
+		claims *config.Claims
+		want   bool
+	}{
+		{name: "caller", claims: caller, want: true},
+		{name: "other session of the caller", claims: sibling, want: true},
+		{name: "other user", claims: stranger, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := denylist.IsClaimsRevoked(ctx, tokens, tt.claims)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if revoked != tt.want {
+				t.Errorf("revoked = %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package job
+
+import "kswi-backend/internal/model"
+
+// JobResponse is a job with its completion ratio
+type JobResponse struct {
+	model.Job
+	Progress float64 `json:"progress"`
+}
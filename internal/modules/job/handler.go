@@ -0,0 +1,79 @@
+package job
+
+import (
+	"kswi-backend/internal/middleware"
+	"kswi-backend/internal/shared/api"
+	"kswi-backend/internal/shared/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetJob godoc
+// @Summary Get a background job
+// @Description Returns the status and progress of a job started by the caller
+// @Tags jobs
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Job ID"
+// @Success 200 {object} api.APIResponse{data=JobResponse}
+// @Failure 404 {object} api.APIResponse
+// @Router /api/jobs/{id} [get]
+func (h *Handler) GetJob(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		_ = c.Error(errors.NewAuthError("Authentication required"))
+		return
+	}
+
+	job, err := h.service.GetJob(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Job retrieved successfully",
+		Data:    job,
+	})
+}
+
+// CancelJob godoc
+// @Summary Cancel a background job
+// @Description Asks a queued or running job started by the caller to stop
+// @Tags jobs
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Job ID"
+// @Success 202 {object} api.APIResponse{data=JobResponse}
+// @Failure 404 {object} api.APIResponse
+// @Failure 409 {object} api.APIResponse
+// @Router /api/jobs/{id}/cancel [post]
+func (h *Handler) CancelJob(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		_ = c.Error(errors.NewAuthError("Authentication required"))
+		return
+	}
+
+	job, err := h.service.CancelJob(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, api.APIResponse{
+		Success: true,
+		Message: "Job cancellation requested",
+		Data:    job,
+	})
+}
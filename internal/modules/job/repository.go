@@ -0,0 +1,33 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"kswi-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	FindByID(ctx context.Context, id string) (*model.Job, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) FindByID(ctx context.Context, id string) (*model.Job, error) {
+	var job model.Job
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
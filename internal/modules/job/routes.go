@@ -0,0 +1,20 @@
+package job
+
+import (
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/worker"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	repo := NewRepository(config.GetDB())
+	svc := NewService(repo, worker.Get())
+	handler := NewHandler(svc)
+
+	jobs := r.Group("/jobs")
+	{
+		jobs.GET("/:id", handler.GetJob)
+		jobs.POST("/:id/cancel", handler.CancelJob)
+	}
+}
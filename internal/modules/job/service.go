@@ -0,0 +1,79 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/errors"
+	"kswi-backend/internal/worker"
+)
+
+type Service interface {
+	GetJob(ctx context.Context, id string, userID uint) (*JobResponse, error)
+	CancelJob(ctx context.Context, id string, userID uint) (*JobResponse, error)
+}
+
+type service struct {
+	repo Repository
+	jobs *worker.Manager
+}
+
+func NewService(repo Repository, jobs *worker.Manager) Service {
+	return &service{repo: repo, jobs: jobs}
+}
+
+// GetJob returns a job started by the user. Jobs of other users are reported
+// as not found.
+func (s *service) GetJob(ctx context.Context, id string, userID uint) (*JobResponse, error) {
+	job, err := s.findJob(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toJobResponse(job), nil
+}
+
+// CancelJob asks a queued or running job to stop. The job reports the
+// cancelled status once its task has returned.
+func (s *service) CancelJob(ctx context.Context, id string, userID uint) (*JobResponse, error) {
+	job, err := s.findJob(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.IsFinished() {
+		return nil, errors.NewConflictError(fmt.Sprintf("Job is already %s", job.Status))
+	}
+
+	if err := s.jobs.Cancel(ctx, id); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to cancel job: %w", err))
+	}
+
+	return s.GetJob(ctx, id, userID)
+}
+
+func (s *service) findJob(ctx context.Context, id string, userID uint) (*model.Job, error) {
+	job, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find job: %w", err))
+	}
+	if job == nil || job.CreatedBy != int(userID) {
+		return nil, errors.NewNotFoundError("Job")
+	}
+	return job, nil
+}
+
+func toJobResponse(job *model.Job) *JobResponse {
+	response := &JobResponse{Job: *job}
+
+	switch {
+	case job.Status == model.JobCompleted:
+		response.Progress = 1
+	case job.TotalCount > 0:
+		response.Progress = float64(job.ProcessedCount) / float64(job.TotalCount)
+		if response.Progress > 1 {
+			response.Progress = 1
+		}
+	}
+
+	return response
+}
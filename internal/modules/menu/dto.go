@@ -1,5 +1,7 @@
 package menu
 
+import "time"
+
 type MenuResponse struct {
 	ID       uint           `json:"id" gorm:"column:id"`
 	ParentID uint           `json:"parent_id" gorm:"column:parent_id"`
@@ -10,12 +12,97 @@ type MenuResponse struct {
 	Children []MenuResponse `json:"children" gorm:"-"`
 }
 
+// MenuTree is a built menu tree together with the validators used for
+// conditional requests
+type MenuTree struct {
+	Menus        []MenuResponse `json:"menus"`
+	ETag         string         `json:"etag"`
+	LastModified time.Time      `json:"last_modified"`
+}
+
 type MenuDetailResponse struct {
-	ID       uint    `json:"id" gorm:"column:id"`
-	ParentID uint    `json:"parent_id" gorm:"column:parent_id"`
-	Sort     int     `json:"sort" gorm:"column:sort"`
-	Name     string  `json:"name" gorm:"column:name"`
-	Route    *string `json:"route" gorm:"column:route"`
-	Icon     *string `json:"icon" gorm:"column:icon"`
-	IsActive bool    `json:"is_active" gorm:"column:is_active"`
+	ID           uint    `json:"id" gorm:"column:id"`
+	ParentID     uint    `json:"parent_id" gorm:"column:parent_id"`
+	PermissionID *uint   `json:"permission_id" gorm:"column:permission_id"`
+	Code         *string `json:"code" gorm:"column:code"`
+	ParentCode   *string `json:"parent_code" gorm:"column:parent_code"`
+	Sort         int     `json:"sort" gorm:"column:sort"`
+	Name         string  `json:"name" gorm:"column:name"`
+	Route        *string `json:"route" gorm:"column:route"`
+	Icon         *string `json:"icon" gorm:"column:icon"`
+	IsActive     bool    `json:"is_active" gorm:"column:is_active"`
+}
+
+type CreateMenuRequest struct {
+	ParentID     uint    `json:"parent_id"`
+	PermissionID *uint   `json:"permission_id"`
+	Code         *string `json:"code" binding:"omitempty,max=200"`
+	Sort         *int    `json:"sort"`
+	Name         string  `json:"name" binding:"required,max=200"`
+	Route        *string `json:"route" binding:"omitempty,max=200"`
+	Icon         *string `json:"icon" binding:"omitempty,max=1000"`
+	IsActive     *bool   `json:"is_active"`
+}
+
+// UpdateMenuRequest changes the fields it sets; omitted fields keep their
+// value. Clear lists the optional fields to set to null.
+type UpdateMenuRequest struct {
+	PermissionID *uint    `json:"permission_id"`
+	Code         *string  `json:"code" binding:"omitempty,max=200"`
+	Sort         *int     `json:"sort"`
+	Name         string   `json:"name" binding:"required,max=200"`
+	Route        *string  `json:"route" binding:"omitempty,max=200"`
+	Icon         *string  `json:"icon" binding:"omitempty,max=1000"`
+	Clear        []string `json:"clear" binding:"omitempty,dive,oneof=permission_id code route icon"`
+}
+
+type MoveMenuRequest struct {
+	ParentID *uint `json:"parent_id" binding:"required"`
+	Sort     *int  `json:"sort"`
+}
+
+type ReorderMenuRequest struct {
+	ParentID *uint  `json:"parent_id" binding:"required"`
+	IDs      []uint `json:"ids" binding:"required,min=1"`
+}
+
+type SetMenuActiveRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
+
+// MenuSeed is the portable form of the menu tree used for import and export.
+// Menus are keyed by code and linked to their parent through parent_code.
+type MenuSeed struct {
+	Menus []MenuSeedEntry `json:"menus" yaml:"menus"`
+}
+
+type MenuSeedEntry struct {
+	Code       string  `json:"code" yaml:"code"`
+	ParentCode *string `json:"parent_code" yaml:"parent_code"`
+	Name       string  `json:"name" yaml:"name"`
+	Route      *string `json:"route" yaml:"route"`
+	Icon       *string `json:"icon" yaml:"icon"`
+	Sort       int     `json:"sort" yaml:"sort"`
+	IsActive   *bool   `json:"is_active" yaml:"is_active"`
+	Permission *string `json:"permission" yaml:"permission"`
+}
+
+type MenuImportResult struct {
+	DryRun    bool             `json:"dry_run"`
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Changes   []MenuSeedChange `json:"changes"`
+}
+
+type MenuSeedChange struct {
+	Code   string            `json:"code"`
+	Action string            `json:"action"`
+	Fields []MenuFieldChange `json:"fields,omitempty"`
+}
+
+type MenuFieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
 }
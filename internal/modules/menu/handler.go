@@ -1,9 +1,16 @@
 package menu
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"kswi-backend/internal/middleware"
 	"kswi-backend/internal/shared/api"
+	"kswi-backend/internal/shared/errors"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,31 +25,74 @@ func NewHandler(service Service) *Handler {
 
 // GetMenuTree godoc
 // @Summary Get active menu tree
-// @Description Retrieves hierarchical menu structure with active menus only
+// @Description Retrieves hierarchical menu structure with active menus only. Supports
+// @Description conditional requests through If-None-Match and If-Modified-Since.
 // @Tags menu
 // @Produce json
 // @Success 200 {object} api.APIResponse{data=[]MenuResponse}
+// @Success 304 "Not Modified"
 // @Failure 500 {object} api.APIResponse
 // @Router /api/menu/tree [get]
 func (h *Handler) GetMenuTree(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	menus, err := h.service.GetMenuTree(ctx)
+	tree, err := h.service.GetMenuTree(ctx)
 	if err != nil {
 		// Error will be handled by error middleware
 		_ = c.Error(err)
 		return
 	}
 
+	if api.NotModified(c, tree.ETag, tree.LastModified) {
+		return
+	}
+
 	response := api.APIResponse{
 		Success: true,
 		Message: "Menu tree retrieved successfully",
-		Data:    menus,
+		Data:    tree.Menus,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// GetMyMenuTree godoc
+// @Summary Get the menu tree of the current user
+// @Description Retrieves the active menus the caller has permission to see. Menus that
+// @Description lead nowhere (no route and no visible children) are left out.
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} api.APIResponse{data=[]MenuResponse}
+// @Success 304 "Not Modified"
+// @Failure 401 {object} api.APIResponse
+// @Failure 500 {object} api.APIResponse
+// @Router /api/menu/me [get]
+func (h *Handler) GetMyMenuTree(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		_ = c.Error(errors.NewAuthError("Authentication required"))
+		return
+	}
+
+	menus, err := h.service.GetUserMenuTree(c.Request.Context(), claims.UserID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// The tree depends on the caller's permissions, so only the ETag is usable
+	if data, err := json.Marshal(menus); err == nil && api.NotModified(c, api.ETag(data), time.Time{}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Menu tree retrieved successfully",
+		Data:    menus,
+	})
+}
+
 func (h *Handler) GetMenuByID(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -63,3 +113,332 @@ func (h *Handler) GetMenuByID(c *gin.Context) {
 
 	c.JSON(200, menu)
 }
+
+// CreateMenu godoc
+// @Summary Create a menu
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateMenuRequest true "Menu"
+// @Success 201 {object} api.APIResponse{data=MenuDetailResponse}
+// @Failure 400 {object} api.APIResponse
+// @Failure 409 {object} api.APIResponse
+// @Router /api/menu [post]
+func (h *Handler) CreateMenu(c *gin.Context) {
+	var req CreateMenuRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	menu, err := h.service.CreateMenu(c.Request.Context(), &req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, api.APIResponse{
+		Success: true,
+		Message: "Menu created successfully",
+		Data:    menu,
+	})
+}
+
+// UpdateMenu godoc
+// @Summary Update a menu
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu ID"
+// @Param request body UpdateMenuRequest true "Menu"
+// @Success 200 {object} api.APIResponse{data=MenuDetailResponse}
+// @Failure 400 {object} api.APIResponse
+// @Failure 404 {object} api.APIResponse
+// @Failure 409 {object} api.APIResponse
+// @Router /api/menu/{id} [put]
+func (h *Handler) UpdateMenu(c *gin.Context) {
+	id, err := parseMenuID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req UpdateMenuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	menu, err := h.service.UpdateMenu(c.Request.Context(), id, &req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Menu updated successfully",
+		Data:    menu,
+	})
+}
+
+// MoveMenu godoc
+// @Summary Move a menu under another parent
+// @Description Rejects moves that would place a menu under itself or one of its descendants
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu ID"
+// @Param request body MoveMenuRequest true "New parent (0 for root)"
+// @Success 200 {object} api.APIResponse{data=MenuDetailResponse}
+// @Failure 400 {object} api.APIResponse
+// @Failure 404 {object} api.APIResponse
+// @Router /api/menu/{id}/move [patch]
+func (h *Handler) MoveMenu(c *gin.Context) {
+	id, err := parseMenuID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req MoveMenuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	menu, err := h.service.MoveMenu(c.Request.Context(), id, &req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Menu moved successfully",
+		Data:    menu,
+	})
+}
+
+// ReorderMenus godoc
+// @Summary Reorder the children of a menu
+// @Description Sets the sort of every child of the parent to its position in ids
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ReorderMenuRequest true "Parent and ordered child IDs"
+// @Success 200 {object} api.APIResponse
+// @Failure 400 {object} api.APIResponse
+// @Router /api/menu/reorder [put]
+func (h *Handler) ReorderMenus(c *gin.Context) {
+	var req ReorderMenuRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	if err := h.service.ReorderMenus(c.Request.Context(), &req); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Menus reordered successfully",
+	})
+}
+
+// SetMenuActive godoc
+// @Summary Activate or deactivate a menu
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu ID"
+// @Param request body SetMenuActiveRequest true "Active flag"
+// @Success 200 {object} api.APIResponse{data=MenuDetailResponse}
+// @Failure 404 {object} api.APIResponse
+// @Router /api/menu/{id}/active [patch]
+func (h *Handler) SetMenuActive(c *gin.Context) {
+	id, err := parseMenuID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req SetMenuActiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	menu, err := h.service.SetMenuActive(c.Request.Context(), id, *req.IsActive)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Menu updated successfully",
+		Data:    menu,
+	})
+}
+
+// DeleteMenu godoc
+// @Summary Delete a menu
+// @Description Soft deletes a menu that has no children
+// @Tags menu
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Menu ID"
+// @Success 200 {object} api.APIResponse
+// @Failure 404 {object} api.APIResponse
+// @Failure 409 {object} api.APIResponse
+// @Router /api/menu/{id} [delete]
+func (h *Handler) DeleteMenu(c *gin.Context) {
+	id, err := parseMenuID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.service.DeleteMenu(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Menu deleted successfully",
+	})
+}
+
+// ExportMenus godoc
+// @Summary Export the menu tree
+// @Description Downloads every menu keyed by code, parents first, as YAML or JSON.
+// @Description Menus without a code are skipped and reported in the X-Export-Warnings header.
+// @Tags menu
+// @Produce json,application/yaml
+// @Security BearerAuth
+// @Param format query string false "yaml or json" default(yaml)
+// @Success 200 {object} MenuSeed
+// @Failure 500 {object} api.APIResponse
+// @Router /api/menu/export [get]
+func (h *Handler) ExportMenus(c *gin.Context) {
+	format := DetectSeedFormat(c.DefaultQuery("format", SeedFormatYAML), "", "")
+
+	seed, warnings, err := h.service.ExportMenus(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	data, err := EncodeMenuSeed(seed, format)
+	if err != nil {
+		_ = c.Error(errors.NewInternalError(err))
+		return
+	}
+
+	contentType := "application/json"
+	if format == SeedFormatYAML {
+		contentType = "application/yaml"
+	}
+
+	if len(warnings) > 0 {
+		c.Header("X-Export-Warnings", strconv.Itoa(len(warnings)))
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="menus.%s"`, format))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportMenus godoc
+// @Summary Import the menu tree
+// @Description Upserts menus by code from a YAML or JSON seed sent as the request body
+// @Description or as a multipart "file". Menus missing from the seed are left untouched.
+// @Description With dry_run=true the changes are returned without being written.
+// @Tags menu
+// @Accept json,application/yaml,multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param format query string false "yaml or json, detected from the file name or content type when empty"
+// @Param dry_run query bool false "Only report the changes"
+// @Param file formData file false "Seed file"
+// @Success 200 {object} api.APIResponse{data=MenuImportResult}
+// @Failure 400 {object} api.APIResponse
+// @Router /api/menu/import [post]
+func (h *Handler) ImportMenus(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	var (
+		data     []byte
+		fileName string
+		err      error
+	)
+
+	if file, ferr := c.FormFile("file"); ferr == nil {
+		fileName = file.Filename
+		data, err = readFormFile(file)
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+	}
+	if err != nil {
+		_ = c.Error(errors.NewValidationErrorWithOriginal([]errors.ValidationError{{
+			Field:   "file",
+			Message: "Unable to read the seed file",
+		}}, err))
+		return
+	}
+
+	format := DetectSeedFormat(c.Query("format"), fileName, c.ContentType())
+
+	seed, err := DecodeMenuSeed(data, format)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	result, err := h.service.ImportMenus(c.Request.Context(), seed, dryRun)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	message := "Menus imported successfully"
+	if dryRun {
+		message = "Menu import dry run completed"
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    result,
+	})
+}
+
+func readFormFile(file *multipart.FileHeader) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+func parseMenuID(c *gin.Context) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "id",
+			Message: "Must be a positive integer",
+		}})
+	}
+	return uint(id), nil
+}
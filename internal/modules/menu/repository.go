@@ -2,15 +2,36 @@ package menu
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"kswi-backend/internal/model"
 	"sort"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type Repository interface {
 	GetMenuTree(ctx context.Context) ([]MenuResponse, error)
+	GetUserMenuTree(ctx context.Context, userID int) ([]MenuResponse, error)
+	LastModified(ctx context.Context) (time.Time, error)
 	FindByID(ctx context.Context, id uint) (*MenuDetailResponse, error)
+
+	FindMenu(ctx context.Context, id uint) (*model.Menu, error)
+	FindMenuByCode(ctx context.Context, code string) (*model.Menu, error)
+	GetParentMap(ctx context.Context) (map[uint]uint, error)
+	NextSort(ctx context.Context, parentID uint) (int, error)
+	CountChildren(ctx context.Context, id uint) (int64, error)
+	PermissionExists(ctx context.Context, id uint) (bool, error)
+	Create(ctx context.Context, menu *model.Menu) error
+	Save(ctx context.Context, menu *model.Menu) error
+	Reorder(ctx context.Context, parentID uint, ids []uint) error
+	SetActive(ctx context.Context, id uint, active bool) error
+	Delete(ctx context.Context, id uint) error
+
+	ListMenus(ctx context.Context) ([]model.Menu, error)
+	GetPermissionCodes(ctx context.Context) (map[uint]string, error)
+	Transaction(ctx context.Context, fn func(repo Repository) error) error
 }
 
 type repository struct {
@@ -27,7 +48,7 @@ func (r *repository) GetMenuTree(ctx context.Context) ([]MenuResponse, error) {
 	err := r.db.WithContext(ctx).
 		Table("menus").
 		Select("id, parent_id, sort, name, route, icon").
-		Where("is_active = ?", true).
+		Where("is_active = ? AND deleted_at IS NULL", true).
 		Order("sort ASC").
 		Find(&menus).Error
 
@@ -38,6 +59,18 @@ func (r *repository) GetMenuTree(ctx context.Context) ([]MenuResponse, error) {
 	return r.buildMenuTree(menus, 0), nil
 }
 
+// LastModified returns the time of the latest menu write, soft deletes included
+func (r *repository) LastModified(ctx context.Context) (time.Time, error) {
+	var lastModified sql.NullTime
+
+	err := r.db.WithContext(ctx).
+		Table("menus").
+		Select("MAX(GREATEST(updated_at, COALESCE(deleted_at, updated_at)))").
+		Scan(&lastModified).Error
+
+	return lastModified.Time, err
+}
+
 func (r *repository) buildMenuTree(flat []MenuResponse, parentID uint) []MenuResponse {
 	var result []MenuResponse
 
@@ -61,13 +94,55 @@ func (r *repository) buildMenuTree(flat []MenuResponse, parentID uint) []MenuRes
 	return result
 }
 
+// GetUserMenuTree returns the active menus visible to a user: menus without a
+// permission plus menus whose permission is granted by one of the user's roles
+func (r *repository) GetUserMenuTree(ctx context.Context, userID int) ([]MenuResponse, error) {
+	var menus []MenuResponse
+
+	grantedPermissions := r.db.
+		Table("role_permissions").
+		Select("role_permissions.permission_id").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID)
+
+	err := r.db.WithContext(ctx).
+		Table("menus").
+		Select("id, parent_id, sort, name, route, icon").
+		Where("is_active = ? AND deleted_at IS NULL", true).
+		Where("permission_id IS NULL OR permission_id IN (?)", grantedPermissions).
+		Order("sort ASC").
+		Find(&menus).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pruneMenuTree(r.buildMenuTree(menus, 0)), nil
+}
+
+// pruneMenuTree drops menus that lead nowhere: a menu is kept when it has a
+// route of its own or at least one visible child
+func pruneMenuTree(menus []MenuResponse) []MenuResponse {
+	result := []MenuResponse{}
+
+	for _, item := range menus {
+		item.Children = pruneMenuTree(item.Children)
+
+		if len(item.Children) > 0 || (item.Route != nil && *item.Route != "") {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
 func (r *repository) FindByID(ctx context.Context, id uint) (*MenuDetailResponse, error) {
 	var dto MenuDetailResponse
 
 	err := r.db.WithContext(ctx).
 		Table("menus").
-		Select("id, parent_id, sort, name, route, icon, is_active").
-		Where("id = ? AND is_active = ?", id, true).
+		Select("id, parent_id, permission_id, code, parent_code, sort, name, route, icon, is_active").
+		Where("id = ? AND is_active = ? AND deleted_at IS NULL", id, true).
 		First(&dto).Error
 
 	if err != nil {
@@ -79,3 +154,150 @@ func (r *repository) FindByID(ctx context.Context, id uint) (*MenuDetailResponse
 
 	return &dto, nil
 }
+
+func (r *repository) FindMenu(ctx context.Context, id uint) (*model.Menu, error) {
+	var menu model.Menu
+
+	err := r.db.WithContext(ctx).First(&menu, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &menu, nil
+}
+
+func (r *repository) FindMenuByCode(ctx context.Context, code string) (*model.Menu, error) {
+	var menu model.Menu
+
+	err := r.db.WithContext(ctx).Where("code = ?", code).First(&menu).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &menu, nil
+}
+
+// GetParentMap returns the parent ID of every menu, keyed by menu ID
+func (r *repository) GetParentMap(ctx context.Context) (map[uint]uint, error) {
+	var rows []struct {
+		ID       uint
+		ParentID uint
+	}
+
+	err := r.db.WithContext(ctx).Model(&model.Menu{}).Select("id, parent_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[uint]uint, len(rows))
+	for _, row := range rows {
+		parents[row.ID] = row.ParentID
+	}
+
+	return parents, nil
+}
+
+// NextSort returns the sort value that places a menu after its last sibling
+func (r *repository) NextSort(ctx context.Context, parentID uint) (int, error) {
+	var maxSort *int
+
+	err := r.db.WithContext(ctx).
+		Model(&model.Menu{}).
+		Select("MAX(sort)").
+		Where("parent_id = ?", parentID).
+		Scan(&maxSort).Error
+	if err != nil || maxSort == nil {
+		return 0, err
+	}
+
+	return *maxSort + 1, nil
+}
+
+func (r *repository) CountChildren(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Menu{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *repository) PermissionExists(ctx context.Context, id uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Permission{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *repository) Create(ctx context.Context, menu *model.Menu) error {
+	return r.db.WithContext(ctx).Create(menu).Error
+}
+
+// Save updates every column of the menu and keeps the parent_code of its
+// children in line with its code
+func (r *repository) Save(ctx context.Context, menu *model.Menu) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Parent", "Children").Save(menu).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.Menu{}).
+			Where("parent_id = ?", menu.ID).
+			Update("parent_code", menu.Code).Error
+	})
+}
+
+// Reorder sets the sort of the given siblings to their position in ids
+func (r *repository) Reorder(ctx context.Context, parentID uint, ids []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			err := tx.Model(&model.Menu{}).
+				Where("id = ? AND parent_id = ?", id, parentID).
+				Update("sort", i+1).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *repository) SetActive(ctx context.Context, id uint, active bool) error {
+	return r.db.WithContext(ctx).Model(&model.Menu{}).Where("id = ?", id).Update("is_active", active).Error
+}
+
+// Delete soft deletes the menu through its deleted_at column
+func (r *repository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Menu{}, id).Error
+}
+
+// ListMenus returns every menu that is not deleted, active or not
+func (r *repository) ListMenus(ctx context.Context) ([]model.Menu, error) {
+	var menus []model.Menu
+	err := r.db.WithContext(ctx).Order("parent_id ASC, sort ASC, id ASC").Find(&menus).Error
+	return menus, err
+}
+
+// GetPermissionCodes returns the code of every permission, keyed by ID
+func (r *repository) GetPermissionCodes(ctx context.Context) (map[uint]string, error) {
+	var permissions []model.Permission
+	if err := r.db.WithContext(ctx).Select("id, code").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make(map[uint]string, len(permissions))
+	for _, permission := range permissions {
+		codes[permission.ID] = permission.Code
+	}
+
+	return codes, nil
+}
+
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *repository) Transaction(ctx context.Context, fn func(repo Repository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
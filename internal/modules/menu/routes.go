@@ -2,18 +2,31 @@ package menu
 
 import (
 	"kswi-backend/internal/config"
+	"kswi-backend/internal/middleware"
+	"kswi-backend/internal/shared/cache"
 
 	"github.com/gin-gonic/gin"
 )
 
 func RegisterRoutes(r *gin.RouterGroup) {
 	repo := NewRepository(config.GetDB())
-	svc := NewService(repo)
+	svc := NewService(repo, cache.Get())
 	handler := NewHandler(svc)
 
 	menu := r.Group("/menu")
 	{
 		menu.GET("/tree", handler.GetMenuTree)
+		menu.GET("/me", handler.GetMyMenuTree)
 		menu.GET("/:id", handler.GetMenuByID)
+
+		admin := menu.Group("", middleware.RequirePermission("menu.manage"))
+		admin.POST("", handler.CreateMenu)
+		admin.GET("/export", handler.ExportMenus)
+		admin.POST("/import", handler.ImportMenus)
+		admin.PUT("/reorder", handler.ReorderMenus)
+		admin.PUT("/:id", handler.UpdateMenu)
+		admin.PATCH("/:id/move", handler.MoveMenu)
+		admin.PATCH("/:id/active", handler.SetMenuActive)
+		admin.DELETE("/:id", handler.DeleteMenu)
 	}
 }
@@ -0,0 +1,465 @@
+package menu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Seed file formats
+const (
+	SeedFormatJSON = "json"
+	SeedFormatYAML = "yaml"
+)
+
+// Actions reported by a menu import
+const (
+	SeedActionCreate    = "create"
+	SeedActionUpdate    = "update"
+	SeedActionUnchanged = "unchanged"
+)
+
+// DetectSeedFormat picks the seed format from an explicit value, a file name
+// or a content type, defaulting to JSON
+func DetectSeedFormat(format, fileName, contentType string) string {
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+		return SeedFormatYAML
+	case "json":
+		return SeedFormatJSON
+	}
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		return SeedFormatYAML
+	case ".json":
+		return SeedFormatJSON
+	}
+
+	if strings.Contains(contentType, "yaml") {
+		return SeedFormatYAML
+	}
+
+	return SeedFormatJSON
+}
+
+// EncodeMenuSeed serializes a menu seed in the given format
+func EncodeMenuSeed(seed *MenuSeed, format string) ([]byte, error) {
+	if format == SeedFormatYAML {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(seed); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), encoder.Close()
+	}
+
+	data, err := json.MarshalIndent(seed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// DecodeMenuSeed parses a menu seed, rejecting unknown fields
+func DecodeMenuSeed(data []byte, format string) (*MenuSeed, error) {
+	var seed MenuSeed
+
+	if format == SeedFormatYAML {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&seed); err != nil {
+			return nil, errors.NewValidationErrorWithOriginal([]errors.ValidationError{{
+				Field:   "file",
+				Message: fmt.Sprintf("Invalid YAML: %s", err.Error()),
+			}}, err)
+		}
+		return &seed, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&seed); err != nil {
+		return nil, errors.NewValidationErrorWithOriginal([]errors.ValidationError{{
+			Field:   "file",
+			Message: fmt.Sprintf("Invalid JSON: %s", err.Error()),
+		}}, err)
+	}
+	return &seed, nil
+}
+
+// ExportMenus returns the current menu tree keyed by code, parents before
+// children. Menus without a code cannot be referenced and are left out; the
+// returned warnings list them.
+func (s *service) ExportMenus(ctx context.Context) (*MenuSeed, []string, error) {
+	menus, err := s.repo.ListMenus(ctx)
+	if err != nil {
+		return nil, nil, errors.NewDatabaseError(fmt.Errorf("failed to list menus: %w", err))
+	}
+
+	permissionCodes, err := s.repo.GetPermissionCodes(ctx)
+	if err != nil {
+		return nil, nil, errors.NewDatabaseError(fmt.Errorf("failed to list permissions: %w", err))
+	}
+
+	byID := make(map[uint]model.Menu, len(menus))
+	children := make(map[uint][]model.Menu)
+	for _, m := range menus {
+		byID[m.ID] = m
+		children[m.ParentID] = append(children[m.ParentID], m)
+	}
+
+	seed := &MenuSeed{Menus: []MenuSeedEntry{}}
+	var warnings []string
+
+	var walk func(parentID uint)
+	walk = func(parentID uint) {
+		siblings := children[parentID]
+		sort.SliceStable(siblings, func(i, j int) bool {
+			return siblings[i].Sort < siblings[j].Sort
+		})
+
+		for _, m := range siblings {
+			if m.Code == nil || *m.Code == "" {
+				warnings = append(warnings, fmt.Sprintf("menu %d (%s) has no code and was skipped", m.ID, m.Name))
+			} else {
+				entry := MenuSeedEntry{
+					Code:     *m.Code,
+					Name:     m.Name,
+					Route:    m.Route,
+					Icon:     m.Icon,
+					Sort:     m.Sort,
+					IsActive: boolPtr(m.IsActive),
+				}
+
+				if parent, ok := byID[m.ParentID]; ok {
+					if parent.Code == nil || *parent.Code == "" {
+						warnings = append(warnings, fmt.Sprintf("menu %s has a parent without code and was exported without parent_code", *m.Code))
+					} else {
+						entry.ParentCode = parent.Code
+					}
+				}
+
+				if m.PermissionID != nil {
+					if code, ok := permissionCodes[*m.PermissionID]; ok {
+						entry.Permission = &code
+					}
+				}
+
+				seed.Menus = append(seed.Menus, entry)
+			}
+
+			walk(m.ID)
+		}
+	}
+	walk(0)
+
+	return seed, warnings, nil
+}
+
+// ImportMenus upserts the menus of a seed by code. Running the same seed twice
+// changes nothing. Menus missing from the seed are left untouched, and so is
+// a parent without code of a menu whose entry has no parent_code. With dryRun
+// the changes are computed and returned without being written.
+func (s *service) ImportMenus(ctx context.Context, seed *MenuSeed, dryRun bool) (*MenuImportResult, error) {
+	existing, err := s.repo.ListMenus(ctx)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to list menus: %w", err))
+	}
+
+	permissionCodes, err := s.repo.GetPermissionCodes(ctx)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to list permissions: %w", err))
+	}
+
+	existingByCode := make(map[string]model.Menu, len(existing))
+	codeByID := make(map[uint]string, len(existing))
+	for _, m := range existing {
+		if m.Code != nil && *m.Code != "" {
+			existingByCode[*m.Code] = m
+			codeByID[m.ID] = *m.Code
+		}
+	}
+
+	permissionIDs := make(map[string]uint, len(permissionCodes))
+	for id, code := range permissionCodes {
+		permissionIDs[code] = id
+	}
+
+	ordered, err := validateSeed(seed, existing, existingByCode, codeByID, permissionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MenuImportResult{DryRun: dryRun, Changes: []MenuSeedChange{}}
+
+	apply := func(repo Repository) error {
+		idByCode := make(map[string]uint, len(existingByCode)+len(ordered))
+		for code, m := range existingByCode {
+			idByCode[code] = m.ID
+		}
+
+		for _, entry := range ordered {
+			desired := model.Menu{
+				Code:       stringPtr(entry.Code),
+				ParentCode: entry.ParentCode,
+				Name:       entry.Name,
+				Route:      entry.Route,
+				Icon:       entry.Icon,
+				Sort:       entry.Sort,
+				IsActive:   entry.IsActive == nil || *entry.IsActive,
+			}
+			if entry.ParentCode != nil {
+				desired.ParentID = idByCode[*entry.ParentCode]
+			}
+			if entry.Permission != nil {
+				id := permissionIDs[*entry.Permission]
+				desired.PermissionID = &id
+			}
+
+			current, exists := existingByCode[entry.Code]
+			if exists && entry.ParentCode == nil && current.ParentID != 0 {
+				// A parent without code cannot be named in a seed, so an
+				// entry without parent_code keeps it instead of moving to
+				// the root
+				if _, named := codeByID[current.ParentID]; !named {
+					desired.ParentID, desired.ParentCode = current.ParentID, current.ParentCode
+				}
+			}
+			if !exists {
+				result.Created++
+				result.Changes = append(result.Changes, MenuSeedChange{Code: entry.Code, Action: SeedActionCreate})
+
+				if repo != nil {
+					if err := repo.Create(ctx, &desired); err != nil {
+						return fmt.Errorf("failed to create menu %s: %w", entry.Code, err)
+					}
+				}
+				idByCode[entry.Code] = desired.ID
+				continue
+			}
+
+			fields := diffMenu(current, desired, entry, codeByID, permissionCodes)
+			if len(fields) == 0 {
+				result.Unchanged++
+				result.Changes = append(result.Changes, MenuSeedChange{Code: entry.Code, Action: SeedActionUnchanged})
+				continue
+			}
+
+			result.Updated++
+			result.Changes = append(result.Changes, MenuSeedChange{Code: entry.Code, Action: SeedActionUpdate, Fields: fields})
+
+			if repo != nil {
+				updated := current
+				updated.ParentID = desired.ParentID
+				updated.ParentCode = desired.ParentCode
+				updated.PermissionID = desired.PermissionID
+				updated.Name = desired.Name
+				updated.Route = desired.Route
+				updated.Icon = desired.Icon
+				updated.Sort = desired.Sort
+				updated.IsActive = desired.IsActive
+
+				if err := repo.Save(ctx, &updated); err != nil {
+					return fmt.Errorf("failed to update menu %s: %w", entry.Code, err)
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if dryRun {
+		// New menus have no ID yet, so parents are compared by code only
+		_ = apply(nil)
+		return result, nil
+	}
+
+	if err := s.repo.Transaction(ctx, apply); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to import menus: %w", err))
+	}
+	if result.Created > 0 || result.Updated > 0 {
+		s.invalidateTree(ctx)
+	}
+
+	return result, nil
+}
+
+// validateSeed checks the seed entries and returns them ordered so that every
+// parent comes before its children
+func validateSeed(
+	seed *MenuSeed,
+	existing []model.Menu,
+	existingByCode map[string]model.Menu,
+	codeByID map[uint]string,
+	permissionIDs map[string]uint,
+) ([]MenuSeedEntry, error) {
+	var details []errors.ValidationError
+
+	entries := make(map[string]MenuSeedEntry, len(seed.Menus))
+	for i, entry := range seed.Menus {
+		field := fmt.Sprintf("menus[%d]", i)
+
+		switch {
+		case entry.Code == "":
+			details = append(details, errors.ValidationError{Field: field + ".code", Message: "This field is required"})
+			continue
+		case entry.Name == "":
+			details = append(details, errors.ValidationError{Field: field + ".name", Message: "This field is required"})
+		}
+
+		if _, dup := entries[entry.Code]; dup {
+			details = append(details, errors.ValidationError{Field: field + ".code", Message: fmt.Sprintf("Duplicate code '%s'", entry.Code)})
+			continue
+		}
+
+		if entry.ParentCode != nil && *entry.ParentCode == "" {
+			entry.ParentCode = nil
+		}
+		if entry.Permission != nil {
+			if _, ok := permissionIDs[*entry.Permission]; !ok {
+				details = append(details, errors.ValidationError{Field: field + ".permission", Message: fmt.Sprintf("Unknown permission '%s'", *entry.Permission)})
+			}
+		}
+
+		entries[entry.Code] = entry
+	}
+
+	// Parent of every code after the import: seed entries override the database
+	parentOf := make(map[string]*string, len(existingByCode)+len(entries))
+	for _, m := range existing {
+		if code, ok := codeByID[m.ID]; ok {
+			if parentCode, ok := codeByID[m.ParentID]; ok {
+				parentOf[code] = &parentCode
+			} else {
+				parentOf[code] = nil
+			}
+		}
+	}
+	for code, entry := range entries {
+		parentOf[code] = entry.ParentCode
+	}
+
+	for i, entry := range seed.Menus {
+		if entry.ParentCode == nil || *entry.ParentCode == "" {
+			continue
+		}
+		if _, ok := parentOf[*entry.ParentCode]; !ok {
+			details = append(details, errors.ValidationError{
+				Field:   fmt.Sprintf("menus[%d].parent_code", i),
+				Message: fmt.Sprintf("Unknown parent code '%s'", *entry.ParentCode),
+			})
+		}
+	}
+
+	// Every chain of parents must end at the root
+	for i, entry := range seed.Menus {
+		visited := map[string]struct{}{}
+		for code := entry.Code; ; {
+			if _, seen := visited[code]; seen {
+				details = append(details, errors.ValidationError{
+					Field:   fmt.Sprintf("menus[%d].parent_code", i),
+					Message: fmt.Sprintf("Menu '%s' is part of a parent cycle", entry.Code),
+				})
+				break
+			}
+			visited[code] = struct{}{}
+
+			parent := parentOf[code]
+			if parent == nil {
+				break
+			}
+			code = *parent
+		}
+	}
+
+	if len(details) > 0 {
+		return nil, errors.NewValidationError(details)
+	}
+
+	// Order entries parents first, keeping the seed order among siblings
+	ordered := make([]MenuSeedEntry, 0, len(entries))
+	placed := make(map[string]bool, len(entries))
+	var place func(code string)
+	place = func(code string) {
+		entry, ok := entries[code]
+		if !ok || placed[code] {
+			return
+		}
+		if entry.ParentCode != nil {
+			place(*entry.ParentCode)
+		}
+		placed[code] = true
+		ordered = append(ordered, entry)
+	}
+	for _, entry := range seed.Menus {
+		place(entry.Code)
+	}
+
+	return ordered, nil
+}
+
+// diffMenu lists the fields of an existing menu that the seed entry changes
+func diffMenu(current, desired model.Menu, entry MenuSeedEntry, codeByID map[uint]string, permissionCodes map[uint]string) []MenuFieldChange {
+	var fields []MenuFieldChange
+
+	var currentParent *string
+	if code, ok := codeByID[current.ParentID]; ok {
+		currentParent = &code
+	}
+	if !equalStringPtr(currentParent, entry.ParentCode) {
+		fields = append(fields, MenuFieldChange{Field: "parent_code", Old: currentParent, New: entry.ParentCode})
+	}
+
+	if current.Name != desired.Name {
+		fields = append(fields, MenuFieldChange{Field: "name", Old: current.Name, New: desired.Name})
+	}
+	if !equalStringPtr(current.Route, desired.Route) {
+		fields = append(fields, MenuFieldChange{Field: "route", Old: current.Route, New: desired.Route})
+	}
+	if !equalStringPtr(current.Icon, desired.Icon) {
+		fields = append(fields, MenuFieldChange{Field: "icon", Old: current.Icon, New: desired.Icon})
+	}
+	if current.Sort != desired.Sort {
+		fields = append(fields, MenuFieldChange{Field: "sort", Old: current.Sort, New: desired.Sort})
+	}
+	if current.IsActive != desired.IsActive {
+		fields = append(fields, MenuFieldChange{Field: "is_active", Old: current.IsActive, New: desired.IsActive})
+	}
+
+	var currentPermission *string
+	if current.PermissionID != nil {
+		if code, ok := permissionCodes[*current.PermissionID]; ok {
+			currentPermission = &code
+		}
+	}
+	if !equalStringPtr(currentPermission, entry.Permission) {
+		fields = append(fields, MenuFieldChange{Field: "permission", Old: currentPermission, New: entry.Permission})
+	}
+
+	return fields
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -2,30 +2,114 @@ package menu
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/api"
+	"kswi-backend/internal/shared/cache"
 	"kswi-backend/internal/shared/errors"
+	"kswi-backend/internal/shared/logger"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Service interface {
-	GetMenuTree(ctx context.Context) ([]MenuResponse, error)
+	GetMenuTree(ctx context.Context) (*MenuTree, error)
+	GetUserMenuTree(ctx context.Context, userID uint) ([]MenuResponse, error)
 	GetMenuByID(ctx context.Context, id uint) (*MenuDetailResponse, error)
+	CreateMenu(ctx context.Context, req *CreateMenuRequest) (*MenuDetailResponse, error)
+	UpdateMenu(ctx context.Context, id uint, req *UpdateMenuRequest) (*MenuDetailResponse, error)
+	MoveMenu(ctx context.Context, id uint, req *MoveMenuRequest) (*MenuDetailResponse, error)
+	ReorderMenus(ctx context.Context, req *ReorderMenuRequest) error
+	SetMenuActive(ctx context.Context, id uint, active bool) (*MenuDetailResponse, error)
+	DeleteMenu(ctx context.Context, id uint) error
+	ExportMenus(ctx context.Context) (*MenuSeed, []string, error)
+	ImportMenus(ctx context.Context, seed *MenuSeed, dryRun bool) (*MenuImportResult, error)
 }
 
+// The active menu tree is cached under menuTreeCacheKey plus the current
+// generation. Every menu write moves the generation on, so a tree built from
+// data read before the write is stored under a key nobody reads any more. The
+// generation outlives any tree stored before it was set.
+const (
+	menuTreeCacheKey           = "menu:tree:"
+	menuTreeGenerationCacheKey = "menu:tree-generation"
+	menuTreeCacheTTL           = time.Hour
+	menuTreeGenerationTTL      = 2 * menuTreeCacheTTL
+)
+
 type service struct {
-	repo Repository
+	repo  Repository
+	cache cache.Cache
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, treeCache cache.Cache) Service {
+	return &service{repo: repo, cache: treeCache}
 }
 
-// GetMenuTree retrieves the hierarchical menu structure
-func (s *service) GetMenuTree(ctx context.Context) ([]MenuResponse, error) {
+// GetMenuTree retrieves the hierarchical menu structure, served from the cache
+// when possible
+func (s *service) GetMenuTree(ctx context.Context) (*MenuTree, error) {
+	var tree MenuTree
+	var generation string
+	cacheable := true
+	if _, err := s.cache.Get(ctx, menuTreeGenerationCacheKey, &generation); err != nil {
+		logger.FromContext(ctx).WithModule("menu").WithError(err).Warn("Failed to read menu tree generation")
+		cacheable = false
+	} else if found, err := s.cache.Get(ctx, menuTreeCacheKey+generation, &tree); err != nil {
+		logger.FromContext(ctx).WithModule("menu").WithError(err).Warn("Failed to read cached menu tree")
+	} else if found {
+		return &tree, nil
+	}
+
+	// Read the timestamp first so a write racing the build yields an older
+	// Last-Modified, never a newer one
+	lastModified, err := s.repo.LastModified(ctx)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to get menu modification time: %w", err))
+	}
+
 	menus, err := s.repo.GetMenuTree(ctx)
 	if err != nil {
 		return nil, errors.NewDatabaseError(fmt.Errorf("failed to get menu tree: %w", err))
 	}
 
+	data, err := json.Marshal(menus)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	tree = MenuTree{
+		Menus:        menus,
+		ETag:         api.ETag(data),
+		LastModified: lastModified.UTC().Truncate(time.Second),
+	}
+
+	if cacheable {
+		if err := s.cache.Set(ctx, menuTreeCacheKey+generation, &tree, menuTreeCacheTTL); err != nil {
+			logger.FromContext(ctx).WithModule("menu").WithError(err).Warn("Failed to cache menu tree")
+		}
+	}
+
+	return &tree, nil
+}
+
+// invalidateTree moves the menu tree generation on after a write, which
+// retires the cached tree and any tree still being built from older data
+func (s *service) invalidateTree(ctx context.Context) {
+	if err := s.cache.Set(ctx, menuTreeGenerationCacheKey, uuid.NewString(), menuTreeGenerationTTL); err != nil {
+		logger.FromContext(ctx).WithModule("menu").WithError(err).Error("Failed to invalidate cached menu tree")
+	}
+}
+
+// GetUserMenuTree retrieves the menu structure filtered by the user's permissions
+func (s *service) GetUserMenuTree(ctx context.Context, userID uint) ([]MenuResponse, error) {
+	menus, err := s.repo.GetUserMenuTree(ctx, int(userID))
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to get user menu tree: %w", err))
+	}
+
 	return menus, nil
 }
 
@@ -39,3 +123,350 @@ func (s *service) GetMenuByID(ctx context.Context, id uint) (*MenuDetailResponse
 	}
 	return menu, nil
 }
+
+// CreateMenu adds a menu under the given parent. Without an explicit sort the
+// menu is placed after its last sibling.
+func (s *service) CreateMenu(ctx context.Context, req *CreateMenuRequest) (*MenuDetailResponse, error) {
+	parent, err := s.findParent(ctx, req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCode(ctx, req.Code, 0); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPermission(ctx, req.PermissionID); err != nil {
+		return nil, err
+	}
+
+	menu := &model.Menu{
+		ParentID:     req.ParentID,
+		PermissionID: req.PermissionID,
+		Code:         normalizeCode(req.Code),
+		ParentCode:   parentCode(parent),
+		Name:         req.Name,
+		Route:        req.Route,
+		Icon:         req.Icon,
+		IsActive:     true,
+	}
+
+	if req.IsActive != nil {
+		menu.IsActive = *req.IsActive
+	}
+
+	if req.Sort != nil {
+		menu.Sort = *req.Sort
+	} else if menu.Sort, err = s.repo.NextSort(ctx, req.ParentID); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to get next sort: %w", err))
+	}
+
+	if err := s.repo.Create(ctx, menu); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to create menu: %w", err))
+	}
+	s.invalidateTree(ctx)
+
+	return toDetailResponse(menu), nil
+}
+
+// UpdateMenu changes the editable fields a request sets and clears the ones
+// it lists in Clear. Use MoveMenu to change its parent.
+func (s *service) UpdateMenu(ctx context.Context, id uint, req *UpdateMenuRequest) (*MenuDetailResponse, error) {
+	menu, err := s.findMenu(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCode(ctx, req.Code, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPermission(ctx, req.PermissionID); err != nil {
+		return nil, err
+	}
+
+	if req.PermissionID != nil {
+		menu.PermissionID = req.PermissionID
+	}
+	if req.Code != nil {
+		menu.Code = normalizeCode(req.Code)
+	}
+	if req.Sort != nil {
+		menu.Sort = *req.Sort
+	}
+	menu.Name = req.Name
+	if req.Route != nil {
+		menu.Route = req.Route
+	}
+	if req.Icon != nil {
+		menu.Icon = req.Icon
+	}
+
+	for _, field := range req.Clear {
+		switch field {
+		case "permission_id":
+			menu.PermissionID = nil
+		case "code":
+			menu.Code = nil
+		case "route":
+			menu.Route = nil
+		case "icon":
+			menu.Icon = nil
+		}
+	}
+
+	if err := s.repo.Save(ctx, menu); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to update menu: %w", err))
+	}
+	s.invalidateTree(ctx)
+
+	return toDetailResponse(menu), nil
+}
+
+// MoveMenu attaches a menu to a new parent, rejecting moves that would create a cycle
+func (s *service) MoveMenu(ctx context.Context, id uint, req *MoveMenuRequest) (*MenuDetailResponse, error) {
+	menu, err := s.findMenu(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID := *req.ParentID
+
+	parent, err := s.findParent(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID != 0 {
+		parents, err := s.repo.GetParentMap(ctx)
+		if err != nil {
+			return nil, errors.NewDatabaseError(fmt.Errorf("failed to load menu hierarchy: %w", err))
+		}
+
+		if createsCycle(parents, id, parentID) {
+			return nil, errors.NewValidationError([]errors.ValidationError{{
+				Field:   "parent_id",
+				Message: "A menu cannot be moved under itself or one of its descendants",
+			}})
+		}
+	}
+
+	if req.Sort != nil {
+		menu.Sort = *req.Sort
+	} else if menu.ParentID != parentID {
+		if menu.Sort, err = s.repo.NextSort(ctx, parentID); err != nil {
+			return nil, errors.NewDatabaseError(fmt.Errorf("failed to get next sort: %w", err))
+		}
+	}
+
+	menu.ParentID = parentID
+	menu.ParentCode = parentCode(parent)
+
+	if err := s.repo.Save(ctx, menu); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to move menu: %w", err))
+	}
+	s.invalidateTree(ctx)
+
+	return toDetailResponse(menu), nil
+}
+
+// ReorderMenus sets the sort of all children of a parent to the given order
+func (s *service) ReorderMenus(ctx context.Context, req *ReorderMenuRequest) error {
+	parents, err := s.repo.GetParentMap(ctx)
+	if err != nil {
+		return errors.NewDatabaseError(fmt.Errorf("failed to load menu hierarchy: %w", err))
+	}
+
+	parentID := *req.ParentID
+	siblings := 0
+	for _, p := range parents {
+		if p == parentID {
+			siblings++
+		}
+	}
+
+	seen := make(map[uint]struct{}, len(req.IDs))
+	for _, id := range req.IDs {
+		if p, ok := parents[id]; !ok || p != parentID {
+			return errors.NewValidationError([]errors.ValidationError{{
+				Field:   "ids",
+				Message: fmt.Sprintf("Menu %d is not a child of parent %d", id, parentID),
+			}})
+		}
+		if _, dup := seen[id]; dup {
+			return errors.NewValidationError([]errors.ValidationError{{
+				Field:   "ids",
+				Message: fmt.Sprintf("Menu %d is listed more than once", id),
+			}})
+		}
+		seen[id] = struct{}{}
+	}
+
+	if len(seen) != siblings {
+		return errors.NewValidationError([]errors.ValidationError{{
+			Field:   "ids",
+			Message: "Must list every child of the parent",
+		}})
+	}
+
+	if err := s.repo.Reorder(ctx, parentID, req.IDs); err != nil {
+		return errors.NewDatabaseError(fmt.Errorf("failed to reorder menus: %w", err))
+	}
+	s.invalidateTree(ctx)
+
+	return nil
+}
+
+func (s *service) SetMenuActive(ctx context.Context, id uint, active bool) (*MenuDetailResponse, error) {
+	menu, err := s.findMenu(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetActive(ctx, id, active); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to update menu: %w", err))
+	}
+	s.invalidateTree(ctx)
+
+	menu.IsActive = active
+	return toDetailResponse(menu), nil
+}
+
+// DeleteMenu soft deletes a menu. Menus that still have children must be
+// emptied or moved first.
+func (s *service) DeleteMenu(ctx context.Context, id uint) error {
+	if _, err := s.findMenu(ctx, id); err != nil {
+		return err
+	}
+
+	children, err := s.repo.CountChildren(ctx, id)
+	if err != nil {
+		return errors.NewDatabaseError(fmt.Errorf("failed to count menu children: %w", err))
+	}
+	if children > 0 {
+		return errors.NewConflictError("Menu still has children; move or delete them first")
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return errors.NewDatabaseError(fmt.Errorf("failed to delete menu: %w", err))
+	}
+	s.invalidateTree(ctx)
+
+	return nil
+}
+
+func (s *service) findMenu(ctx context.Context, id uint) (*model.Menu, error) {
+	menu, err := s.repo.FindMenu(ctx, id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find menu: %w", err))
+	}
+	if menu == nil {
+		return nil, errors.NewNotFoundError("Menu")
+	}
+	return menu, nil
+}
+
+// findParent loads the parent menu; ID 0 is the root and has no menu
+func (s *service) findParent(ctx context.Context, parentID uint) (*model.Menu, error) {
+	if parentID == 0 {
+		return nil, nil
+	}
+
+	parent, err := s.repo.FindMenu(ctx, parentID)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find parent menu: %w", err))
+	}
+	if parent == nil {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "parent_id",
+			Message: "Parent menu does not exist",
+		}})
+	}
+
+	return parent, nil
+}
+
+// checkCode rejects a code already used by another menu
+func (s *service) checkCode(ctx context.Context, code *string, id uint) error {
+	code = normalizeCode(code)
+	if code == nil {
+		return nil
+	}
+
+	existing, err := s.repo.FindMenuByCode(ctx, *code)
+	if err != nil {
+		return errors.NewDatabaseError(fmt.Errorf("failed to find menu by code: %w", err))
+	}
+	if existing != nil && existing.ID != id {
+		return errors.NewConflictError(fmt.Sprintf("Menu code '%s' is already in use", *code))
+	}
+
+	return nil
+}
+
+func (s *service) checkPermission(ctx context.Context, permissionID *uint) error {
+	if permissionID == nil {
+		return nil
+	}
+
+	exists, err := s.repo.PermissionExists(ctx, *permissionID)
+	if err != nil {
+		return errors.NewDatabaseError(fmt.Errorf("failed to find permission: %w", err))
+	}
+	if !exists {
+		return errors.NewValidationError([]errors.ValidationError{{
+			Field:   "permission_id",
+			Message: "Permission does not exist",
+		}})
+	}
+
+	return nil
+}
+
+// createsCycle reports whether attaching menu id under parentID would make
+// the menu its own ancestor
+func createsCycle(parents map[uint]uint, id, parentID uint) bool {
+	visited := make(map[uint]struct{})
+
+	for current := parentID; current != 0; current = parents[current] {
+		if current == id {
+			return true
+		}
+		if _, ok := visited[current]; ok {
+			// The hierarchy already contains a cycle; refuse to extend it
+			return true
+		}
+		visited[current] = struct{}{}
+	}
+
+	return false
+}
+
+func normalizeCode(code *string) *string {
+	if code == nil || *code == "" {
+		return nil
+	}
+	return code
+}
+
+func parentCode(parent *model.Menu) *string {
+	if parent == nil {
+		return nil
+	}
+	return parent.Code
+}
+
+func toDetailResponse(menu *model.Menu) *MenuDetailResponse {
+	return &MenuDetailResponse{
+		ID:           menu.ID,
+		ParentID:     menu.ParentID,
+		PermissionID: menu.PermissionID,
+		Code:         menu.Code,
+		ParentCode:   menu.ParentCode,
+		Sort:         menu.Sort,
+		Name:         menu.Name,
+		Route:        menu.Route,
+		Icon:         menu.Icon,
+		IsActive:     menu.IsActive,
+	}
+}
@@ -0,0 +1,174 @@
+package menu
+
+import (
+	"context"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/cache"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCreatesCycle(t *testing.T) {
+	// 1 ─ 2 ─ 3 ─ 4, 1 ─ 5, 6 at the root, and 7 and 8 already in a loop
+	parents := map[uint]uint{1: 0, 2: 1, 3: 2, 4: 3, 5: 1, 6: 0, 7: 8, 8: 7}
+
+	tests := []struct {
+		name     string
+		id       uint
+		parentID uint
+		want     bool
+	}{
+		{name: "to the root", id: 3, parentID: 0, want: false},
+		{name: "under itself", id: 2, parentID: 2, want: true},
+		{name: "under its child", id: 2, parentID: 3, want: true},
+		{name: "under a deeper descendant", id: 1, parentID: 4, want: true},
+		{name: "under a sibling", id: 2, parentID: 5, want: false},
+		{name: "under an ancestor", id: 4, parentID: 1, want: false},
+		{name: "under another tree", id: 1, parentID: 6, want: false},
+		{name: "new menu under a leaf", id: 9, parentID: 4, want: false},
+		{name: "under an unknown parent", id: 2, parentID: 42, want: false},
+		{name: "under a menu in an existing loop", id: 6, parentID: 7, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createsCycle(parents, tt.id, tt.parentID); got != tt.want {
+				t.Errorf("createsCycle(%d, %d) = %v, want %v", tt.id, tt.parentID, got, tt.want)
+			}
+		})
+	}
+}
+
+// menuRepo keeps one menu in memory; other methods are not used
+type menuRepo struct {
+	Repository
+	menu  model.Menu
+	saved *model.Menu
+}
+
+func (r *menuRepo) FindMenu(ctx context.Context, id uint) (*model.Menu, error) {
+	menu := r.menu
+	return &menu, nil
+}
+
+func (r *menuRepo) FindMenuByCode(ctx context.Context, code string) (*model.Menu, error) {
+	return nil, nil
+}
+
+func (r *menuRepo) PermissionExists(ctx context.Context, id uint) (bool, error) {
+	return true, nil
+}
+
+func (r *menuRepo) Save(ctx context.Context, menu *model.Menu) error {
+	r.saved = menu
+	return nil
+}
+
+func TestUpdateMenu(t *testing.T) {
+	str := func(s string) *string { return &s }
+	permission, otherPermission, sort := uint(3), uint(4), 9
+
+	current := model.Menu{
+		ID:           1,
+		PermissionID: &permission,
+		Code:         str("reports"),
+		Sort:         2,
+		Name:         "Reports",
+		Route:        str("/reports"),
+		Icon:         str("chart"),
+	}
+
+	tests := []struct {
+		name string
+		req  UpdateMenuRequest
+		want model.Menu
+	}{
+		{
+			name: "omitted fields are kept",
+			req:  UpdateMenuRequest{Name: "Laporan"},
+			want: model.Menu{PermissionID: &permission, Code: str("reports"), Sort: 2, Name: "Laporan", Route: str("/reports"), Icon: str("chart")},
+		},
+		{
+			name: "set fields are changed",
+			req:  UpdateMenuRequest{Name: "Reports", PermissionID: &otherPermission, Code: str("laporan"), Sort: &sort, Route: str("/laporan"), Icon: str("table")},
+			want: model.Menu{PermissionID: &otherPermission, Code: str("laporan"), Sort: 9, Name: "Reports", Route: str("/laporan"), Icon: str("table")},
+		},
+		{
+			name: "listed fields are cleared",
+			req:  UpdateMenuRequest{Name: "Reports", Clear: []string{"permission_id", "code", "route", "icon"}},
+			want: model.Menu{Sort: 2, Name: "Reports"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &menuRepo{menu: current}
+			s := &service{repo: repo, cache: cache.NewMemoryCache()}
+
+			if _, err := s.UpdateMenu(context.Background(), 1, &tt.req); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := *repo.saved
+			got.ID = 0
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("saved %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// treeRepo builds the menu tree from memory and runs duringBuild while a
+// build is in progress; other methods are not used
+type treeRepo struct {
+	Repository
+	builds      int
+	duringBuild func()
+}
+
+func (r *treeRepo) LastModified(ctx context.Context) (time.Time, error) {
+	return time.Now(), nil
+}
+
+func (r *treeRepo) GetMenuTree(ctx context.Context) ([]MenuResponse, error) {
+	r.builds++
+	if r.duringBuild != nil {
+		r.duringBuild()
+		r.duringBuild = nil
+	}
+	return []MenuResponse{}, nil
+}
+
+func TestGetMenuTreeCache(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		racedWrite bool
+		wantBuilds int
+	}{
+		{name: "served from the cache", wantBuilds: 1},
+		{name: "tree built before a racing write is not served", racedWrite: true, wantBuilds: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &treeRepo{}
+			s := &service{repo: repo, cache: cache.NewMemoryCache()}
+			if tt.racedWrite {
+				repo.duringBuild = func() { s.invalidateTree(ctx) }
+			}
+
+			for i := 0; i < 3; i++ {
+				if _, err := s.GetMenuTree(ctx); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if repo.builds != tt.wantBuilds {
+				t.Errorf("tree built %d times, want %d", repo.builds, tt.wantBuilds)
+			}
+		})
+	}
+}
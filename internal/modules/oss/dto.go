@@ -1,71 +1,414 @@
 package oss
 
 import (
+	"encoding/json"
+	"kswi-backend/internal/model"
 	"kswi-backend/internal/shared/pagination"
 	"time"
 )
 
-type DtDatabaseResponse struct {
-	ID                     int        `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
-	LogUploadID            *int       `json:"log_upload_id" gorm:"column:_log_upload_id"`
-	IdProyek               *string    `json:"id_proyek" gorm:"column:idProyek;size:150"`
-	UraianJenisProyek      *string    `json:"uraian_jenis_proyek" gorm:"column:uraianJenisProyek;size:150"`
-	NIB                    *string    `json:"nib" gorm:"column:nib;size:25"`
-	TglDownload            *time.Time `json:"tgl_download" gorm:"column:tglDownload"`
-	TglDownloadExcel       *string    `json:"tgl_download_excel" gorm:"column:tglDownloadExcel;size:10"`
-	TglTerbitOss           *time.Time `json:"tgl_terbit_oss" gorm:"column:tglTerbitOss"`
-	TglTerbitOssExcel      *string    `json:"tgl_terbit_oss_excel" gorm:"column:tglTerbitOssExcel;size:10"`
-	TglPengajuan           *time.Time `json:"tgl_pengajuan" gorm:"column:tglPengajuan"`
-	TglPengajuanExcel      *string    `json:"tgl_pengajuan_excel" gorm:"column:tglPengajuanExcel;size:10"`
-	LastUpdateProyek       *time.Time `json:"last_update_proyek" gorm:"column:lastUpdateProyek"`
-	LastUpdateProyekRaw    *string    `json:"last_update_proyek_raw" gorm:"column:lastUpdateProyekRaw;size:150"`
-	PendaftarNIK           *string    `json:"pendaftar_nik" gorm:"column:pendaftarNIK;size:25"`
-	PendaftarTglLahir      *time.Time `json:"pendaftar_tgl_lahir" gorm:"column:pendaftarTglLahir"`
-	PendaftarGender        *string    `json:"pendaftar_gender" gorm:"column:pendaftarGender;size:25"`
-	PendaftarNama          *string    `json:"pendaftar_nama" gorm:"column:pendaftarNama;size:245"`
-	PendaftarTelp          *string    `json:"pendaftar_telp" gorm:"column:pendaftarTelp;size:445"`
-	PendaftarEmail         *string    `json:"pendaftar_email" gorm:"column:pendaftarEmail;size:145"`
-	PerusahaanNPWP         *string    `json:"perusahaan_npwp" gorm:"column:perusahaanNPWP;size:445"`
-	PerusahaanNama         *string    `json:"perusahaan_nama" gorm:"column:perusahaanNama;size:545"`
-	PerusahaanAlamat       *string    `json:"perusahaan_alamat" gorm:"column:perusahaanAlamat;size:545"`
-	PerusahaanKelurahan    *string    `json:"perusahaan_kelurahan" gorm:"column:perusahaanKelurahan;size:445"`
-	PerusahaanKecamatan    *string    `json:"perusahaan_kecamatan" gorm:"column:perusahaanKecamatan;size:445"`
-	PerusahaanKota         *string    `json:"perusahaan_kota" gorm:"column:perusahaanKota;size:445"`
-	PerusahaanProv         *string    `json:"perusahaan_prov" gorm:"column:perusahaanProv;size:445"`
-	PerusahaanLon          *string    `json:"perusahaan_lon" gorm:"column:perusahaanLon;size:145"`
-	PerusahaanLat          *string    `json:"perusahaan_lat" gorm:"column:perusahaanLat;size:145"`
-	PerusahaanSkala        *string    `json:"perusahaan_skala" gorm:"column:perusahaanSkala;size:445"`
-	PerusahaanSkalaKbli    *string    `json:"perusahaan_skala_kbli" gorm:"column:perusahaanSkalaKbli;size:445"`
-	JenisBadan             *string    `json:"jenis_badan" gorm:"column:jenisBadan;size:445"`
-	JenisBadanDetail       *string    `json:"jenis_badan_detail" gorm:"column:jenisBadanDetail;size:445"`
-	StatusNIB              *string    `json:"status_nib" gorm:"column:statusNIB;size:445"`
-	StatusPM               *string    `json:"status_pm" gorm:"column:statusPM;size:445"`
-	Resiko                 *string    `json:"resiko" gorm:"column:resiko;size:445"`
-	Kbli                   *string    `json:"kbli" gorm:"column:kbli;size:445"`
-	KbliJudul              *string    `json:"kbli_judul" gorm:"column:kbliJudul;size:445"`
-	SektorPembina          *string    `json:"sektor_pembina" gorm:"column:sektorPembina;size:445"`
-	TenagaKerja            *int       `json:"tenaga_kerja" gorm:"column:tenagaKerja"`
-	NamaProyek             *string    `json:"nama_proyek" gorm:"column:namaProyek;size:550"`
-	LuasTanah              *string    `json:"luas_tanah" gorm:"column:luasTanah;size:20"`
-	SatuanTanah            *string    `json:"satuan_tanah" gorm:"column:satuanTanah;size:20"`
-	InvModalTetap          *uint64    `json:"inv_modal_tetap" gorm:"column:invModalTetap"`
-	InvMesinPeralatanImpor *uint64    `json:"inv_mesin_peralatan_impor" gorm:"column:invMesinPeralatanImpor"`
-	InvMesinPeralatan      *uint64    `json:"inv_mesin_peralatan" gorm:"column:invMesinPeralatan"`
-	InvBeliPematanganTanah *uint64    `json:"inv_beli_pematangan_tanah" gorm:"column:invBeliPematanganTanah"`
-	InvBangunanGedung      *uint64    `json:"inv_bangunan_gedung" gorm:"column:invBangunanGedung"`
-	InvModalKerja          *uint64    `json:"inv_modal_kerja" gorm:"column:invModalKerja"`
-	InvLain                *uint64    `json:"inv_lain" gorm:"column:invLain"`
-	InvJumlah              *uint64    `json:"inv_jumlah" gorm:"column:invJumlah"`
-	InvJumlahRumus         *uint64    `json:"inv_jumlah_rumus" gorm:"column:invJumlahRumus"`
-	CreatedAt              *time.Time `json:"created_at" gorm:"column:_created_at;autoCreateTime"`
-	CreatedBy              *int       `json:"created_by" gorm:"column:_created_by"`
-	UpdatedAt              *time.Time `json:"updated_at" gorm:"column:_updated_at;autoUpdateTime"`
-	UpdatedBy              *int       `json:"updated_by" gorm:"column:_updated_by"`
-	InputManual            *int       `json:"input_manual" gorm:"column:_input_manual;default:0"`
-}
+// DtDatabaseResponse is a row of the OSS datatable
+type DtDatabaseResponse = model.OssBase
 
 type DtDatabaseRequest struct {
 	pagination.PaginationRequest
 	StartDate *time.Time `form:"start_date" time_format:"2006-01-02"`
 	EndDate   *time.Time `form:"end_date" time_format:"2006-01-02"`
 }
+
+// DtDatabasePage is a page of the OSS datatable. Totals are -1 when they were
+// not counted; the cursors are only set in cursor mode.
+type DtDatabasePage struct {
+	Data       []DtDatabaseResponse
+	Total      int
+	Filtered   int
+	NextCursor string
+	PrevCursor string
+}
+
+// dtCounts are the totals of a datatable request kept in the count cache
+type dtCounts struct {
+	Total    int `json:"total"`
+	Filtered int `json:"filtered"`
+}
+
+// ExportRequest takes the filters and sort of DtDatabaseRequest without pagination.
+// Columns lists the columns to include by JSON or column name, all when empty.
+type ExportRequest struct {
+	Search    string                 `json:"search"`
+	SortBy    string                 `json:"sort_by"`
+	SortDesc  bool                   `json:"sort_desc"`
+	Sort      []pagination.SortField `json:"sort"`
+	Filters   *pagination.Filters    `json:"filters"`
+	StartDate *time.Time             `form:"start_date" time_format:"2006-01-02"`
+	EndDate   *time.Time             `form:"end_date" time_format:"2006-01-02"`
+	Format    string                 `json:"format" binding:"omitempty,oneof=csv xlsx"`
+	Columns   []string               `json:"columns"`
+}
+
+// dtRequest returns the datatable request selecting the same rows
+func (r ExportRequest) dtRequest() DtDatabaseRequest {
+	return DtDatabaseRequest{
+		PaginationRequest: pagination.PaginationRequest{
+			Search:   r.Search,
+			SortBy:   r.SortBy,
+			SortDesc: r.SortDesc,
+			Sort:     r.Sort,
+			Filters:  r.Filters,
+		},
+		StartDate: r.StartDate,
+		EndDate:   r.EndDate,
+	}
+}
+
+// UploadInput is an OSS export file handed to the importer
+type UploadInput struct {
+	Path     string
+	FileName string
+	FileSize int64
+	Sheet    string
+	MatchNIB bool
+	UserID   int
+}
+
+// UploadStarted is returned when an upload was queued for import
+type UploadStarted struct {
+	Upload *model.LogUpload `json:"upload"`
+	Job    *model.Job       `json:"job"`
+}
+
+// UploadResult summarizes an imported OSS export and is stored as the result
+// of its job. Errors lists the rejected rows; it is capped, the full list is
+// kept in log_upload_errors.
+type UploadResult struct {
+	Upload          *model.LogUpload `json:"upload"`
+	IgnoredColumns  []string         `json:"ignored_columns"`
+	Errors          []UploadRowError `json:"errors"`
+	ErrorsTruncated bool             `json:"errors_truncated"`
+}
+
+type UploadRowError struct {
+	Row    int               `json:"row"`
+	Errors []UploadCellError `json:"errors"`
+}
+
+type UploadCellError struct {
+	Column  string `json:"column,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+type ListUploadsRequest struct {
+	Page    int    `form:"page,default=1" binding:"min=1"`
+	PerPage int    `form:"per_page,default=20" binding:"min=1,max=100"`
+	Status  string `form:"status"`
+}
+
+// UploadRowStats describes the oss_base rows still carrying an upload's ID
+type UploadRowStats struct {
+	UploadID int        `json:"-" gorm:"column:upload_id"`
+	RowCount int        `json:"row_count" gorm:"column:row_count"`
+	DateFrom *time.Time `json:"date_from" gorm:"column:date_from"`
+	DateTo   *time.Time `json:"date_to" gorm:"column:date_to"`
+}
+
+// UploadSummary is an upload with the rows it currently owns. The date range
+// is taken from tglTerbitOss.
+type UploadSummary struct {
+	model.LogUpload
+	UploadRowStats
+}
+
+type RollbackRequest struct {
+	Mode string `json:"mode" binding:"required,oneof=soft hard"`
+}
+
+// RollbackStarted is returned when the rollback of an upload was queued
+type RollbackStarted struct {
+	Upload *model.LogUpload `json:"upload"`
+	Job    *model.Job       `json:"job"`
+}
+
+// RollbackResult is the outcome of a rollback, stored as the result of its job
+type RollbackResult struct {
+	UploadID int    `json:"upload_id"`
+	Mode     string `json:"mode"`
+	Removed  int    `json:"removed"`
+	Restored int    `json:"restored"`
+	Skipped  int    `json:"skipped"`
+}
+
+type HistoryRequest struct {
+	Page    int    `form:"page,default=1" binding:"min=1"`
+	PerPage int    `form:"per_page,default=50" binding:"min=1,max=200"`
+	Field   string `form:"field"`
+}
+
+// StatsFilter selects the rows of a statistic: the filters of
+// DtDatabaseRequest plus an optional date range on a date column, by default
+// tglTerbitOss. Both ends of the range are inclusive.
+type StatsFilter struct {
+	Search    string              `json:"search"`
+	Filters   *pagination.Filters `json:"filters"`
+	StartDate *time.Time          `form:"start_date" time_format:"2006-01-02"`
+	EndDate   *time.Time          `form:"end_date" time_format:"2006-01-02"`
+	DateField string              `json:"date_field"`
+	DateFrom  string              `json:"date_from" binding:"omitempty,datetime=2006-01-02"`
+	DateTo    string              `json:"date_to" binding:"omitempty,datetime=2006-01-02"`
+}
+
+// dtRequest returns the datatable request selecting the same rows, before
+// the date range
+func (f StatsFilter) dtRequest() DtDatabaseRequest {
+	return DtDatabaseRequest{
+		PaginationRequest: pagination.PaginationRequest{
+			Search:  f.Search,
+			Filters: f.Filters,
+		},
+		StartDate: f.StartDate,
+		EndDate:   f.EndDate,
+	}
+}
+
+// StatsGroupedRequest groups a statistic by one dimension. OrderBy is key,
+// projects (default) or a measure; Limit caps the groups returned.
+type StatsGroupedRequest struct {
+	StatsFilter
+	GroupBy   string `json:"group_by" binding:"required"`
+	OrderBy   string `json:"order_by"`
+	OrderDesc *bool  `json:"order_desc"`
+	Limit     int    `json:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// StatsMeasure is the sum of a numeric column and the number of rows having
+// a value. Sums are exact and can exceed 64 bits.
+type StatsMeasure struct {
+	Sum   json.Number `json:"sum"`
+	Count int64       `json:"count"`
+}
+
+// StatsTotals are the project count and the measures of a set of rows,
+// keyed by the JSON name of the measured column
+type StatsTotals struct {
+	Projects int64                   `json:"projects"`
+	Measures map[string]StatsMeasure `json:"measures"`
+}
+
+type StatsGroup struct {
+	Key *string `json:"key"`
+	StatsTotals
+}
+
+// StatsGrouped lists the groups of a statistic. Totals cover every row,
+// including groups left out when Truncated is set.
+type StatsGrouped struct {
+	GroupBy   string       `json:"group_by"`
+	Groups    []StatsGroup `json:"groups"`
+	Truncated bool         `json:"truncated"`
+	Totals    StatsTotals  `json:"totals"`
+}
+
+// AggregateQuery describes an aggregate over oss_base. GroupBy and Measures
+// are DB columns and OrderBy an ORDER BY clause, all taken from whitelists.
+// With DayColumn the rows are grouped by its date, shifted by UTCOffset
+// seconds, before the GroupBy columns; rows without a date are left out.
+// Extremes adds the minimum and maximum of each measure.
+type AggregateQuery struct {
+	Filter    StatsFilter
+	DayColumn string
+	UTCOffset int
+	GroupBy   []string
+	Measures  []string
+	Extremes  bool
+	OrderBy   string
+	Limit     int
+}
+
+// AggregateRow is a row of an aggregate: the group keys, starting with the
+// YYYY-MM-DD date when grouped by day, the number of rows,
+// and for each measure its sum, as a decimal string, and its non-null count.
+// Mins and Maxes are set with AggregateQuery.Extremes, nil when no row has a
+// value.
+type AggregateRow struct {
+	Keys     []*string
+	Projects int64
+	Sums     []string
+	Counts   []int64
+	Mins     []*string
+	Maxes    []*string
+}
+
+// TimeSeriesRequest buckets the matching rows by Interval on DateField
+// (tglTerbitOss, tglPengajuan or _created_at) in the display timezone.
+// SplitBy makes one series per value of a dimension. Compare adds the change
+// against the previous bucket or the same bucket a year earlier, and then
+// needs DateFrom.
+type TimeSeriesRequest struct {
+	StatsFilter
+	Interval string `json:"interval" binding:"required,oneof=day week month quarter year"`
+	SplitBy  string `json:"split_by"`
+	Compare  string `json:"compare" binding:"omitempty,oneof=previous year"`
+}
+
+// TimeSeriesPoint is a bucket of a time series. Period names it (2024-01-31,
+// 2024-W05, 2024-01, 2024-Q1 or 2024) and Start is its first day.
+type TimeSeriesPoint struct {
+	Period     string            `json:"period"`
+	Start      string            `json:"start"`
+	Projects   int64             `json:"projects"`
+	Investment json.Number       `json:"investment"`
+	Change     *TimeSeriesChange `json:"change,omitempty"`
+}
+
+// TimeSeriesChange compares a bucket with an earlier one. From and To are the
+// days of the earlier bucket compared, fewer than all of them when the bucket
+// is cut by the range. Percentages are nil when the earlier value is zero.
+type TimeSeriesChange struct {
+	Period            string      `json:"period"`
+	From              string      `json:"from"`
+	To                string      `json:"to"`
+	Projects          int64       `json:"projects"`
+	Investment        json.Number `json:"investment"`
+	ProjectsDelta     int64       `json:"projects_delta"`
+	InvestmentDelta   json.Number `json:"investment_delta"`
+	ProjectsPercent   *float64    `json:"projects_percent"`
+	InvestmentPercent *float64    `json:"investment_percent"`
+}
+
+// TimeSeries is one series; Key is the dimension value of a split series
+type TimeSeries struct {
+	Key    *string           `json:"key"`
+	Points []TimeSeriesPoint `json:"points"`
+}
+
+// TimeSeriesResult holds one series, or one per dimension value when split.
+// Values beyond the largest series are summed up in Other.
+type TimeSeriesResult struct {
+	Interval  string       `json:"interval"`
+	DateField string       `json:"date_field"`
+	Timezone  string       `json:"timezone"`
+	SplitBy   string       `json:"split_by,omitempty"`
+	Compare   string       `json:"compare,omitempty"`
+	Series    []TimeSeries `json:"series"`
+	Other     *TimeSeries  `json:"other,omitempty"`
+}
+
+// PivotRequest cross-tabulates the matching rows by two dimensions. The
+// aggregate is count, sum, avg, min or max of Measure; count without a
+// measure counts projects. RowLimit and ColumnLimit cap the keys kept, by
+// project count.
+type PivotRequest struct {
+	StatsFilter
+	Rows        string `json:"rows" binding:"required"`
+	Columns     string `json:"columns" binding:"required"`
+	Aggregate   string `json:"aggregate" binding:"required,oneof=count sum avg min max"`
+	Measure     string `json:"measure"`
+	RowLimit    int    `json:"row_limit" binding:"omitempty,min=1,max=1000"`
+	ColumnLimit int    `json:"column_limit" binding:"omitempty,min=1,max=200"`
+}
+
+// PivotTable is a dense pivot: Values[i][j] is the aggregate of row key i
+// and column key j, nil for avg, min and max of an empty cell. Totals are
+// aggregated over every matching row, including keys cut by the limits.
+type PivotTable struct {
+	Rows             string           `json:"rows"`
+	Columns          string           `json:"columns"`
+	Aggregate        string           `json:"aggregate"`
+	Measure          string           `json:"measure,omitempty"`
+	RowKeys          []*string        `json:"row_keys"`
+	ColumnKeys       []*string        `json:"column_keys"`
+	Values           [][]*json.Number `json:"values"`
+	RowTotals        []*json.Number   `json:"row_totals"`
+	ColumnTotals     []*json.Number   `json:"column_totals"`
+	Total            *json.Number     `json:"total"`
+	RowsTruncated    bool             `json:"rows_truncated"`
+	ColumnsTruncated bool             `json:"columns_truncated"`
+}
+
+// FacetsRequest asks for the distinct values of Columns among the rows
+// matching the filters. Each column ignores its own filter; Limit caps the
+// values listed per column.
+type FacetsRequest struct {
+	StatsFilter
+	Columns []string `json:"columns" binding:"required,min=1,max=20"`
+	Limit   int      `json:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type FacetValue struct {
+	Value *string `json:"value"`
+	Count int64   `json:"count"`
+}
+
+// Facet lists the most frequent values of a column. Other counts the rows
+// whose value was left out of Values; Total counts every row.
+type Facet struct {
+	Column string       `json:"column"`
+	Values []FacetValue `json:"values"`
+	Other  int64        `json:"other"`
+	Total  int64        `json:"total"`
+}
+
+// GeoBackfillRequest chooses the rows of a coordinate backfill: the rows not
+// parsed yet, or every row with All
+type GeoBackfillRequest struct {
+	All bool `json:"all"`
+}
+
+// GeoBackfillResult is the outcome of a coordinate backfill, stored as the
+// result of its job, with the number of rows per geo status
+type GeoBackfillResult struct {
+	Processed int            `json:"processed"`
+	Statuses  map[string]int `json:"statuses"`
+}
+
+// MapRequest selects the projects with valid coordinates in a bounding box,
+// given as [min lon, min lat, max lon, max lat]. Below a zoom level, or when
+// the box holds too many projects, they are clustered on a grid.
+type MapRequest struct {
+	StatsFilter
+	BBox []float64 `json:"bbox" binding:"required,len=4"`
+	Zoom int       `json:"zoom" binding:"min=0,max=22"`
+}
+
+// MapQuery selects the rows of a map. With Cell the rows are clustered on a
+// grid of Cell degrees, otherwise at most Limit points are read.
+type MapQuery struct {
+	Filter StatsFilter
+	BBox   [4]float64
+	Cell   float64
+	Limit  int
+}
+
+// MapCluster is a grid cell of a clustered map, placed on the mean position
+// of its projects
+type MapCluster struct {
+	Lat        float64
+	Lon        float64
+	Projects   int64
+	Investment string
+}
+
+// FeatureCollection is a GeoJSON feature collection. Clustered tells whether
+// the features are grid clusters; Unmapped counts the matching rows left
+// off any map, by geo status (pending when not parsed yet).
+type FeatureCollection struct {
+	Type      string           `json:"type"`
+	BBox      []float64        `json:"bbox"`
+	Features  []Feature        `json:"features"`
+	Clustered bool             `json:"clustered"`
+	CellSize  float64          `json:"cell_size,omitempty"`
+	Unmapped  map[string]int64 `json:"unmapped"`
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry is a GeoJSON point, as [lon, lat]
+type Geometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
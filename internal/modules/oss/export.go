@@ -0,0 +1,476 @@
+package oss
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Export formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// xlsxContentType is the MIME type of a workbook
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// exportChunkSize is the number of rows read from the database at a time
+const exportChunkSize = 2000
+
+// xlsxMaxRows is the row limit of a worksheet; longer exports continue on a
+// new sheet
+const xlsxMaxRows = 1048576
+
+// exportLabels are the exportable columns in output order with their headers
+var exportLabels = []struct {
+	column string
+	label  string
+}{
+	{"id", "ID"},
+	{"idProyek", "ID Proyek"},
+	{"uraianJenisProyek", "Uraian Jenis Proyek"},
+	{"nib", "NIB"},
+	{"tglDownload", "Tanggal Download"},
+	{"tglTerbitOss", "Tanggal Terbit OSS"},
+	{"tglPengajuan", "Tanggal Pengajuan"},
+	{"lastUpdateProyek", "Update Terakhir Proyek"},
+	{"pendaftarNIK", "NIK Pendaftar"},
+	{"pendaftarTglLahir", "Tanggal Lahir Pendaftar"},
+	{"pendaftarGender", "Jenis Kelamin Pendaftar"},
+	{"pendaftarNama", "Nama Pendaftar"},
+	{"pendaftarTelp", "Telepon Pendaftar"},
+	{"pendaftarEmail", "Email Pendaftar"},
+	{"perusahaanNPWP", "NPWP Perusahaan"},
+	{"perusahaanNama", "Nama Perusahaan"},
+	{"perusahaanAlamat", "Alamat Perusahaan"},
+	{"perusahaanKelurahan", "Kelurahan"},
+	{"perusahaanKecamatan", "Kecamatan"},
+	{"perusahaanKota", "Kabupaten/Kota"},
+	{"perusahaanProv", "Provinsi"},
+	{"perusahaanLon", "Longitude"},
+	{"perusahaanLat", "Latitude"},
+	{"perusahaanSkala", "Skala Usaha"},
+	{"perusahaanSkalaKbli", "Skala Usaha KBLI"},
+	{"jenisBadan", "Jenis Badan Usaha"},
+	{"jenisBadanDetail", "Detail Jenis Badan Usaha"},
+	{"statusNIB", "Status NIB"},
+	{"statusPM", "Status PM"},
+	{"resiko", "Risiko"},
+	{"kbli", "KBLI"},
+	{"kbliJudul", "Judul KBLI"},
+	{"sektorPembina", "Sektor Pembina"},
+	{"tenagaKerja", "Tenaga Kerja"},
+	{"namaProyek", "Nama Proyek"},
+	{"luasTanah", "Luas Tanah"},
+	{"satuanTanah", "Satuan Luas Tanah"},
+	{"invModalTetap", "Modal Tetap"},
+	{"invMesinPeralatanImpor", "Mesin & Peralatan Impor"},
+	{"invMesinPeralatan", "Mesin & Peralatan"},
+	{"invBeliPematanganTanah", "Pembelian & Pematangan Tanah"},
+	{"invBangunanGedung", "Bangunan/Gedung"},
+	{"invModalKerja", "Modal Kerja"},
+	{"invLain", "Investasi Lain"},
+	{"invJumlah", "Jumlah Investasi"},
+	{"invJumlahRumus", "Jumlah Investasi (Rumus)"},
+	{"_created_at", "Dibuat Pada"},
+	{"_updated_at", "Diperbarui Pada"},
+	{"_geo_status", "Status Koordinat"},
+}
+
+// exportColumn is an oss_base column that can be included in an export
+type exportColumn struct {
+	name  string
+	json  string
+	label string
+	field int
+}
+
+var exportColumns, exportColumnsByName = buildExportColumns()
+
+// buildExportColumns resolves exportLabels against the gorm tags of
+// model.OssBase
+func buildExportColumns() ([]*exportColumn, map[string]*exportColumn) {
+	t := reflect.TypeOf(model.OssBase{})
+
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _ := parseGormTag(t.Field(i).Tag.Get("gorm"))
+		fields[name] = i
+	}
+
+	columns := make([]*exportColumn, len(exportLabels))
+	byName := make(map[string]*exportColumn, len(exportLabels)*2)
+	for i, l := range exportLabels {
+		field, ok := fields[l.column]
+		if !ok {
+			panic(fmt.Sprintf("oss: export column %s is not in model.OssBase", l.column))
+		}
+
+		col := &exportColumn{
+			name:  l.column,
+			json:  strings.Split(t.Field(field).Tag.Get("json"), ",")[0],
+			label: l.label,
+			field: field,
+		}
+		columns[i] = col
+		byName[col.name] = col
+		byName[col.json] = col
+	}
+
+	return columns, byName
+}
+
+// resolveExportColumns returns the columns named by JSON or DB name, in the
+// requested order, or every column when names is empty
+func resolveExportColumns(names []string) ([]*exportColumn, error) {
+	if len(names) == 0 {
+		return exportColumns, nil
+	}
+
+	var details []errors.ValidationError
+	var columns []*exportColumn
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		col, ok := exportColumnsByName[name]
+		if !ok {
+			details = append(details, errors.ValidationError{
+				Field:   fmt.Sprintf("columns[%d]", i),
+				Message: fmt.Sprintf("Unknown column '%s'", name),
+			})
+			continue
+		}
+		if !seen[col.name] {
+			seen[col.name] = true
+			columns = append(columns, col)
+		}
+	}
+
+	if len(details) > 0 {
+		return nil, errors.NewValidationError(details)
+	}
+	return columns, nil
+}
+
+// Export is a prepared datatable export. It is validated up front so that
+// errors can still be reported before the response starts.
+type Export struct {
+	Format      string
+	FileName    string
+	ContentType string
+
+	repo    Repository
+	req     DtDatabaseRequest
+	columns []*exportColumn
+}
+
+// NewExport validates an export request
+func (s *service) NewExport(ctx context.Context, req ExportRequest) (*Export, error) {
+	columns, err := resolveExportColumns(req.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	dtReq := req.dtRequest()
+	if _, _, err := dtSchema.Compile(dtReq.Filters); err != nil {
+		return nil, err
+	}
+	if _, err := dtSchema.Sort(&dtReq.PaginationRequest, "id"); err != nil {
+		return nil, err
+	}
+
+	format := req.Format
+	if format == "" {
+		format = ExportFormatCSV
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == ExportFormatXLSX {
+		contentType = xlsxContentType
+	}
+
+	return &Export{
+		Format:      format,
+		FileName:    fmt.Sprintf("oss_export_%s.%s", time.Now().Format("20060102_150405"), format),
+		ContentType: contentType,
+		repo:        s.repo,
+		req:         dtReq,
+		columns:     columns,
+	}, nil
+}
+
+// Write streams the export to w, reading the rows in chunks
+func (e *Export) Write(ctx context.Context, w io.Writer) error {
+	var out exportWriter
+	if e.Format == ExportFormatXLSX {
+		out = newXLSXExportWriter(w)
+	} else {
+		out = newCSVExportWriter(w)
+	}
+	defer out.Close()
+
+	labels := make([]string, len(e.columns))
+	names := make([]string, 0, len(e.columns))
+	for i, col := range e.columns {
+		labels[i] = col.label
+		names = append(names, col.name)
+	}
+
+	if err := out.WriteHeader(labels); err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(e.columns))
+	err := e.repo.ExportRows(ctx, e.req, names, func(rows []model.OssBase) error {
+		for i := range rows {
+			row := reflect.ValueOf(&rows[i]).Elem()
+			for j, col := range e.columns {
+				values[j] = cellValue(row.Field(col.field))
+			}
+			if err := out.WriteRow(values); err != nil {
+				return err
+			}
+		}
+		return out.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	return out.Finish()
+}
+
+// cellValue dereferences a nullable column value, nil when unset
+func cellValue(v reflect.Value) interface{} {
+	if v.IsNil() {
+		return nil
+	}
+	return v.Elem().Interface()
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// exportWriter encodes export rows in one file format
+type exportWriter interface {
+	WriteHeader(labels []string) error
+	WriteRow(values []interface{}) error
+	// Flush is called after each chunk of rows
+	Flush() error
+	// Finish completes the file after the last row
+	Finish() error
+	// Close releases the resources of the writer
+	Close() error
+}
+
+type csvExportWriter struct {
+	w      *csv.Writer
+	flush  func()
+	record []string
+}
+
+func newCSVExportWriter(w io.Writer) *csvExportWriter {
+	out := &csvExportWriter{w: csv.NewWriter(w)}
+	if f, ok := w.(interface{ Flush() }); ok {
+		out.flush = f.Flush
+	}
+	return out
+}
+
+func (c *csvExportWriter) WriteHeader(labels []string) error {
+	return c.w.Write(labels)
+}
+
+func (c *csvExportWriter) WriteRow(values []interface{}) error {
+	if c.record == nil {
+		c.record = make([]string, len(values))
+	}
+
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+			c.record[i] = ""
+		case string:
+			c.record[i] = v
+		case int:
+			c.record[i] = strconv.Itoa(v)
+		case uint64:
+			c.record[i] = strconv.FormatUint(v, 10)
+		case time.Time:
+			c.record[i] = formatExportTime(v)
+		default:
+			c.record[i] = fmt.Sprint(v)
+		}
+	}
+
+	return c.w.Write(c.record)
+}
+
+func (c *csvExportWriter) Flush() error {
+	c.w.Flush()
+	if c.flush != nil {
+		c.flush()
+	}
+	return c.w.Error()
+}
+
+func (c *csvExportWriter) Finish() error {
+	return c.Flush()
+}
+
+func (c *csvExportWriter) Close() error {
+	return nil
+}
+
+// formatExportTime writes dates without a time of day as plain dates
+func formatExportTime(t time.Time) string {
+	if isDateOnly(t) {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func isDateOnly(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+// xlsxExportWriter streams rows into a workbook whose sheet data is buffered
+// on disk by excelize; the file is written to w on Finish
+type xlsxExportWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	sheet  *excelize.StreamWriter
+	sheets int
+	row    int
+	header []interface{}
+
+	dateStyle     int
+	dateTimeStyle int
+	err           error
+}
+
+func newXLSXExportWriter(w io.Writer) *xlsxExportWriter {
+	x := &xlsxExportWriter{w: w, file: excelize.NewFile()}
+	dateFormat, dateTimeFormat := "yyyy-mm-dd", "yyyy-mm-dd hh:mm:ss"
+	x.dateStyle, x.err = x.file.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if x.err == nil {
+		x.dateTimeStyle, x.err = x.file.NewStyle(&excelize.Style{CustomNumFmt: &dateTimeFormat})
+	}
+	return x
+}
+
+func (x *xlsxExportWriter) WriteHeader(labels []string) error {
+	if x.err != nil {
+		return x.err
+	}
+
+	headerStyle, err := x.file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	x.header = make([]interface{}, len(labels))
+	for i, label := range labels {
+		x.header[i] = excelize.Cell{StyleID: headerStyle, Value: label}
+	}
+
+	return x.nextSheet()
+}
+
+// nextSheet finishes the current sheet and starts a new one with the header
+func (x *xlsxExportWriter) nextSheet() error {
+	if x.sheet != nil {
+		if err := x.sheet.Flush(); err != nil {
+			return err
+		}
+	}
+
+	x.sheets++
+	name := "Data"
+	if x.sheets == 1 {
+		if err := x.file.SetSheetName("Sheet1", name); err != nil {
+			return err
+		}
+	} else {
+		name = fmt.Sprintf("Data %d", x.sheets)
+		if _, err := x.file.NewSheet(name); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := x.file.NewStreamWriter(name)
+	if err != nil {
+		return err
+	}
+	if err := sheet.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	if err := sheet.SetColWidth(1, len(x.header), 20); err != nil {
+		return err
+	}
+	if err := sheet.SetRow("A1", x.header); err != nil {
+		return err
+	}
+
+	x.sheet = sheet
+	x.row = 1
+	return nil
+}
+
+func (x *xlsxExportWriter) WriteRow(values []interface{}) error {
+	if x.row == xlsxMaxRows {
+		if err := x.nextSheet(); err != nil {
+			return err
+		}
+	}
+	x.row++
+
+	cells := make([]interface{}, len(values))
+	for i, value := range values {
+		if t, ok := value.(time.Time); ok {
+			style := x.dateTimeStyle
+			if isDateOnly(t) {
+				style = x.dateStyle
+			}
+			cells[i] = excelize.Cell{StyleID: style, Value: t}
+			continue
+		}
+		cells[i] = value
+	}
+
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.sheet.SetRow(cell, cells)
+}
+
+func (x *xlsxExportWriter) Flush() error {
+	return nil
+}
+
+func (x *xlsxExportWriter) Finish() error {
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.file.Write(x.w)
+}
+
+func (x *xlsxExportWriter) Close() error {
+	return x.file.Close()
+}
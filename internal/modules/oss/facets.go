@@ -0,0 +1,106 @@
+package oss
+
+import (
+	"context"
+	"fmt"
+	"kswi-backend/internal/shared/errors"
+	"strings"
+)
+
+// defaultFacetLimit is the number of values listed per facet when no limit
+// is given
+const defaultFacetLimit = 20
+
+// facetColumns are the columns facets can be requested for: the statistics
+// dimensions and a few more low-cardinality columns
+var facetColumns = buildFacetColumns(
+	"perusahaanKecamatan",
+	"perusahaanSkalaKbli",
+	"jenisBadanDetail",
+	"statusNIB",
+	"uraianJenisProyek",
+	"kbliJudul",
+)
+
+func buildFacetColumns(extra ...string) map[string]bool {
+	columns := make(map[string]bool, len(statsDimensions)+len(extra))
+	for column := range statsDimensions {
+		columns[column] = true
+	}
+	for _, column := range extra {
+		columns[column] = true
+	}
+	return columns
+}
+
+// Facets counts the distinct values of each requested column. A facet drops
+// the filters on its own column, so that a dropdown keeps offering the
+// values next to the one selected; values beyond the limit are summed up in
+// Other.
+func (s *service) Facets(ctx context.Context, req FacetsRequest) ([]Facet, error) {
+	// The filters are checked as a whole before being taken apart
+	if _, _, err := dtSchema.Compile(req.Filters); err != nil {
+		return nil, err
+	}
+
+	var details []errors.ValidationError
+	columns := make([]string, 0, len(req.Columns))
+	names := make([]string, 0, len(req.Columns))
+	seen := make(map[string]bool, len(req.Columns))
+	for i, name := range req.Columns {
+		field, ok := dtSchema.Field(name)
+		if !ok || !facetColumns[field.Column] {
+			details = append(details, errors.ValidationError{
+				Field:   fmt.Sprintf("columns[%d]", i),
+				Message: fmt.Sprintf("Must be one of %s", strings.Join(sortedKeys(facetColumns), ", ")),
+			})
+			continue
+		}
+		if !seen[field.Column] {
+			seen[field.Column] = true
+			columns = append(columns, field.Column)
+			names = append(names, field.Name)
+		}
+	}
+	if len(details) > 0 {
+		return nil, errors.NewValidationError(details)
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultFacetLimit
+	}
+
+	facets := make([]Facet, len(columns))
+	for i, column := range columns {
+		filter := req.StatsFilter
+		filter.Filters = dtSchema.Without(req.Filters, column)
+
+		totals, err := s.aggregate(ctx, AggregateQuery{Filter: filter})
+		if err != nil {
+			return nil, err
+		}
+		rows, err := s.aggregate(ctx, AggregateQuery{
+			Filter:  filter,
+			GroupBy: []string{column},
+			OrderBy: "projects DESC, g0 ASC",
+			Limit:   limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		facet := Facet{Column: names[i], Values: make([]FacetValue, len(rows))}
+		if len(totals) > 0 {
+			facet.Total = totals[0].Projects
+		}
+		facet.Other = facet.Total
+		for j, row := range rows {
+			facet.Values[j] = FacetValue{Value: row.Keys[0], Count: row.Projects}
+			facet.Other -= row.Projects
+		}
+		facets[i] = facet
+	}
+
+	return facets, nil
+}
@@ -0,0 +1,117 @@
+package oss
+
+import (
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/pagination"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// dtSchema lists the oss_base columns the datatable can be filtered and
+// sorted on, by JSON or column name
+var dtSchema = pagination.NewSchema(buildFilterFields()...)
+
+// sortableColumns are the columns the datatable can be sorted on
+var sortableColumns = map[string]bool{
+	"id":               true,
+	"idProyek":         true,
+	"nib":              true,
+	"tglDownload":      true,
+	"tglTerbitOss":     true,
+	"tglPengajuan":     true,
+	"lastUpdateProyek": true,
+	"perusahaanNama":   true,
+	"perusahaanKota":   true,
+	"perusahaanProv":   true,
+	"perusahaanSkala":  true,
+	"jenisBadan":       true,
+	"statusNIB":        true,
+	"statusPM":         true,
+	"resiko":           true,
+	"kbli":             true,
+	"sektorPembina":    true,
+	"tenagaKerja":      true,
+	"namaProyek":       true,
+	"invModalTetap":    true,
+	"invModalKerja":    true,
+	"invJumlah":        true,
+	"_created_at":      true,
+	"_updated_at":      true,
+}
+
+// unfilteredColumns are handled by the queries themselves: the soft delete
+// columns, and the coordinates, which are selected by bounding box
+var unfilteredColumns = map[string]bool{
+	"_deleted_at": true,
+	"_deleted_by": true,
+	"_geo_lat":    true,
+	"_geo_lon":    true,
+}
+
+// buildFilterFields reads the filterable columns from model.OssBase
+func buildFilterFields() []pagination.Field {
+	t := reflect.TypeOf(model.OssBase{})
+
+	var fields []pagination.Field
+	for i := 0; i < t.NumField(); i++ {
+		name, _ := parseGormTag(t.Field(i).Tag.Get("gorm"))
+		if name == "" || unfilteredColumns[name] {
+			continue
+		}
+
+		field := pagination.Field{
+			Name:     strings.Split(t.Field(i).Tag.Get("json"), ",")[0],
+			Column:   name,
+			Sortable: sortableColumns[name],
+		}
+		typ := t.Field(i).Type
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+
+		switch typ {
+		case reflect.TypeOf(time.Time{}):
+			field.Type = pagination.FieldDate
+		case reflect.TypeOf(0), reflect.TypeOf(uint64(0)):
+			field.Type = pagination.FieldInt
+		default:
+			field.Type = pagination.FieldString
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// ossFieldsByColumn maps the columns of model.OssBase to their field index
+var ossFieldsByColumn = indexOssFields()
+
+func indexOssFields() map[string]int {
+	t := reflect.TypeOf(model.OssBase{})
+
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, _ := parseGormTag(t.Field(i).Tag.Get("gorm")); name != "" {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+// orderValues returns the sort key values of a row, as stored in cursors
+func orderValues(row *model.OssBase, order pagination.Order) []interface{} {
+	v := reflect.ValueOf(row).Elem()
+
+	values := make([]interface{}, len(order))
+	for i, key := range order {
+		field := v.Field(ossFieldsByColumn[key.Field.Column])
+		if field.Kind() == reflect.Ptr {
+			values[i] = cellValue(field)
+		} else {
+			values[i] = field.Interface()
+		}
+	}
+	return values
+}
@@ -0,0 +1,239 @@
+package oss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/errors"
+	"kswi-backend/internal/worker"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// JobTypeGeoBackfill is the job type of coordinate backfills
+const JobTypeGeoBackfill = "oss.geo_backfill"
+
+// geoBackfillBatchSize is the number of rows parsed and written at a time
+const geoBackfillBatchSize = 1000
+
+// indonesiaBounds is a box around Indonesia, with some margin for the outer
+// islands: min longitude, min latitude, max longitude, max latitude
+var indonesiaBounds = [4]float64{94.5, -11.5, 141.5, 6.5}
+
+// locate parses the coordinates of a row into its geo columns
+func locate(row *model.OssBase) {
+	lat, lon, status := parseCoordinates(row.PerusahaanLat, row.PerusahaanLon)
+	row.GeoLat, row.GeoLon, row.GeoStatus = lat, lon, &status
+}
+
+// parseCoordinates validates the free-text coordinates of a row. Decimal
+// commas are accepted. A pair outside Indonesia is returned with the outside
+// status; a pair that cannot be a position on earth, or is exactly 0,0,
+// yields no coordinates.
+func parseCoordinates(latText, lonText *string) (lat, lon *float64, status string) {
+	latValue, latOK, latSet := parseCoordinate(latText)
+	lonValue, lonOK, lonSet := parseCoordinate(lonText)
+
+	switch {
+	case !latSet && !lonSet:
+		return nil, nil, model.OssGeoMissing
+	case !latOK || !lonOK:
+		return nil, nil, model.OssGeoInvalid
+	case math.Abs(latValue) > 90 || math.Abs(lonValue) > 180 || (latValue == 0 && lonValue == 0):
+		return nil, nil, model.OssGeoInvalid
+	}
+
+	status = model.OssGeoValid
+	if lonValue < indonesiaBounds[0] || latValue < indonesiaBounds[1] ||
+		lonValue > indonesiaBounds[2] || latValue > indonesiaBounds[3] {
+		status = model.OssGeoOutside
+	}
+	return &latValue, &lonValue, status
+}
+
+// parseCoordinate parses a decimal degree; set is false for a blank value
+func parseCoordinate(text *string) (value float64, ok, set bool) {
+	if text == nil {
+		return 0, false, false
+	}
+	s := strings.TrimSpace(*text)
+	if s == "" || s == "-" {
+		return 0, false, false
+	}
+
+	if strings.Count(s, ",") == 1 && !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false, true
+	}
+
+	// The column holds 6 decimals
+	return math.Round(value*1e6) / 1e6, true, true
+}
+
+// StartGeoBackfill queues a job parsing the coordinates of the rows not
+// parsed yet, or of every row when all is set
+func (s *service) StartGeoBackfill(ctx context.Context, all bool, userID int) (*model.Job, error) {
+	job, err := s.jobs.Enqueue(ctx, worker.Spec{
+		Type:      JobTypeGeoBackfill,
+		CreatedBy: userID,
+		Task: func(ctx context.Context, progress *worker.Progress) (interface{}, error) {
+			return s.backfillGeo(ctx, progress, all)
+		},
+	})
+	if err != nil {
+		if err == worker.ErrQueueFull || err == worker.ErrShuttingDown {
+			return nil, errors.NewConflictError("The job queue is full, please try again later")
+		}
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to queue coordinate backfill: %w", err))
+	}
+	return job, nil
+}
+
+// backfillGeo walks the rows by id and writes their geo columns
+func (s *service) backfillGeo(ctx context.Context, progress *worker.Progress, all bool) (*GeoBackfillResult, error) {
+	total, err := s.repo.CountGeoPending(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+	progress.SetTotal(int(total))
+
+	result := &GeoBackfillResult{Statuses: map[string]int{}}
+	afterID := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		rows, err := s.repo.ListGeoPending(ctx, afterID, geoBackfillBatchSize, all)
+		if err != nil {
+			return result, err
+		}
+		if len(rows) == 0 {
+			return result, nil
+		}
+
+		for i := range rows {
+			locate(&rows[i])
+			result.Statuses[*rows[i].GeoStatus]++
+		}
+		if err := s.repo.SaveGeo(ctx, rows); err != nil {
+			return result, err
+		}
+
+		afterID = rows[len(rows)-1].ID
+		result.Processed += len(rows)
+		progress.Add(len(rows), 0)
+	}
+}
+
+const (
+	// mapClusterMaxZoom is the zoom level from which single projects are
+	// shown
+	mapClusterMaxZoom = 11
+	// maxMapPoints caps the projects of an unclustered map; busier boxes are
+	// clustered whatever the zoom
+	maxMapPoints = 5000
+	// mapClusterPixels is the size of a cluster cell on screen, with 256
+	// pixel tiles
+	mapClusterPixels = 64
+)
+
+// Map returns the projects with valid coordinates in a bounding box as
+// GeoJSON points, or as grid clusters with their project count and summed
+// investment at low zoom levels
+func (s *service) Map(ctx context.Context, req MapRequest) (*FeatureCollection, error) {
+	bbox := [4]float64{req.BBox[0], req.BBox[1], req.BBox[2], req.BBox[3]}
+	if bbox[0] < -180 || bbox[2] > 180 || bbox[1] < -90 || bbox[3] > 90 || bbox[0] > bbox[2] || bbox[1] > bbox[3] {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "bbox",
+			Message: "Must be [min lon, min lat, max lon, max lat] within -180,-90,180,90",
+		}})
+	}
+
+	result := &FeatureCollection{
+		Type:     "FeatureCollection",
+		BBox:     bbox[:],
+		Features: []Feature{},
+	}
+	q := MapQuery{Filter: req.StatsFilter, BBox: bbox}
+
+	if req.Zoom >= mapClusterMaxZoom {
+		q.Limit = maxMapPoints + 1
+		rows, err := s.repo.MapPoints(ctx, q)
+		if err != nil {
+			return nil, mapError(err)
+		}
+
+		if len(rows) <= maxMapPoints {
+			for _, row := range rows {
+				result.Features = append(result.Features, Feature{
+					Type:     "Feature",
+					ID:       row.ID,
+					Geometry: Geometry{Type: "Point", Coordinates: [2]float64{*row.GeoLon, *row.GeoLat}},
+					Properties: map[string]interface{}{
+						"id":              row.ID,
+						"id_proyek":       row.IdProyek,
+						"nama_proyek":     row.NamaProyek,
+						"perusahaan_nama": row.PerusahaanNama,
+						"status_pm":       row.StatusPM,
+						"inv_jumlah":      row.InvJumlah,
+					},
+				})
+			}
+		} else {
+			result.Clustered = true
+		}
+	} else {
+		result.Clustered = true
+	}
+
+	if result.Clustered {
+		q.Cell = mapClusterPixels * 360 / (256 * math.Exp2(float64(req.Zoom)))
+		clusters, err := s.repo.MapClusters(ctx, q)
+		if err != nil {
+			return nil, mapError(err)
+		}
+
+		result.CellSize = q.Cell
+		for _, c := range clusters {
+			result.Features = append(result.Features, Feature{
+				Type:     "Feature",
+				Geometry: Geometry{Type: "Point", Coordinates: [2]float64{c.Lon, c.Lat}},
+				Properties: map[string]interface{}{
+					"cluster":    true,
+					"projects":   c.Projects,
+					"investment": json.Number(c.Investment),
+				},
+			})
+		}
+	}
+
+	statuses, err := s.aggregate(ctx, AggregateQuery{Filter: req.StatsFilter, GroupBy: []string{"_geo_status"}})
+	if err != nil {
+		return nil, err
+	}
+	result.Unmapped = map[string]int64{}
+	for _, row := range statuses {
+		switch {
+		case row.Keys[0] == nil:
+			result.Unmapped["pending"] += row.Projects
+		case *row.Keys[0] != model.OssGeoValid:
+			result.Unmapped[*row.Keys[0]] += row.Projects
+		}
+	}
+
+	return result, nil
+}
+
+func mapError(err error) error {
+	var appErr *errors.AppError
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return errors.NewDatabaseError(fmt.Errorf("failed to read map: %w", err))
+}
@@ -0,0 +1,47 @@
+package oss
+
+import (
+	"kswi-backend/internal/model"
+	"testing"
+)
+
+func TestParseCoordinates(t *testing.T) {
+	tests := []struct {
+		name       string
+		lat, lon   *string
+		wantStatus string
+		wantLat    float64
+		wantLon    float64
+	}{
+		{name: "valid", lat: strPtr("-6.2088"), lon: strPtr("106.8456"), wantStatus: model.OssGeoValid, wantLat: -6.2088, wantLon: 106.8456},
+		{name: "decimal commas and spaces", lat: strPtr(" -6,2088 "), lon: strPtr("106,8456"), wantStatus: model.OssGeoValid, wantLat: -6.2088, wantLon: 106.8456},
+		{name: "rounded to 6 decimals", lat: strPtr("-6.20881234"), lon: strPtr("106.84569999"), wantStatus: model.OssGeoValid, wantLat: -6.208812, wantLon: 106.8457},
+		{name: "within the margin of the box", lat: strPtr("1.3521"), lon: strPtr("103.8198"), wantStatus: model.OssGeoValid, wantLat: 1.3521, wantLon: 103.8198},
+		{name: "swapped", lat: strPtr("106.8456"), lon: strPtr("-6.2088"), wantStatus: model.OssGeoInvalid},
+		{name: "far away", lat: strPtr("48.8566"), lon: strPtr("2.3522"), wantStatus: model.OssGeoOutside, wantLat: 48.8566, wantLon: 2.3522},
+		{name: "both missing", wantStatus: model.OssGeoMissing},
+		{name: "blank and dash", lat: strPtr(" "), lon: strPtr("-"), wantStatus: model.OssGeoMissing},
+		{name: "one missing", lat: strPtr("-6.2"), wantStatus: model.OssGeoInvalid},
+		{name: "text", lat: strPtr("-6.2"), lon: strPtr("timur"), wantStatus: model.OssGeoInvalid},
+		{name: "thousands comma with a dot", lat: strPtr("-6.2"), lon: strPtr("1,068.456"), wantStatus: model.OssGeoInvalid},
+		{name: "zero", lat: strPtr("0"), lon: strPtr("0"), wantStatus: model.OssGeoInvalid},
+		{name: "not a number", lat: strPtr("NaN"), lon: strPtr("106.8"), wantStatus: model.OssGeoInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lon, status := parseCoordinates(tt.lat, tt.lon)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", status, tt.wantStatus)
+			}
+
+			located := tt.wantStatus == model.OssGeoValid || tt.wantStatus == model.OssGeoOutside
+			if (lat != nil) != located || (lon != nil) != located {
+				t.Fatalf("coordinates = %v, %v; want them only when located", lat, lon)
+			}
+			if located && (*lat != tt.wantLat || *lon != tt.wantLon) {
+				t.Errorf("coordinates = %v, %v; want %v, %v", *lat, *lon, tt.wantLat, tt.wantLon)
+			}
+		})
+	}
+}
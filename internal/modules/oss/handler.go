@@ -1,9 +1,23 @@
 package oss
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/middleware"
+	"kswi-backend/internal/shared/api"
 	"kswi-backend/internal/shared/errors"
+	"kswi-backend/internal/shared/logger"
 	"kswi-backend/internal/shared/pagination"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,20 +40,690 @@ func (h *Handler) DtDatabase(c *gin.Context) {
 		return
 	}
 
-	data, total, filtered, err := h.svc.DtDatabase(c.Request.Context(), req)
+	page, err := h.svc.DtDatabase(c.Request.Context(), req)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
+	c.JSON(http.StatusOK, pagination.BuildResponse(
+		pagination.ResponseParam{
+			Ctx:        c,
+			Req:        req.PaginationRequest,
+			Data:       page.Data,
+			Total:      page.Total,
+			Filtered:   page.Filtered,
+			NextCursor: page.NextCursor,
+			PrevCursor: page.PrevCursor,
+		},
+	))
+
+}
+
+// Facets godoc
+// @Summary Distinct values for datatable filter dropdowns
+// @Description Lists the most frequent values of each requested column, with their project
+// @Description counts, among the rows matching the datatable filters. Each column ignores the
+// @Description filters on itself; the rows of values beyond the limit are counted in other.
+// @Tags oss
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body FacetsRequest true "Columns, limit and filters"
+// @Success 200 {object} api.APIResponse{data=[]Facet}
+// @Failure 400 {object} api.APIResponse
+// @Router /api/oss/facets [post]
+func (h *Handler) Facets(c *gin.Context) {
+	var req FacetsRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	facets, err := h.svc.Facets(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Facets retrieved successfully",
+		Data:    facets,
+	})
+}
+
+// Map godoc
+// @Summary Projects on a map
+// @Description Returns the projects matching the datatable filters whose coordinates lie in
+// @Description bbox ([min lon, min lat, max lon, max lat]) as a GeoJSON FeatureCollection.
+// @Description Below zoom 11, or when the box holds more than 5000 projects, the features are
+// @Description grid clusters with their project count and summed invJumlah. Rows with missing,
+// @Description invalid or out-of-Indonesia coordinates are counted in unmapped.
+// @Tags oss
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MapRequest true "Bounding box, zoom and filters"
+// @Success 200 {object} api.APIResponse{data=FeatureCollection}
+// @Failure 400 {object} api.APIResponse
+// @Router /api/oss/map [post]
+func (h *Handler) Map(c *gin.Context) {
+	var req MapRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	features, err := h.svc.Map(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Map retrieved successfully",
+		Data:    features,
+	})
+}
+
+// GeoBackfill godoc
+// @Summary Parse project coordinates
+// @Description Queues a job parsing perusahaanLat and perusahaanLon into the geo columns of
+// @Description the rows not parsed yet, or of every row with all. Each row is flagged valid,
+// @Description missing, invalid or outside (Indonesia).
+// @Tags oss
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body GeoBackfillRequest false "Rows to parse"
+// @Success 202 {object} api.APIResponse{data=model.Job}
+// @Failure 409 {object} api.APIResponse
+// @Router /api/oss/geo/backfill [post]
+func (h *Handler) GeoBackfill(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		_ = c.Error(errors.NewAuthError("Authentication required"))
+		return
+	}
+
+	var req GeoBackfillRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			_ = c.Error(errors.HandleValidationError(err))
+			return
+		}
+	}
+
+	job, err := h.svc.StartGeoBackfill(c.Request.Context(), req.All, int(claims.UserID))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, api.APIResponse{
+		Success: true,
+		Message: "Coordinate backfill queued",
+		Data:    job,
+	})
+}
+
+// Export godoc
+// @Summary Export the OSS datatable
+// @Description Streams the rows matching the datatable filters as CSV or XLSX, without
+// @Description pagination. Columns are chosen by JSON or column name and get readable
+// @Description headers; every column is included when none are given.
+// @Tags oss
+// @Accept json
+// @Produce text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security BearerAuth
+// @Param request body ExportRequest true "Filters, format and columns"
+// @Success 200 {file} file
+// @Failure 400 {object} api.APIResponse
+// @Router /api/oss/export [post]
+func (h *Handler) Export(c *gin.Context) {
+	var req ExportRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	export, err := h.svc.NewExport(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// Large exports outlive server.write_timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", export.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName))
+	c.Status(http.StatusOK)
+
+	if err := export.Write(c.Request.Context(), c.Writer); err != nil {
+		// The response has started, so the error can only be logged
+		logger.FromContext(c.Request.Context()).
+			WithModule("oss").
+			WithError(err).
+			Error("OSS export failed")
+		c.Abort()
+	}
+}
+
+// StatsSummary godoc
+// @Summary Investment totals over oss_base
+// @Description Counts the projects matching the datatable filters and an optional date range,
+// @Description and sums invJumlah, invModalTetap, invModalKerja and tenagaKerja.
+// @Tags oss
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body StatsFilter true "Filters and date range"
+// @Success 200 {object} api.APIResponse{data=StatsTotals}
+// @Failure 400 {object} api.APIResponse
+// @Router /api/oss/stats/summary [post]
+func (h *Handler) StatsSummary(c *gin.Context) {
+	var req StatsFilter
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	totals, err := h.svc.StatsSummary(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Statistics retrieved successfully",
+		Data:    totals,
+	})
+}
+
+// StatsGrouped godoc
+// @Summary Investment totals grouped by a dimension
+// @Description Like /api/oss/stats/summary, per value of group_by: perusahaanProv,
+// @Description perusahaanKota, kbli, sektorPembina, perusahaanSkala, statusPM, resiko or jenisBadan.
+// @Tags oss
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body StatsGroupedRequest true "Dimension, filters and date range"
+// @Success 200 {object} api.APIResponse{data=StatsGrouped}
+// @Failure 400 {object} api.APIResponse
+// @Router /api/oss/stats/grouped [post]
+func (h *Handler) StatsGrouped(c *gin.Context) {
+	var req StatsGroupedRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	grouped, err := h.svc.StatsGrouped(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Statistics retrieved successfully",
+		Data:    grouped,
+	})
+}
+
+// TimeSeries godoc
+// @Summary Project and investment trend
+// @Description Buckets the matching projects by day, week, month, quarter or year of
+// @Description tglTerbitOss, tglPengajuan or _created_at, in the display timezone
+// @Description (app.timezone). Each bucket has the project count and summed invJumlah;
+// @Description empty buckets are zero-filled. split_by gives one series per dimension value,
+// @Description compare adds the change against the previous period or the year before.
+// @Tags oss
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body TimeSeriesRequest true "Interval, date column, filters and options"
+// @Success 200 {object} api.APIResponse{data=TimeSeriesResult}
+// @Failure 400 {object} api.APIResponse
+// @Router /api/oss/stats/timeseries [post]
+func (h *Handler) TimeSeries(c *gin.Context) {
+	var req TimeSeriesRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	series, err := h.svc.TimeSeries(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Time series retrieved successfully",
+		Data:    series,
+	})
+}
+
+// Pivot godoc
+// @Summary Pivot of oss_base by two dimensions
+// @Description Cross-tabulates the matching projects by a row and a column dimension into a
+// @Description dense matrix with row, column and grand totals. The aggregate is count, sum,
+// @Description avg, min or max of invJumlah, invModalTetap, invModalKerja or tenagaKerja;
+// @Description count without a measure counts projects.
+// @Tags oss
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body PivotRequest true "Dimensions, aggregate and filters"
+// @Success 200 {object} api.APIResponse{data=PivotTable}
+// @Failure 400 {object} api.APIResponse
+// @Router /api/oss/stats/pivot [post]
+func (h *Handler) Pivot(c *gin.Context) {
+	var req PivotRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	table, err := h.svc.Pivot(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Pivot retrieved successfully",
+		Data:    table,
+	})
+}
+
+// PivotExport godoc
+// @Summary Export a pivot of oss_base to XLSX
+// @Description Builds the same pivot as /api/oss/stats/pivot and downloads it as a workbook
+// @Description with labelled headers and totals.
+// @Tags oss
+// @Accept json
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security BearerAuth
+// @Param request body PivotRequest true "Dimensions, aggregate and filters"
+// @Success 200 {file} file
+// @Failure 400 {object} api.APIResponse
+// @Router /api/oss/stats/pivot/export [post]
+func (h *Handler) PivotExport(c *gin.Context) {
+	var req PivotRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	table, err := h.svc.Pivot(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := table.WriteXLSX(&buf); err != nil {
+		_ = c.Error(errors.NewInternalError(fmt.Errorf("failed to write pivot: %w", err)))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, table.FileName()))
+	c.Data(http.StatusOK, xlsxContentType, buf.Bytes())
+}
+
+// maxUploadSize is the largest OSS export accepted by Upload
+const maxUploadSize = 100 << 20
+
+// Upload godoc
+// @Summary Upload an OSS export
+// @Description Queues the import of an OSS .xlsx export into oss_base and returns the
+// @Description job to poll at /api/jobs/{id}. Headers are matched to columns ignoring
+// @Description case, spaces and punctuation. Rows matching an existing project on idProyek
+// @Description (and nib with match_nib) update it only when their lastUpdateProyek is newer.
+// @Description Invalid rows are skipped and reported in the job result; the upload and its
+// @Description inserted/updated/unchanged counts are recorded in log_uploads.
+// @Tags oss
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "OSS export (.xlsx)"
+// @Param sheet formData string false "Sheet name, defaults to the first sheet"
+// @Param match_nib formData bool false "Match existing projects on idProyek and nib"
+// @Success 202 {object} api.APIResponse{data=UploadStarted}
+// @Failure 400 {object} api.APIResponse
+// @Failure 403 {object} api.APIResponse
+// @Failure 409 {object} api.APIResponse
+// @Router /api/oss/upload [post]
+func (h *Handler) Upload(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		_ = c.Error(errors.NewAuthError("Authentication required"))
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		_ = c.Error(errors.NewValidationError([]errors.ValidationError{{
+			Field:   "file",
+			Message: "This field is required",
+		}}))
+		return
+	}
+
+	if !strings.EqualFold(filepath.Ext(file.Filename), ".xlsx") {
+		_ = c.Error(errors.NewValidationError([]errors.ValidationError{{
+			Field:   "file",
+			Message: "Must be an .xlsx file",
+		}}))
+		return
+	}
+
+	if file.Size > maxUploadSize {
+		_ = c.Error(errors.NewValidationError([]errors.ValidationError{{
+			Field:   "file",
+			Message: fmt.Sprintf("Must be at most %d MB", maxUploadSize>>20),
+		}}))
+		return
+	}
+
+	matchNIB, err := strconv.ParseBool(c.DefaultPostForm("match_nib", "false"))
+	if err != nil {
+		_ = c.Error(errors.NewValidationError([]errors.ValidationError{{
+			Field:   "match_nib",
+			Message: "Must be true or false",
+		}}))
+		return
+	}
+
+	// The import job outlives the request, so it gets its own copy of the file
+	path, err := saveUpload(file)
+	if err != nil {
+		_ = c.Error(errors.NewInternalError(fmt.Errorf("failed to store upload: %w", err)))
+		return
+	}
+
+	started, err := h.svc.StartUpload(c.Request.Context(), UploadInput{
+		Path:     path,
+		FileName: filepath.Base(file.Filename),
+		FileSize: file.Size,
+		Sheet:    c.PostForm("sheet"),
+		MatchNIB: matchNIB,
+		UserID:   int(claims.UserID),
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, api.APIResponse{
+		Success: true,
+		Message: "Upload queued for import",
+		Data:    started,
+	})
+}
+
+// ListUploads godoc
+// @Summary List OSS uploads
+// @Description Lists uploads newest first with the number of oss_base rows each one still
+// @Description owns and the tglTerbitOss range of those rows
+// @Tags oss
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param status query string false "Filter by status"
+// @Success 200 {object} pagination.PaginationResponse{data=pagination.PaginationResponseData{data=[]UploadSummary}}
+// @Failure 400 {object} api.APIResponse
+// @Router /api/oss/uploads [get]
+func (h *Handler) ListUploads(c *gin.Context) {
+	var req ListUploadsRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	uploads, total, err := h.svc.ListUploads(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pagination.BuildResponse(
+		pagination.ResponseParam{
+			Ctx:      c,
+			Req:      pagination.PaginationRequest{Page: req.Page, PerPage: req.PerPage},
+			Data:     uploads,
+			Total:    total,
+			Filtered: total,
+		},
+	))
+}
+
+// GetUpload godoc
+// @Summary Get an OSS upload
+// @Tags oss
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Upload ID"
+// @Success 200 {object} api.APIResponse{data=UploadSummary}
+// @Failure 404 {object} api.APIResponse
+// @Router /api/oss/uploads/{id} [get]
+func (h *Handler) GetUpload(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	upload, err := h.svc.GetUpload(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Upload retrieved successfully",
+		Data:    upload,
+	})
+}
+
+// RollbackUpload godoc
+// @Summary Roll back an OSS upload
+// @Description Queues a job that undoes a completed upload in one transaction. Rows the
+// @Description upload overwrote get their previous version back; rows it created are
+// @Description marked deleted (soft) or removed (hard). Rows changed again by a later
+// @Description upload or edited by hand since are left alone and counted as skipped.
+// @Tags oss
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Upload ID"
+// @Param request body RollbackRequest true "Rollback mode"
+// @Success 202 {object} api.APIResponse{data=RollbackStarted}
+// @Failure 404 {object} api.APIResponse
+// @Failure 409 {object} api.APIResponse
+// @Router /api/oss/uploads/{id}/rollback [post]
+func (h *Handler) RollbackUpload(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		_ = c.Error(errors.NewAuthError("Authentication required"))
+		return
+	}
+
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req RollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	started, err := h.svc.StartRollback(c.Request.Context(), id, req.Mode, int(claims.UserID))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, api.APIResponse{
+		Success: true,
+		Message: "Rollback queued",
+		Data:    started,
+	})
+}
+
+// UpdateProject godoc
+// @Summary Edit an oss_base row
+// @Description Sets the given fields, keyed by JSON or column name, and records each changed
+// @Description field in the row's history. A null value clears the field.
+// @Tags oss
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Row ID"
+// @Param request body map[string]interface{} true "Fields to change"
+// @Success 200 {object} api.APIResponse{data=DtDatabaseResponse}
+// @Failure 400 {object} api.APIResponse
+// @Failure 404 {object} api.APIResponse
+// @Router /api/oss/{id} [patch]
+func (h *Handler) UpdateProject(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		_ = c.Error(errors.NewAuthError("Authentication required"))
+		return
+	}
+
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var patch map[string]interface{}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&patch); err != nil {
+		_ = c.Error(errors.NewValidationErrorWithOriginal([]errors.ValidationError{{
+			Field:   "body",
+			Message: "Must be a JSON object",
+		}}, err))
+		return
+	}
+
+	row, err := h.svc.UpdateProject(c.Request.Context(), id, patch, int(claims.UserID))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Project updated successfully",
+		Data:    row,
+	})
+}
+
+// ProjectHistory godoc
+// @Summary Get the change history of an oss_base row
+// @Description Lists field changes newest first. Each change records the old and new value,
+// @Description its source (import, manual or rollback), the upload and the user behind it.
+// @Tags oss
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Row ID"
+// @Param page query int false "Page" default(1)
+// @Param per_page query int false "Items per page" default(50)
+// @Param field query string false "Comma-separated fields to include, by JSON or column name"
+// @Success 200 {object} pagination.PaginationResponse{data=pagination.PaginationResponseData{data=[]model.OssBaseHistory}}
+// @Failure 400 {object} api.APIResponse
+// @Failure 404 {object} api.APIResponse
+// @Router /api/oss/{id}/history [get]
+func (h *Handler) ProjectHistory(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req HistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		_ = c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	history, total, err := h.svc.GetProjectHistory(c.Request.Context(), id, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
 	c.JSON(http.StatusOK, pagination.BuildResponse(
 		pagination.ResponseParam{
 			Ctx:      c,
-			Req:      req.PaginationRequest,
-			Data:     data,
+			Req:      pagination.PaginationRequest{Page: req.Page, PerPage: req.PerPage},
+			Data:     history,
 			Total:    total,
-			Filtered: filtered,
+			Filtered: total,
 		},
 	))
+}
+
+func parseID(c *gin.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "id",
+			Message: "Must be a positive integer",
+		}})
+	}
+	return id, nil
+}
+
+// saveUpload copies an uploaded file to the worker temp directory
+func saveUpload(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp(config.Get().Worker.TempDir, "oss-upload-*.xlsx")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
 
+	return dst.Name(), nil
 }
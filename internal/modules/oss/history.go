@@ -0,0 +1,243 @@
+package oss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ossColumnsByName finds an editable column by its DB or JSON name
+var ossColumnsByName = indexOssColumns(ossColumns)
+
+func indexOssColumns(columns []*ossColumn) map[string]*ossColumn {
+	byName := make(map[string]*ossColumn, len(columns)*2)
+	for _, col := range columns {
+		byName[col.name] = col
+		byName[col.json] = col
+	}
+	return byName
+}
+
+// fieldChange is a column whose value differs between two versions of a row
+type fieldChange struct {
+	column   string
+	oldValue *string
+	newValue *string
+}
+
+// diffRows lists the data columns that differ between two versions of a row.
+// Bookkeeping columns and the raw copies of dates are not compared.
+func diffRows(oldRow, newRow *model.OssBase) []fieldChange {
+	var changes []fieldChange
+
+	o := reflect.ValueOf(oldRow).Elem()
+	n := reflect.ValueOf(newRow).Elem()
+	for _, col := range ossColumns {
+		oldValue := formatValue(o.Field(col.field))
+		newValue := formatValue(n.Field(col.field))
+
+		if !equalValues(oldValue, newValue) {
+			changes = append(changes, fieldChange{column: col.name, oldValue: oldValue, newValue: newValue})
+		}
+	}
+
+	return changes
+}
+
+// newHistory turns the changes of one row into history entries sharing a change ID
+func newHistory(rowID int, changes []fieldChange, source string, uploadID, userID *int, at time.Time) []model.OssBaseHistory {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	changeID := uuid.NewString()
+	history := make([]model.OssBaseHistory, len(changes))
+	for i, change := range changes {
+		history[i] = model.OssBaseHistory{
+			ChangeID:    changeID,
+			OssBaseID:   rowID,
+			Field:       change.column,
+			OldValue:    change.oldValue,
+			NewValue:    change.newValue,
+			Source:      source,
+			LogUploadID: uploadID,
+			ChangedBy:   userID,
+			ChangedAt:   at,
+		}
+	}
+	return history
+}
+
+// formatValue renders a nullable column value for the history
+func formatValue(v reflect.Value) *string {
+	if v.IsNil() {
+		return nil
+	}
+
+	var s string
+	switch value := v.Elem().Interface().(type) {
+	case string:
+		s = value
+	case int:
+		s = strconv.Itoa(value)
+	case uint64:
+		s = strconv.FormatUint(value, 10)
+	case time.Time:
+		if value.Equal(value.Truncate(24 * time.Hour)) {
+			s = value.Format("2006-01-02")
+		} else {
+			s = value.Format(time.RFC3339)
+		}
+	default:
+		s = fmt.Sprint(value)
+	}
+	return &s
+}
+
+func equalValues(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// applyPatch sets the columns named in patch, keyed by JSON or DB name.
+// Numbers are expected as json.Number; a null value clears the column.
+func applyPatch(row *model.OssBase, patch map[string]interface{}) []errors.ValidationError {
+	var details []errors.ValidationError
+
+	v := reflect.ValueOf(row).Elem()
+	for name, value := range patch {
+		col, ok := ossColumnsByName[name]
+		if !ok {
+			details = append(details, errors.ValidationError{Field: name, Message: "Unknown or read-only field"})
+			continue
+		}
+
+		if value == nil {
+			v.Field(col.field).Set(reflect.Zero(v.Field(col.field).Type()))
+			if col.rawField >= 0 {
+				v.Field(col.rawField).Set(reflect.Zero(v.Field(col.rawField).Type()))
+			}
+			continue
+		}
+
+		var text string
+		switch typed := value.(type) {
+		case string:
+			text = strings.TrimSpace(typed)
+		case json.Number:
+			text = typed.String()
+		default:
+			details = append(details, errors.ValidationError{Field: name, Message: "Must be a string, a number or null"})
+			continue
+		}
+
+		if text == "" {
+			details = append(details, errors.ValidationError{Field: name, Message: "Use null to clear a field"})
+			continue
+		}
+
+		if err := col.set(v, text); err != nil {
+			details = append(details, errors.ValidationError{Field: name, Message: err.Error()})
+		}
+	}
+
+	return details
+}
+
+// UpdateProject applies a manual edit to an oss_base row and records the
+// changed fields in its history
+func (s *service) UpdateProject(ctx context.Context, id int, patch map[string]interface{}, userID int) (*model.OssBase, error) {
+	if len(patch) == 0 {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "body",
+			Message: "At least one field is required",
+		}})
+	}
+
+	row, err := s.findProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *row
+	if details := applyPatch(&updated, patch); len(details) > 0 {
+		return nil, errors.NewValidationError(details)
+	}
+
+	if updated.IdProyek == nil {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "id_proyek",
+			Message: "This field is required",
+		}})
+	}
+
+	changes := diffRows(row, &updated)
+	if len(changes) == 0 {
+		return row, nil
+	}
+
+	now := time.Now()
+	updated.UpdatedAt = &now
+	updated.UpdatedBy = &userID
+	locate(&updated)
+	history := newHistory(id, changes, model.OssChangeManual, nil, &userID, now)
+
+	if err := s.repo.SaveProjectEdit(ctx, &updated, history); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to update project: %w", err))
+	}
+
+	return &updated, nil
+}
+
+// GetProjectHistory returns the recorded changes of an oss_base row, newest first
+func (s *service) GetProjectHistory(ctx context.Context, id int, req HistoryRequest) ([]model.OssBaseHistory, int, error) {
+	var fields []string
+	if req.Field != "" {
+		for _, name := range strings.Split(req.Field, ",") {
+			col, ok := ossColumnsByName[strings.TrimSpace(name)]
+			if !ok {
+				return nil, 0, errors.NewValidationError([]errors.ValidationError{{
+					Field:   "field",
+					Message: fmt.Sprintf("Unknown field '%s'", name),
+				}})
+			}
+			fields = append(fields, col.name)
+		}
+	}
+
+	exists, err := s.repo.ProjectExists(ctx, id)
+	if err != nil {
+		return nil, 0, errors.NewDatabaseError(fmt.Errorf("failed to find project: %w", err))
+	}
+	if !exists {
+		return nil, 0, errors.NewNotFoundError("Project")
+	}
+
+	history, total, err := s.repo.ListHistory(ctx, id, fields, req.Page, req.PerPage)
+	if err != nil {
+		return nil, 0, errors.NewDatabaseError(fmt.Errorf("failed to list project history: %w", err))
+	}
+
+	return history, total, nil
+}
+
+func (s *service) findProject(ctx context.Context, id int) (*model.OssBase, error) {
+	row, err := s.repo.FindProject(ctx, id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find project: %w", err))
+	}
+	if row == nil {
+		return nil, errors.NewNotFoundError("Project")
+	}
+	return row, nil
+}
@@ -0,0 +1,156 @@
+package oss
+
+import (
+	"encoding/json"
+	"kswi-backend/internal/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatValue(t *testing.T) {
+	n := uint64(1500000)
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  *string
+	}{
+		{name: "null", value: (*string)(nil), want: nil},
+		{name: "string", value: strPtr("Pabrik"), want: strPtr("Pabrik")},
+		{name: "int", value: intPtr(-12), want: strPtr("-12")},
+		{name: "uint64", value: &n, want: strPtr("1500000")},
+		{name: "date", value: datePtr("2024-03-15"), want: strPtr("2024-03-15")},
+		{name: "time", value: timePtr(time.Date(2024, 3, 15, 8, 30, 0, 0, time.UTC)), want: strPtr("2024-03-15T08:30:00Z")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatValue(reflect.ValueOf(tt.value))
+			if !equalValues(got, tt.want) {
+				t.Errorf("formatValue = %v, want %v", deref(got), deref(tt.want))
+			}
+		})
+	}
+}
+
+func TestDiffRows(t *testing.T) {
+	old := model.OssBase{ID: 1, IdProyek: strPtr("P1"), NamaProyek: strPtr("Pabrik"), TenagaKerja: intPtr(10), TglTerbitOss: datePtr("2024-01-01")}
+
+	tests := []struct {
+		name   string
+		change func(row *model.OssBase)
+		want   []string
+	}{
+		{name: "unchanged", change: func(row *model.OssBase) {}},
+		{name: "bookkeeping only", change: func(row *model.OssBase) {
+			row.UpdatedBy = intPtr(3)
+			row.TglTerbitOssExcel = strPtr("01/01/2024")
+		}},
+		{name: "same values in new pointers", change: func(row *model.OssBase) {
+			row.NamaProyek = strPtr("Pabrik")
+			row.TglTerbitOss = datePtr("2024-01-01")
+		}},
+		{name: "changed and cleared", change: func(row *model.OssBase) {
+			row.NamaProyek = strPtr("Gudang")
+			row.TenagaKerja = nil
+		}, want: []string{"tenagaKerja", "namaProyek"}},
+		{name: "set", change: func(row *model.OssBase) {
+			row.PerusahaanNama = strPtr("PT A")
+		}, want: []string{"perusahaanNama"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := old
+			tt.change(&updated)
+
+			var got []string
+			for _, change := range diffRows(&old, &updated) {
+				got = append(got, change.column)
+			}
+			if !sameColumns(got, tt.want) {
+				t.Errorf("changed columns = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		patch       map[string]interface{}
+		wantInvalid []string
+		check       func(t *testing.T, row *model.OssBase)
+	}{
+		{
+			name:  "by JSON and DB name",
+			patch: map[string]interface{}{"nama_proyek": " Gudang ", "tenagaKerja": json.Number("25")},
+			check: func(t *testing.T, row *model.OssBase) {
+				if *row.NamaProyek != "Gudang" || *row.TenagaKerja != 25 {
+					t.Errorf("row = %v, %v", *row.NamaProyek, *row.TenagaKerja)
+				}
+			},
+		},
+		{
+			name:  "null clears a date and its raw copy",
+			patch: map[string]interface{}{"tgl_terbit_oss": nil},
+			check: func(t *testing.T, row *model.OssBase) {
+				if row.TglTerbitOss != nil || row.TglTerbitOssExcel != nil {
+					t.Errorf("date = %v (%v), want both cleared", row.TglTerbitOss, row.TglTerbitOssExcel)
+				}
+			},
+		},
+		{
+			name:        "invalid fields",
+			patch:       map[string]interface{}{"id": json.Number("2"), "namaProyek": "", "tenagaKerja": "dua", "nib": true},
+			wantInvalid: []string{"id", "namaProyek", "tenagaKerja", "nib"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := model.OssBase{ID: 1, IdProyek: strPtr("P1"), NamaProyek: strPtr("Pabrik"), TglTerbitOss: datePtr("2024-01-01"), TglTerbitOssExcel: strPtr("01/01/2024")}
+
+			var invalid []string
+			for _, detail := range applyPatch(&row, tt.patch) {
+				invalid = append(invalid, detail.Field)
+			}
+			if !sameColumns(invalid, tt.wantInvalid) {
+				t.Errorf("invalid fields = %v, want %v", invalid, tt.wantInvalid)
+			}
+			if tt.check != nil {
+				tt.check(t, &row)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func deref(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+// sameColumns compares two lists of column names ignoring their order
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int)
+	for _, name := range a {
+		counts[name]++
+	}
+	for _, name := range b {
+		counts[name]--
+	}
+	for _, n := range counts {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
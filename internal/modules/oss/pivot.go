@@ -0,0 +1,401 @@
+package oss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"kswi-backend/internal/shared/errors"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Pivot aggregates
+const (
+	PivotCount = "count"
+	PivotSum   = "sum"
+	PivotAvg   = "avg"
+	PivotMin   = "min"
+	PivotMax   = "max"
+)
+
+const (
+	defaultPivotRowLimit    = 100
+	defaultPivotColumnLimit = 50
+	// maxPivotCells caps the non-empty cells read, before the limits apply
+	maxPivotCells = 50000
+	// pivotAvgScale is the number of decimals of an average
+	pivotAvgScale = 2
+)
+
+// pivotAggregateLabels head the XLSX export of a pivot
+var pivotAggregateLabels = map[string]string{
+	PivotCount: "Jumlah",
+	PivotSum:   "Total",
+	PivotAvg:   "Rata-rata",
+	PivotMin:   "Minimum",
+	PivotMax:   "Maksimum",
+}
+
+// pivotCell accumulates the rows of a cell, of a row or column total, or of
+// the grand total
+type pivotCell struct {
+	projects int64
+	sum      big.Int
+	count    int64
+	min, max *big.Int
+}
+
+// newPivotCell reads an aggregate row over at most one measure
+func newPivotCell(row AggregateRow) (*pivotCell, error) {
+	c := &pivotCell{projects: row.Projects}
+	if len(row.Sums) == 0 {
+		return c, nil
+	}
+
+	if _, ok := c.sum.SetString(row.Sums[0], 10); !ok {
+		return nil, fmt.Errorf("unexpected sum %q", row.Sums[0])
+	}
+	c.count = row.Counts[0]
+
+	if row.Mins != nil {
+		var err error
+		if c.min, err = parseExtreme(row.Mins[0]); err != nil {
+			return nil, err
+		}
+		if c.max, err = parseExtreme(row.Maxes[0]); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func parseExtreme(value *string) (*big.Int, error) {
+	if value == nil {
+		return nil, nil
+	}
+	n, ok := new(big.Int).SetString(*value, 10)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value %q", *value)
+	}
+	return n, nil
+}
+
+func (c *pivotCell) merge(o *pivotCell) {
+	c.projects += o.projects
+	c.sum.Add(&c.sum, &o.sum)
+	c.count += o.count
+	if o.min != nil && (c.min == nil || o.min.Cmp(c.min) < 0) {
+		c.min = new(big.Int).Set(o.min)
+	}
+	if o.max != nil && (c.max == nil || o.max.Cmp(c.max) > 0) {
+		c.max = new(big.Int).Set(o.max)
+	}
+}
+
+// value returns the aggregate of the cell; counts without a measure count
+// projects
+func (c *pivotCell) value(aggregate string, measured bool) *json.Number {
+	var n string
+	switch aggregate {
+	case PivotCount:
+		if measured {
+			n = strconv.FormatInt(c.count, 10)
+		} else {
+			n = strconv.FormatInt(c.projects, 10)
+		}
+	case PivotSum:
+		n = c.sum.String()
+	case PivotAvg:
+		if c.count == 0 {
+			return nil
+		}
+		n = new(big.Rat).SetFrac(&c.sum, big.NewInt(c.count)).FloatString(pivotAvgScale)
+	case PivotMin:
+		if c.min == nil {
+			return nil
+		}
+		n = c.min.String()
+	case PivotMax:
+		if c.max == nil {
+			return nil
+		}
+		n = c.max.String()
+	}
+
+	number := json.Number(n)
+	return &number
+}
+
+// Pivot cross-tabulates the matching rows by two dimensions. Every cell is
+// read in one grouped query; totals are then merged here so that averages,
+// minimums and maximums come out right.
+func (s *service) Pivot(ctx context.Context, req PivotRequest) (*PivotTable, error) {
+	rowDimension, err := dimensionColumn("rows", req.Rows)
+	if err != nil {
+		return nil, err
+	}
+	columnDimension, err := dimensionColumn("columns", req.Columns)
+	if err != nil {
+		return nil, err
+	}
+	if rowDimension.Column == columnDimension.Column {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "columns",
+			Message: "Must differ from rows",
+		}})
+	}
+
+	table := &PivotTable{
+		Rows:      rowDimension.Name,
+		Columns:   columnDimension.Name,
+		Aggregate: req.Aggregate,
+	}
+	q := AggregateQuery{
+		Filter:  req.StatsFilter,
+		GroupBy: []string{rowDimension.Column, columnDimension.Column},
+		Limit:   maxPivotCells + 1,
+	}
+
+	if req.Measure != "" {
+		field, ok := dtSchema.Field(req.Measure)
+		if !ok || !containsString(statsMeasures, field.Column) {
+			return nil, errors.NewValidationError([]errors.ValidationError{{
+				Field:   "measure",
+				Message: "Must be one of " + strings.Join(statsMeasures, ", "),
+			}})
+		}
+		table.Measure = field.Name
+		q.Measures = []string{field.Column}
+		q.Extremes = req.Aggregate == PivotMin || req.Aggregate == PivotMax
+	} else if req.Aggregate != PivotCount {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "measure",
+			Message: "Required for sum, avg, min and max",
+		}})
+	}
+
+	rows, err := s.aggregate(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > maxPivotCells {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "rows",
+			Message: fmt.Sprintf("The pivot has more than %d cells; narrow the filters or pick other dimensions", maxPivotCells),
+		}})
+	}
+
+	cells := make(map[[2]string]*pivotCell, len(rows))
+	rowTotals := make(map[string]*pivotCell)
+	columnTotals := make(map[string]*pivotCell)
+	rowKeys := make(map[string]*string)
+	columnKeys := make(map[string]*string)
+	total := &pivotCell{}
+
+	for _, row := range rows {
+		cell, err := newPivotCell(row)
+		if err != nil {
+			return nil, errors.NewInternalError(err)
+		}
+
+		rowKey, columnKey := groupKey(row.Keys[0]), groupKey(row.Keys[1])
+		cells[[2]string{rowKey, columnKey}] = cell
+		rowKeys[rowKey] = row.Keys[0]
+		columnKeys[columnKey] = row.Keys[1]
+
+		mergePivotCell(rowTotals, rowKey, cell)
+		mergePivotCell(columnTotals, columnKey, cell)
+		total.merge(cell)
+	}
+
+	rowLimit := req.RowLimit
+	if rowLimit == 0 {
+		rowLimit = defaultPivotRowLimit
+	}
+	columnLimit := req.ColumnLimit
+	if columnLimit == 0 {
+		columnLimit = defaultPivotColumnLimit
+	}
+
+	rowOrder := rankPivotKeys(rowKeys, rowTotals)
+	if table.RowsTruncated = len(rowOrder) > rowLimit; table.RowsTruncated {
+		rowOrder = rowOrder[:rowLimit]
+	}
+	columnOrder := rankPivotKeys(columnKeys, columnTotals)
+	if table.ColumnsTruncated = len(columnOrder) > columnLimit; table.ColumnsTruncated {
+		columnOrder = columnOrder[:columnLimit]
+	}
+
+	measured := req.Measure != ""
+	empty := &pivotCell{}
+
+	table.ColumnKeys = make([]*string, len(columnOrder))
+	table.ColumnTotals = make([]*json.Number, len(columnOrder))
+	for j, columnKey := range columnOrder {
+		table.ColumnKeys[j] = columnKeys[columnKey]
+		table.ColumnTotals[j] = columnTotals[columnKey].value(req.Aggregate, measured)
+	}
+
+	table.RowKeys = make([]*string, len(rowOrder))
+	table.RowTotals = make([]*json.Number, len(rowOrder))
+	table.Values = make([][]*json.Number, len(rowOrder))
+	for i, rowKey := range rowOrder {
+		table.RowKeys[i] = rowKeys[rowKey]
+		table.RowTotals[i] = rowTotals[rowKey].value(req.Aggregate, measured)
+
+		table.Values[i] = make([]*json.Number, len(columnOrder))
+		for j, columnKey := range columnOrder {
+			cell, ok := cells[[2]string{rowKey, columnKey}]
+			if !ok {
+				cell = empty
+			}
+			table.Values[i][j] = cell.value(req.Aggregate, measured)
+		}
+	}
+	table.Total = total.value(req.Aggregate, measured)
+
+	return table, nil
+}
+
+func mergePivotCell(totals map[string]*pivotCell, key string, cell *pivotCell) {
+	t, ok := totals[key]
+	if !ok {
+		t = &pivotCell{}
+		totals[key] = t
+	}
+	t.merge(cell)
+}
+
+// rankPivotKeys orders the keys of a dimension by projects, descending, then
+// by key with NULL last
+func rankPivotKeys(keys map[string]*string, totals map[string]*pivotCell) []string {
+	ranked := make([]string, 0, len(keys))
+	for key := range keys {
+		ranked = append(ranked, key)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if totals[a].projects != totals[b].projects {
+			return totals[a].projects > totals[b].projects
+		}
+		if (keys[a] == nil) != (keys[b] == nil) {
+			return keys[b] == nil
+		}
+		return a < b
+	})
+	return ranked
+}
+
+// FileName names the XLSX export of a pivot
+func (t *PivotTable) FileName() string {
+	return fmt.Sprintf("oss_pivot_%s_%s_%s.xlsx", t.Rows, t.Columns, time.Now().Format("20060102_150405"))
+}
+
+// WriteXLSX writes the pivot as a workbook: a title naming the aggregate,
+// then the matrix with labelled keys and a total row and column
+func (t *PivotTable) WriteXLSX(w io.Writer) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	const sheet = "Pivot"
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	title := "Jumlah Proyek"
+	if t.Measure != "" {
+		title = pivotAggregateLabels[t.Aggregate] + " " + pivotLabel(t.Measure)
+	}
+	if err := file.SetCellValue(sheet, "A1", title); err != nil {
+		return err
+	}
+
+	header := []interface{}{pivotLabel(t.Rows) + " / " + pivotLabel(t.Columns)}
+	for _, key := range t.ColumnKeys {
+		header = append(header, pivotKeyLabel(key))
+	}
+	header = append(header, "Total")
+	if err := file.SetSheetRow(sheet, "A3", &header); err != nil {
+		return err
+	}
+
+	for i, key := range t.RowKeys {
+		values := []interface{}{pivotKeyLabel(key)}
+		for _, value := range t.Values[i] {
+			values = append(values, pivotCellValue(value))
+		}
+		values = append(values, pivotCellValue(t.RowTotals[i]))
+		if err := file.SetSheetRow(sheet, fmt.Sprintf("A%d", i+4), &values); err != nil {
+			return err
+		}
+	}
+
+	totals := []interface{}{"Total"}
+	for _, value := range t.ColumnTotals {
+		totals = append(totals, pivotCellValue(value))
+	}
+	totals = append(totals, pivotCellValue(t.Total))
+	lastRow := len(t.RowKeys) + 4
+	if err := file.SetSheetRow(sheet, fmt.Sprintf("A%d", lastRow), &totals); err != nil {
+		return err
+	}
+
+	// The title, the keys and the totals are bold
+	bold, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	lastColumn, err := excelize.ColumnNumberToName(len(t.ColumnKeys) + 2)
+	if err != nil {
+		return err
+	}
+	for _, area := range [][2]string{
+		{"A1", "A1"},
+		{"A3", fmt.Sprintf("%s3", lastColumn)},
+		{"A4", fmt.Sprintf("A%d", lastRow)},
+		{fmt.Sprintf("%s4", lastColumn), fmt.Sprintf("%s%d", lastColumn, lastRow)},
+		{fmt.Sprintf("A%d", lastRow), fmt.Sprintf("%s%d", lastColumn, lastRow)},
+	} {
+		if err := file.SetCellStyle(sheet, area[0], area[1], bold); err != nil {
+			return err
+		}
+	}
+
+	_, err = file.WriteTo(w)
+	return err
+}
+
+// pivotLabel is the export header of a column, by JSON name
+func pivotLabel(name string) string {
+	if col, ok := exportColumnsByName[name]; ok {
+		return col.label
+	}
+	return name
+}
+
+func pivotKeyLabel(key *string) string {
+	if key == nil {
+		return "(kosong)"
+	}
+	return *key
+}
+
+// pivotCellValue converts an aggregate to a spreadsheet number; empty cells
+// stay blank
+func pivotCellValue(value *json.Number) interface{} {
+	if value == nil {
+		return nil
+	}
+	if n, err := value.Int64(); err == nil {
+		return n
+	}
+	f, _ := value.Float64()
+	return f
+}
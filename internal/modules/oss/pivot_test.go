@@ -0,0 +1,144 @@
+package oss
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// aggregateRepo answers Aggregate with fixed rows; other methods are not used
+type aggregateRepo struct {
+	Repository
+	rows []AggregateRow
+}
+
+func (r *aggregateRepo) Aggregate(ctx context.Context, q AggregateQuery) ([]AggregateRow, error) {
+	rows := make([]AggregateRow, len(r.rows))
+	for i, row := range r.rows {
+		if len(q.Measures) == 0 {
+			row.Sums, row.Counts = nil, nil
+		}
+		if !q.Extremes {
+			row.Mins, row.Maxes = nil, nil
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// pivotRow is a cell of prov and kota with the sum, count, min and max of one measure
+func pivotRow(prov, kota *string, projects int64, sum string, count int64, min, max *string) AggregateRow {
+	return AggregateRow{
+		Keys:     []*string{prov, kota},
+		Projects: projects,
+		Sums:     []string{sum},
+		Counts:   []int64{count},
+		Mins:     []*string{min},
+		Maxes:    []*string{max},
+	}
+}
+
+// numbers renders pivot values, "-" standing for nil
+func numbers(values []*json.Number) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = "-"
+		if v != nil {
+			out[i] = v.String()
+		}
+	}
+	return out
+}
+
+func TestPivotTotals(t *testing.T) {
+	a, b, x, y := strPtr("A"), strPtr("B"), strPtr("X"), strPtr("Y")
+	repo := &aggregateRepo{rows: []AggregateRow{
+		pivotRow(a, x, 2, "100", 2, strPtr("40"), strPtr("60")),
+		pivotRow(a, y, 1, "30", 1, strPtr("30"), strPtr("30")),
+		pivotRow(b, x, 3, "90", 2, strPtr("10"), strPtr("80")),
+		pivotRow(nil, y, 1, "0", 0, nil, nil),
+	}}
+
+	tests := []struct {
+		name             string
+		req              PivotRequest
+		wantRowKeys      []*string
+		wantValues       [][]string
+		wantRowTotals    []string
+		wantColumnTotals []string
+		wantTotal        string
+	}{
+		{
+			name:             "project count",
+			req:              PivotRequest{Aggregate: PivotCount},
+			wantRowKeys:      []*string{a, b, nil},
+			wantValues:       [][]string{{"2", "1"}, {"3", "0"}, {"0", "1"}},
+			wantRowTotals:    []string{"3", "3", "1"},
+			wantColumnTotals: []string{"5", "2"},
+			wantTotal:        "7",
+		},
+		{
+			name:             "average of the rows, not of the cells",
+			req:              PivotRequest{Aggregate: PivotAvg, Measure: "inv_jumlah"},
+			wantRowKeys:      []*string{a, b, nil},
+			wantValues:       [][]string{{"50.00", "30.00"}, {"45.00", "-"}, {"-", "-"}},
+			wantRowTotals:    []string{"43.33", "45.00", "-"},
+			wantColumnTotals: []string{"47.50", "30.00"},
+			wantTotal:        "44.00",
+		},
+		{
+			name:             "minimum",
+			req:              PivotRequest{Aggregate: PivotMin, Measure: "invJumlah"},
+			wantRowKeys:      []*string{a, b, nil},
+			wantValues:       [][]string{{"40", "30"}, {"10", "-"}, {"-", "-"}},
+			wantRowTotals:    []string{"30", "10", "-"},
+			wantColumnTotals: []string{"10", "30"},
+			wantTotal:        "10",
+		},
+		{
+			name:             "totals include rows cut by the limit",
+			req:              PivotRequest{Aggregate: PivotSum, Measure: "invJumlah", RowLimit: 1},
+			wantRowKeys:      []*string{a},
+			wantValues:       [][]string{{"100", "30"}},
+			wantRowTotals:    []string{"130"},
+			wantColumnTotals: []string{"190", "30"},
+			wantTotal:        "220",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Rows, tt.req.Columns = "perusahaan_prov", "perusahaan_kota"
+			s := &service{repo: repo}
+
+			table, err := s.Pivot(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(table.RowKeys, tt.wantRowKeys) || !reflect.DeepEqual(table.ColumnKeys, []*string{x, y}) {
+				t.Errorf("keys = %v by %v", table.RowKeys, table.ColumnKeys)
+			}
+			var values [][]string
+			for _, row := range table.Values {
+				values = append(values, numbers(row))
+			}
+			if !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("values = %v, want %v", values, tt.wantValues)
+			}
+			if got := numbers(table.RowTotals); !reflect.DeepEqual(got, tt.wantRowTotals) {
+				t.Errorf("row totals = %v, want %v", got, tt.wantRowTotals)
+			}
+			if got := numbers(table.ColumnTotals); !reflect.DeepEqual(got, tt.wantColumnTotals) {
+				t.Errorf("column totals = %v, want %v", got, tt.wantColumnTotals)
+			}
+			if got := numbers([]*json.Number{table.Total})[0]; got != tt.wantTotal {
+				t.Errorf("total = %s, want %s", got, tt.wantTotal)
+			}
+			if table.RowsTruncated != (tt.req.RowLimit > 0) {
+				t.Errorf("rows truncated = %v", table.RowsTruncated)
+			}
+		})
+	}
+}
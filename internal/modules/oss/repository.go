@@ -2,120 +2,71 @@ package oss
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/model"
 	"kswi-backend/internal/shared/pagination"
+	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository interface {
-	DtDatabase(ctx context.Context, req DtDatabaseRequest) ([]DtDatabaseResponse, int, int, error)
+	DtDatabase(ctx context.Context, req DtDatabaseRequest, count string) (*DtDatabasePage, error)
+	ExportRows(ctx context.Context, req DtDatabaseRequest, columns []string, fn func([]model.OssBase) error) error
+	Aggregate(ctx context.Context, q AggregateQuery) ([]AggregateRow, error)
+
+	CreateLogUpload(ctx context.Context, upload *model.LogUpload) error
+	UpdateLogUpload(ctx context.Context, upload *model.LogUpload) error
+	SetLogUploadJob(ctx context.Context, uploadID int, jobID string) error
+	SetLogUploadRollbackJob(ctx context.Context, uploadID int, jobID string) error
+	FindLogUploadByJob(ctx context.Context, jobID string) (*model.LogUpload, error)
+	FindProjects(ctx context.Context, idProyek []string) ([]model.OssBase, error)
+	SaveUploadBatch(ctx context.Context, batch *UpsertBatch) error
+	WithWriteLock(ctx context.Context, fn func(ctx context.Context) error) error
+	DiscardUpload(ctx context.Context, uploadID int) error
+
+	ListLogUploads(ctx context.Context, req ListUploadsRequest) ([]model.LogUpload, int, error)
+	FindLogUpload(ctx context.Context, id int) (*model.LogUpload, error)
+	GetUploadRowStats(ctx context.Context, uploadIDs []int) (map[int]UploadRowStats, error)
+	SetLogUploadStatus(ctx context.Context, id int, from, to string) (bool, error)
+	RollbackUpload(ctx context.Context, upload *model.LogUpload, mode string, userID int) (*RollbackResult, error)
+
+	FindProject(ctx context.Context, id int) (*model.OssBase, error)
+	ProjectExists(ctx context.Context, id int) (bool, error)
+	SaveProjectEdit(ctx context.Context, row *model.OssBase, history []model.OssBaseHistory) error
+	ListHistory(ctx context.Context, id int, fields []string, page, perPage int) ([]model.OssBaseHistory, int, error)
+
+	CountGeoPending(ctx context.Context, all bool) (int64, error)
+	ListGeoPending(ctx context.Context, afterID, limit int, all bool) ([]model.OssBase, error)
+	SaveGeo(ctx context.Context, rows []model.OssBase) error
+	MapPoints(ctx context.Context, q MapQuery) ([]model.OssBase, error)
+	MapClusters(ctx context.Context, q MapQuery) ([]MapCluster, error)
 }
 
 type repository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	fulltext fulltextIndex
 }
 
 func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
-func (r *repository) DtDatabase(ctx context.Context, req DtDatabaseRequest) ([]DtDatabaseResponse, int, int, error) {
-	var data []DtDatabaseResponse
-	var total int
-	var totalFiltered int
-	var err error
-	var total64 int64
-
-	query := r.db.WithContext(ctx).Table("kswi.oss_base")
-
-	// First get total count without any filters
-	if err := query.Count(&total64).Error; err != nil {
-		return nil, 0, 0, err
-	}
-	total = int(total64)
-
-	// Initialize where conditions and parameters
-	whereAnd := []string{}
-	paramsAnd := []interface{}{}
-	whereOr := []string{}
-	paramsOr := []interface{}{}
-
-	// Always include this condition
-	// whereAnd = append(whereAnd, "statusPM IS NOT NULL")
-
-	// Handle date filters
-	if req.StartDate != nil && !req.StartDate.IsZero() {
-		whereAnd = append(whereAnd, "_created_at >= ?")
-		paramsAnd = append(paramsAnd, req.StartDate)
-	}
-
-	if req.EndDate != nil && !req.EndDate.IsZero() {
-		whereAnd = append(whereAnd, "_created_at <= ?")
-		paramsAnd = append(paramsAnd, req.EndDate)
-	}
-
-	// Handle JSON filters if they exist
-	if req.Filters != nil {
-		// Process AND filters
-		for _, filter := range req.Filters.And {
-			whereClause, param := pagination.BuildWhereClause(filter)
-			if whereClause != "" {
-				whereAnd = append(whereAnd, whereClause)
-				paramsAnd = append(paramsAnd, param)
-			}
-		}
-
-		// Process OR filters
-		for _, filter := range req.Filters.Or {
-			whereClause, param := pagination.BuildWhereClause(filter)
-			if whereClause != "" {
-				whereOr = append(whereOr, whereClause)
-				paramsOr = append(paramsOr, param)
-			}
-		}
-	}
-
-	// Combine all conditions
-	var finalWhere string
-	var finalParams []interface{}
-
-	// Add AND conditions
-	if len(whereAnd) > 0 {
-		finalWhere = strings.Join(whereAnd, " AND ")
-		finalParams = append(finalParams, paramsAnd...)
-	}
-
-	// Add OR conditions (wrapped in parentheses)
-	if len(whereOr) > 0 {
-		orClause := "(" + strings.Join(whereOr, " OR ") + ")"
-		if finalWhere != "" {
-			finalWhere += " AND " + orClause
-		} else {
-			finalWhere = orClause
-		}
-		finalParams = append(finalParams, paramsOr...)
-	}
-
-	// Apply the combined where clause
-	if finalWhere != "" {
-		query = query.Where(finalWhere, finalParams...)
-	}
-
-	// Get filtered count
-	if err := query.Count(&total64).Error; err != nil {
-		return nil, 0, 0, err
-	}
-	totalFiltered = int(total64)
-
-	// Apply pagination and select
-	err = query.Select(`
+// dtColumns are the columns returned by the datatable
+const dtColumns = `
 		id,
 		_log_upload_id,
 		idProyek,
 		uraianJenisProyek,
 		nib,
-		tglDownload as tgl_download,
+		tglDownload,
 		tglDownloadExcel,
 		tglTerbitOss,
 		tglTerbitOssExcel,
@@ -165,12 +116,899 @@ func (r *repository) DtDatabase(ctx context.Context, req DtDatabaseRequest) ([]D
 		_created_by,
 		_updated_at,
 		_updated_by,
-		_input_manual
-	`).Limit(req.PerPage).Offset((req.Page - 1) * req.PerPage).Scan(&data).Error
+		_input_manual,
+		_geo_lat,
+		_geo_lon,
+		_geo_status
+	`
+
+// DtDatabase returns a page of the datatable, by offset or, in cursor mode,
+// after or before the row encoded in req.Cursor. count is exact, estimate or
+// none; totals that are not counted are -1.
+func (r *repository) DtDatabase(ctx context.Context, req DtDatabaseRequest, count string) (*DtDatabasePage, error) {
+	// Session makes both queries safe to branch from
+	base := r.db.WithContext(ctx).Table("kswi.oss_base").Where("_deleted_at IS NULL").Session(&gorm.Session{})
+
+	query, err := r.applyDtFilters(base, req)
+	if err != nil {
+		return nil, err
+	}
+	query = query.Session(&gorm.Session{})
+
+	order, err := dtSchema.Sort(&req.PaginationRequest, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	page := &DtDatabasePage{Total: -1, Filtered: -1}
+	if count != pagination.CountNone {
+		if page.Total, page.Filtered, err = r.countDt(ctx, base, query, req, count); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Mode != pagination.ModeCursor {
+		err = query.Select(dtColumns).
+			Order(order.String()).
+			Limit(req.PerPage).
+			Offset((req.Page - 1) * req.PerPage).
+			Scan(&page.Data).Error
+		if err != nil {
+			return nil, err
+		}
+		return page, nil
+	}
+
+	// Cursor mode reads one row more than requested to know whether another
+	// page follows. Going backward reads in reverse order, then flips the rows.
+	seekOrder, backward := order, false
+	if req.Cursor != "" {
+		var values []interface{}
+		if values, backward, err = order.DecodeCursor(req.Cursor); err != nil {
+			return nil, err
+		}
+		if backward {
+			seekOrder = order.Reverse()
+		}
+		seek, args := seekOrder.Seek(values)
+		query = query.Where(seek, args...)
+	}
+
+	err = query.Select(dtColumns).
+		Order(seekOrder.String()).
+		Limit(req.PerPage + 1).
+		Scan(&page.Data).Error
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(page.Data) > req.PerPage
+	if more {
+		page.Data = page.Data[:req.PerPage]
+	}
+	if backward {
+		for i, j := 0, len(page.Data)-1; i < j; i, j = i+1, j-1 {
+			page.Data[i], page.Data[j] = page.Data[j], page.Data[i]
+		}
+	}
+
+	if len(page.Data) > 0 {
+		first, last := &page.Data[0], &page.Data[len(page.Data)-1]
+		if more || backward {
+			page.NextCursor = order.EncodeCursor(orderValues(last, order), false)
+		}
+		if (more && backward) || (!backward && req.Cursor != "") {
+			page.PrevCursor = order.EncodeCursor(orderValues(first, order), true)
+		}
+	}
+
+	return page, nil
+}
+
+// countDt counts all live rows and the rows matching the request, exactly
+// or from the optimizer's estimates. The filtered count is skipped when the
+// request has no conditions.
+func (r *repository) countDt(ctx context.Context, base, query *gorm.DB, req DtDatabaseRequest, count string) (int, int, error) {
+	if count == pagination.CountEstimate {
+		total, err := r.estimateRows(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		if !dtHasConditions(req) {
+			return total, total, nil
+		}
+
+		filtered, err := r.explainRows(ctx, query)
+		if err != nil {
+			return 0, 0, err
+		}
+		if filtered > total {
+			filtered = total
+		}
+		return total, filtered, nil
+	}
+
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return 0, 0, err
+	}
+	if !dtHasConditions(req) {
+		return int(total), int(total), nil
+	}
+
+	var filtered int64
+	if err := query.Count(&filtered).Error; err != nil {
+		return 0, 0, err
+	}
+	return int(total), int(filtered), nil
+}
+
+// estimateRows returns the table size recorded in information_schema,
+// which InnoDB keeps only approximately up to date
+func (r *repository) estimateRows(ctx context.Context) (int, error) {
+	schema, table, _ := strings.Cut(model.OssBase{}.TableName(), ".")
+
+	var rows sql.NullInt64
+	err := r.db.WithContext(ctx).
+		Raw("SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?", schema, table).
+		Scan(&rows).Error
+	return int(rows.Int64), err
+}
+
+// explainRows returns the optimizer's estimate of the rows matched by query
+func (r *repository) explainRows(ctx context.Context, query *gorm.DB) (int, error) {
+	stmt := query.Session(&gorm.Session{DryRun: true}).Select("id").Find(&[]model.OssBase{}).Statement
+
+	var plan []map[string]interface{}
+	err := r.db.WithContext(ctx).Raw("EXPLAIN "+stmt.SQL.String(), stmt.Vars...).Scan(&plan).Error
+	if err != nil || len(plan) == 0 {
+		return 0, err
+	}
+
+	rows, _ := strconv.ParseFloat(fmt.Sprint(plan[0]["rows"]), 64)
+	filtered, err := strconv.ParseFloat(fmt.Sprint(plan[0]["filtered"]), 64)
+	if err != nil {
+		filtered = 100
+	}
+	return int(rows * filtered / 100), nil
+}
+
+// dtHasConditions reports whether a request narrows down the rows
+func dtHasConditions(req DtDatabaseRequest) bool {
+	return (req.StartDate != nil && !req.StartDate.IsZero()) ||
+		(req.EndDate != nil && !req.EndDate.IsZero()) ||
+		len(searchTerms(req.Search)) > 0 ||
+		(req.Filters != nil && (len(req.Filters.And) > 0 || len(req.Filters.Or) > 0))
+}
+
+// Aggregate runs an aggregate over the live rows matching q.Filter
+func (r *repository) Aggregate(ctx context.Context, q AggregateQuery) ([]AggregateRow, error) {
+	query, err := r.statsQuery(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	selects := make([]string, 0, len(q.GroupBy)+2+len(q.Measures)*2)
+	groups := make([]string, 0, len(q.GroupBy)+1)
+	if q.DayColumn != "" {
+		selects = append(selects, fmt.Sprintf(
+			"DATE_FORMAT(DATE_ADD(`%s`, INTERVAL %d SECOND), '%%Y-%%m-%%d') AS d", q.DayColumn, q.UTCOffset))
+		groups = append(groups, "d")
+		query = query.Where("`" + q.DayColumn + "` IS NOT NULL")
+	}
+	for i, column := range q.GroupBy {
+		groups = append(groups, "`"+column+"`")
+		selects = append(selects, fmt.Sprintf("`%s` AS g%d", column, i))
+	}
+	selects = append(selects, "COUNT(*) AS projects")
+	for i, column := range q.Measures {
+		selects = append(selects,
+			fmt.Sprintf("COALESCE(SUM(`%s`), 0) AS m%d_sum", column, i),
+			fmt.Sprintf("COUNT(`%s`) AS m%d_count", column, i),
+		)
+		if q.Extremes {
+			selects = append(selects,
+				fmt.Sprintf("MIN(`%s`) AS m%d_min", column, i),
+				fmt.Sprintf("MAX(`%s`) AS m%d_max", column, i),
+			)
+		}
+	}
+
+	query = query.Select(strings.Join(selects, ", "))
+	if len(groups) > 0 {
+		query = query.Group(strings.Join(groups, ", "))
+	}
+	if q.OrderBy != "" {
+		query = query.Order(q.OrderBy)
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []AggregateRow
+	for rows.Next() {
+		row := AggregateRow{
+			Keys:   make([]*string, len(groups)),
+			Sums:   make([]string, len(q.Measures)),
+			Counts: make([]int64, len(q.Measures)),
+		}
+		if q.Extremes {
+			row.Mins = make([]*string, len(q.Measures))
+			row.Maxes = make([]*string, len(q.Measures))
+		}
+
+		dest := make([]interface{}, 0, cap(selects))
+		for i := range row.Keys {
+			dest = append(dest, &row.Keys[i])
+		}
+		dest = append(dest, &row.Projects)
+		for i := range q.Measures {
+			dest = append(dest, &row.Sums[i], &row.Counts[i])
+			if q.Extremes {
+				dest = append(dest, &row.Mins[i], &row.Maxes[i])
+			}
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+// statsQuery selects the live rows matching a statistic filter
+func (r *repository) statsQuery(ctx context.Context, f StatsFilter) (*gorm.DB, error) {
+	query, err := r.applyDtFilters(r.db.WithContext(ctx).Table("kswi.oss_base").Where("_deleted_at IS NULL"), f.dtRequest())
+	if err != nil {
+		return nil, err
+	}
+
+	if f.DateField == "" && f.DateFrom == "" && f.DateTo == "" {
+		return query, nil
+	}
+
+	column, err := dateColumn(f.DateField)
+	if err != nil {
+		return nil, err
+	}
+
+	// The dates are days of the display timezone
+	if f.DateFrom != "" {
+		from, _ := time.ParseInLocation("2006-01-02", f.DateFrom, config.Location())
+		query = query.Where("`"+column+"` >= ?", from)
+	}
+	if f.DateTo != "" {
+		to, _ := time.ParseInLocation("2006-01-02", f.DateTo, config.Location())
+		query = query.Where("`"+column+"` < ?", to.AddDate(0, 0, 1))
+	}
+
+	return query, nil
+}
+
+// mapQuery selects the live rows with valid coordinates in a bounding box.
+// MBRContains on _geo_point lets MySQL use its SPATIAL index; as the edges of
+// a box are geodesics there, the decimal columns still give the exact bounds.
+// Boxes the index cannot narrow down, half the globe wide or reaching a pole,
+// only use the decimal bounds.
+func (r *repository) mapQuery(ctx context.Context, q MapQuery) (*gorm.DB, error) {
+	query, err := r.statsQuery(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	b := q.BBox
+	if b[2]-b[0] < 180 && b[0] < b[2] && -90 < b[1] && b[1] < b[3] && b[3] < 90 {
+		query = query.Where("MBRContains(ST_GeomFromText(?, 4326, 'axis-order=long-lat'), _geo_point)", bboxPolygon(b))
+	}
+	return query.Where("_geo_status = ? AND _geo_lat BETWEEN ? AND ? AND _geo_lon BETWEEN ? AND ?",
+		model.OssGeoValid, b[1], b[3], b[0], b[2]), nil
+}
+
+// bboxPolygon renders a min lon, min lat, max lon, max lat box as WKT, in
+// longitude-latitude order
+func bboxPolygon(b [4]float64) string {
+	return fmt.Sprintf("POLYGON((%[1]g %[2]g, %[3]g %[2]g, %[3]g %[4]g, %[1]g %[4]g, %[1]g %[2]g))",
+		b[0], b[1], b[2], b[3])
+}
+
+// MapPoints reads the rows of a map, with the columns shown on a marker
+func (r *repository) MapPoints(ctx context.Context, q MapQuery) ([]model.OssBase, error) {
+	query, err := r.mapQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []model.OssBase
+	err = query.
+		Select("id, idProyek, namaProyek, perusahaanNama, statusPM, invJumlah, _geo_lat, _geo_lon").
+		Order("id ASC").
+		Limit(q.Limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+// MapClusters groups the rows of a map into cells of a grid anchored at
+// -180,-90, so that cells stay put while the map is panned
+func (r *repository) MapClusters(ctx context.Context, q MapQuery) ([]MapCluster, error) {
+	query, err := r.mapQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := query.
+		Select(fmt.Sprintf("FLOOR((_geo_lon + 180) / %[1]g) AS cx, FLOOR((_geo_lat + 90) / %[1]g) AS cy, "+
+			"AVG(_geo_lat) AS lat, AVG(_geo_lon) AS lon, COUNT(*) AS projects, COALESCE(SUM(invJumlah), 0) AS investment",
+			q.Cell)).
+		Group("cx, cy").
+		Order("cy, cx").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clusters []MapCluster
+	for rows.Next() {
+		var cx, cy int64
+		var c MapCluster
+		if err := rows.Scan(&cx, &cy, &c.Lat, &c.Lon, &c.Projects, &c.Investment); err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters, rows.Err()
+}
+
+// geoPending selects the rows a coordinate backfill works on
+func (r *repository) geoPending(ctx context.Context, all bool) *gorm.DB {
+	query := r.db.WithContext(ctx).Table("kswi.oss_base")
+	if !all {
+		query = query.Where("_geo_status IS NULL")
+	}
+	return query
+}
+
+func (r *repository) CountGeoPending(ctx context.Context, all bool) (int64, error) {
+	var count int64
+	err := r.geoPending(ctx, all).Count(&count).Error
+	return count, err
+}
+
+// ListGeoPending reads the coordinates of the next rows to backfill, by id
+func (r *repository) ListGeoPending(ctx context.Context, afterID, limit int, all bool) ([]model.OssBase, error) {
+	var rows []model.OssBase
+	err := r.geoPending(ctx, all).
+		Select("id, perusahaanLat, perusahaanLon").
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+// SaveGeo writes the geo columns of rows in a transaction, leaving
+// _updated_at alone. Rows sharing the same values, such as every row without
+// coordinates, are written by one statement.
+func (r *repository) SaveGeo(ctx context.Context, rows []model.OssBase) error {
+	type geoValues struct {
+		lat, lon float64
+		located  bool
+		status   string
+	}
+
+	groups := make(map[geoValues][]int)
+	var order []geoValues
+	for _, row := range rows {
+		key := geoValues{status: *row.GeoStatus}
+		if row.GeoLat != nil && row.GeoLon != nil {
+			key.lat, key.lon, key.located = *row.GeoLat, *row.GeoLon, true
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], row.ID)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, key := range order {
+			values := map[string]interface{}{"_geo_lat": nil, "_geo_lon": nil, "_geo_status": key.status}
+			if key.located {
+				values["_geo_lat"], values["_geo_lon"] = key.lat, key.lon
+			}
+			if err := tx.Table("kswi.oss_base").Where("id IN ?", groups[key]).Updates(values).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ExportRows reads the rows matching a datatable request in its sort order,
+// in chunks. Each chunk starts after the sort key of the previous one
+// instead of at an offset. Only columns are loaded; fn is called once per
+// chunk.
+func (r *repository) ExportRows(ctx context.Context, req DtDatabaseRequest, columns []string, fn func([]model.OssBase) error) error {
+	order, err := dtSchema.Sort(&req.PaginationRequest, "id")
+	if err != nil {
+		return err
+	}
+	for _, key := range order {
+		if !containsString(columns, key.Field.Column) {
+			columns = append(columns, key.Field.Column)
+		}
+	}
 
+	query, err := r.applyDtFilters(r.db.WithContext(ctx).Model(&model.OssBase{}).Where("_deleted_at IS NULL"), req)
 	if err != nil {
-		return nil, 0, 0, err
+		return err
+	}
+	query = query.Select(columns).Order(order.String()).Limit(exportChunkSize).Session(&gorm.Session{})
+
+	var after []interface{}
+	for {
+		chunk := query
+		if after != nil {
+			seek, args := order.Seek(after)
+			chunk = chunk.Where(seek, args...)
+		}
+
+		var rows []model.OssBase
+		if err := chunk.Find(&rows).Error; err != nil {
+			return err
+		}
+
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := fn(rows); err != nil {
+			return err
+		}
+		if len(rows) < exportChunkSize {
+			return nil
+		}
+
+		after = orderValues(&rows[len(rows)-1], order)
+	}
+}
+
+// applyDtFilters adds the date range, the search and the JSON filters of a
+// datatable request to query. Filters are compiled against dtSchema; invalid
+// ones are returned as a validation error.
+func (r *repository) applyDtFilters(query *gorm.DB, req DtDatabaseRequest) (*gorm.DB, error) {
+	// Handle date filters
+	if req.StartDate != nil && !req.StartDate.IsZero() {
+		query = query.Where("_created_at >= ?", req.StartDate)
+	}
+
+	if req.EndDate != nil && !req.EndDate.IsZero() {
+		query = query.Where("_created_at <= ?", req.EndDate)
+	}
+
+	query = r.applySearch(query, req.Search)
+
+	where, args, err := dtSchema.Compile(req.Filters)
+	if err != nil {
+		return nil, err
+	}
+	if where != "" {
+		query = query.Where(where, args...)
+	}
+
+	return query, nil
+}
+
+// Batch sizes used when writing an upload
+const (
+	uploadRowBatchSize   = 500
+	uploadErrorBatchSize = 1000
+)
+
+func (r *repository) CreateLogUpload(ctx context.Context, upload *model.LogUpload) error {
+	return r.db.WithContext(ctx).Create(upload).Error
+}
+
+func (r *repository) UpdateLogUpload(ctx context.Context, upload *model.LogUpload) error {
+	return r.db.WithContext(ctx).Save(upload).Error
+}
+
+func (r *repository) SetLogUploadJob(ctx context.Context, uploadID int, jobID string) error {
+	return r.db.WithContext(ctx).Model(&model.LogUpload{}).Where("id = ?", uploadID).Update("job_id", jobID).Error
+}
+
+func (r *repository) SetLogUploadRollbackJob(ctx context.Context, uploadID int, jobID string) error {
+	return r.db.WithContext(ctx).Model(&model.LogUpload{}).Where("id = ?", uploadID).Update("rollback_job_id", jobID).Error
+}
+
+// FindLogUploadByJob returns the upload imported or rolled back by a job, or
+// nil when there is none
+func (r *repository) FindLogUploadByJob(ctx context.Context, jobID string) (*model.LogUpload, error) {
+	var upload model.LogUpload
+	err := r.db.WithContext(ctx).Where("job_id = ? OR rollback_job_id = ?", jobID, jobID).First(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// FindProjects returns the live oss_base rows with the given idProyek
+func (r *repository) FindProjects(ctx context.Context, idProyek []string) ([]model.OssBase, error) {
+	var rows []model.OssBase
+	if len(idProyek) == 0 {
+		return rows, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("idProyek IN ? AND _deleted_at IS NULL", idProyek).
+		Order("id ASC").
+		Find(&rows).Error
+	return rows, err
+}
+
+// writeLockName is the MySQL named lock held by imports and rollbacks.
+// oss_base has no unique key on idProyek, so two imports matching projects
+// at the same time would both insert them; the lock runs them one at a time,
+// across instances too.
+const writeLockName = "kswi.oss_base.write"
+
+// writeLockWait is how long one GET_LOCK call waits before the job checks
+// whether it was cancelled
+const writeLockWait = 5
+
+// WithWriteLock runs fn while holding the oss_base write lock, waiting for
+// it as long as ctx allows. The lock lives on a dedicated connection and is
+// released with it. A job waiting here keeps its heartbeat, which also
+// cancels ctx on a cancel request made on another instance.
+func (r *repository) WithWriteLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for {
+		var acquired sql.NullInt64
+		err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", writeLockName, writeLockWait).Scan(&acquired)
+		if err != nil {
+			return err
+		}
+		if acquired.Int64 == 1 {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", writeLockName)
+
+	return fn(ctx)
+}
+
+// SaveUploadBatch writes one batch of an import in a transaction: snapshots
+// of the rows about to be overwritten, new rows, updated rows and row errors.
+// Updates are whole rows merged by prepareBatch and carry the primary key of
+// the matched row, so they are written as INSERT ... ON DUPLICATE KEY UPDATE
+// on that key; _created_at is left untouched.
+func (r *repository) SaveUploadBatch(ctx context.Context, batch *UpsertBatch) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(batch.Snapshots) > 0 {
+			if err := tx.CreateInBatches(batch.Snapshots, uploadRowBatchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(batch.Inserts) > 0 {
+			if err := tx.CreateInBatches(batch.Inserts, uploadRowBatchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(batch.Updates) > 0 {
+			err := tx.Clauses(clause.OnConflict{UpdateAll: true}).
+				CreateInBatches(batch.Updates, uploadRowBatchSize).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(batch.History) > 0 {
+			if err := tx.CreateInBatches(batch.History, uploadErrorBatchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(batch.Errors) > 0 {
+			if err := tx.CreateInBatches(batch.Errors, uploadErrorBatchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DiscardUpload undoes an import that did not complete: overwritten rows are
+// restored, created rows are removed and the history it wrote is dropped
+func (r *repository) DiscardUpload(ctx context.Context, uploadID int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := undoUpload(tx, uploadID, nil, model.RollbackHard, 0, time.Now()); err != nil {
+			return err
+		}
+		return tx.Where("log_upload_id = ?", uploadID).Delete(&model.OssBaseHistory{}).Error
+	})
+}
+
+func (r *repository) ListLogUploads(ctx context.Context, req ListUploadsRequest) ([]model.LogUpload, int, error) {
+	var uploads []model.LogUpload
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.LogUpload{})
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("id DESC").
+		Limit(req.PerPage).
+		Offset((req.Page - 1) * req.PerPage).
+		Find(&uploads).Error
+
+	return uploads, int(total), err
+}
+
+func (r *repository) FindLogUpload(ctx context.Context, id int) (*model.LogUpload, error) {
+	var upload model.LogUpload
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// GetUploadRowStats counts the live oss_base rows of each upload with the
+// range of their tglTerbitOss
+func (r *repository) GetUploadRowStats(ctx context.Context, uploadIDs []int) (map[int]UploadRowStats, error) {
+	stats := make(map[int]UploadRowStats, len(uploadIDs))
+	if len(uploadIDs) == 0 {
+		return stats, nil
+	}
+
+	var rows []UploadRowStats
+	err := r.db.WithContext(ctx).
+		Table("kswi.oss_base").
+		Select("_log_upload_id AS upload_id, COUNT(*) AS row_count, MIN(tglTerbitOss) AS date_from, MAX(tglTerbitOss) AS date_to").
+		Where("_log_upload_id IN ? AND _deleted_at IS NULL", uploadIDs).
+		Group("_log_upload_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		stats[row.UploadID] = row
+	}
+	return stats, nil
+}
+
+// SetLogUploadStatus moves an upload from one status to another and reports
+// whether it was in the expected status
+func (r *repository) SetLogUploadStatus(ctx context.Context, id int, from, to string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&model.LogUpload{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RollbackUpload undoes an upload in one transaction. Rows the upload
+// overwrote are restored from their snapshots and rows it created are soft
+// or hard deleted, unless a later upload or a manual edit changed them.
+func (r *repository) RollbackUpload(ctx context.Context, upload *model.LogUpload, mode string, userID int) (*RollbackResult, error) {
+	var result *RollbackResult
+	now := time.Now()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if result, err = undoUpload(tx, upload.ID, upload.FinishedAt, mode, userID, now); err != nil {
+			return err
+		}
+
+		upload.Status = model.LogUploadRolledBack
+		upload.RolledBackAt = &now
+		upload.RolledBackBy = &userID
+		upload.RollbackMode = &mode
+		return tx.Save(upload).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// undoUpload reverts the rows of an upload inside tx. Rows still tagged
+// with the upload but updated after editedAfter, when set, were edited by
+// hand since and are left alone. Restored rows get a rollback history entry
+// when userID is set. Hard mode also drops the upload's snapshots, which are
+// of no use once its rows are gone.
+func undoUpload(tx *gorm.DB, uploadID int, editedAfter *time.Time, mode string, userID int, now time.Time) (*RollbackResult, error) {
+	result := &RollbackResult{UploadID: uploadID, Mode: mode}
+
+	var snapshots []model.OssBaseSnapshot
+	err := tx.Where("log_upload_id = ?", uploadID).
+		FindInBatches(&snapshots, uploadRowBatchSize, func(_ *gorm.DB, _ int) error {
+			return restoreSnapshots(tx, snapshots, uploadID, editedAfter, userID, result)
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// What is left tagged with the upload was created by it
+	created := tx.Table("kswi.oss_base").Where("_log_upload_id = ?", uploadID)
+	if editedAfter != nil {
+		var edited int64
+		err := created.Session(&gorm.Session{}).
+			Where("_updated_at > ? AND _deleted_at IS NULL", *editedAfter).
+			Count(&edited).Error
+		if err != nil {
+			return nil, err
+		}
+		result.Skipped += int(edited)
+
+		created = created.Where("(_updated_at IS NULL OR _updated_at <= ?)", *editedAfter)
+	}
+
+	var res *gorm.DB
+	if mode == model.RollbackHard {
+		res = created.Delete(&model.OssBase{})
+	} else {
+		res = created.Where("_deleted_at IS NULL").
+			Updates(map[string]interface{}{"_deleted_at": now, "_deleted_by": userID})
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	result.Removed = int(res.RowsAffected)
+
+	if mode == model.RollbackHard {
+		if err := tx.Where("log_upload_id = ?", uploadID).Delete(&model.OssBaseSnapshot{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// restoreSnapshots puts back the rows an upload overwrote. Rows that no longer
+// carry the upload's ID were changed again by a later upload, and rows
+// updated after editedAfter were edited by hand; both are left alone.
+func restoreSnapshots(tx *gorm.DB, snapshots []model.OssBaseSnapshot, uploadID int, editedAfter *time.Time, userID int, result *RollbackResult) error {
+	ids := make([]int, len(snapshots))
+	for i, snapshot := range snapshots {
+		ids[i] = snapshot.OssBaseID
+	}
+
+	var current []model.OssBase
+	if err := tx.Where("id IN ?", ids).Find(&current).Error; err != nil {
+		return err
+	}
+
+	owned := restorableRows(current, uploadID, editedAfter)
+
+	now := time.Now()
+	var history []model.OssBaseHistory
+	for _, snapshot := range snapshots {
+		row, ok := owned[snapshot.OssBaseID]
+		if !ok {
+			result.Skipped++
+			continue
+		}
+
+		var previous model.OssBase
+		if err := json.Unmarshal(snapshot.Data, &previous); err != nil {
+			return fmt.Errorf("invalid snapshot %d: %w", snapshot.ID, err)
+		}
+
+		if userID != 0 {
+			previous.UpdatedBy = &userID
+			history = append(history, newHistory(row.ID, diffRows(row, &previous),
+				model.OssChangeRollback, &uploadID, &userID, now)...)
+		}
+
+		if err := tx.Save(&previous).Error; err != nil {
+			return err
+		}
+		result.Restored++
+	}
+
+	if len(history) > 0 {
+		return tx.CreateInBatches(history, uploadErrorBatchSize).Error
+	}
+	return nil
+}
+
+// restorableRows indexes the rows that still carry the upload's ID and were
+// not updated after editedAfter
+func restorableRows(rows []model.OssBase, uploadID int, editedAfter *time.Time) map[int]*model.OssBase {
+	owned := make(map[int]*model.OssBase, len(rows))
+	for i, row := range rows {
+		if row.LogUploadID == nil || *row.LogUploadID != uploadID {
+			continue
+		}
+		if editedAfter != nil && row.UpdatedAt != nil && row.UpdatedAt.After(*editedAfter) {
+			continue
+		}
+		owned[row.ID] = &rows[i]
+	}
+	return owned
+}
+
+// FindProject returns a live oss_base row, or nil when it does not exist
+func (r *repository) FindProject(ctx context.Context, id int) (*model.OssBase, error) {
+	var row model.OssBase
+	err := r.db.WithContext(ctx).Where("id = ? AND _deleted_at IS NULL", id).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// ProjectExists reports whether an oss_base row exists, deleted or not
+func (r *repository) ProjectExists(ctx context.Context, id int) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.OssBase{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// SaveProjectEdit stores an edited row together with its history entries
+func (r *repository) SaveProjectEdit(ctx context.Context, row *model.OssBase, history []model.OssBaseHistory) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(row).Error; err != nil {
+			return err
+		}
+		return tx.Create(&history).Error
+	})
+}
+
+func (r *repository) ListHistory(ctx context.Context, id int, fields []string, page, perPage int) ([]model.OssBaseHistory, int, error) {
+	var history []model.OssBaseHistory
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.OssBaseHistory{}).Where("oss_base_id = ?", id)
+	if len(fields) > 0 {
+		query = query.Where("field IN ?", fields)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("changed_at DESC, id DESC").
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Find(&history).Error
 
-	return data, total, totalFiltered, nil
+	return history, int(total), err
 }
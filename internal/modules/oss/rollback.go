@@ -0,0 +1,152 @@
+package oss
+
+import (
+	"context"
+	"fmt"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/errors"
+	"kswi-backend/internal/shared/logger"
+	"kswi-backend/internal/worker"
+)
+
+// JobTypeRollback is the job type of upload rollbacks
+const JobTypeRollback = "oss.rollback"
+
+// ListUploads returns a page of uploads, newest first, with the rows each
+// one still owns
+func (s *service) ListUploads(ctx context.Context, req ListUploadsRequest) ([]UploadSummary, int, error) {
+	uploads, total, err := s.repo.ListLogUploads(ctx, req)
+	if err != nil {
+		return nil, 0, errors.NewDatabaseError(fmt.Errorf("failed to list uploads: %w", err))
+	}
+
+	ids := make([]int, len(uploads))
+	for i, upload := range uploads {
+		ids[i] = upload.ID
+	}
+
+	stats, err := s.repo.GetUploadRowStats(ctx, ids)
+	if err != nil {
+		return nil, 0, errors.NewDatabaseError(fmt.Errorf("failed to count upload rows: %w", err))
+	}
+
+	summaries := make([]UploadSummary, len(uploads))
+	for i, upload := range uploads {
+		summaries[i] = UploadSummary{LogUpload: upload, UploadRowStats: stats[upload.ID]}
+	}
+
+	return summaries, total, nil
+}
+
+func (s *service) GetUpload(ctx context.Context, id int) (*UploadSummary, error) {
+	upload, err := s.findUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.repo.GetUploadRowStats(ctx, []int{id})
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to count upload rows: %w", err))
+	}
+
+	return &UploadSummary{LogUpload: *upload, UploadRowStats: stats[id]}, nil
+}
+
+// StartRollback queues a job undoing a completed upload. The upload is
+// locked in the rolling_back status until the job ends; a failed or cancelled
+// rollback changes nothing and returns it to completed.
+func (s *service) StartRollback(ctx context.Context, id int, mode string, userID int) (*RollbackStarted, error) {
+	upload, err := s.findUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := s.repo.SetLogUploadStatus(ctx, id, model.LogUploadCompleted, model.LogUploadRollingBack)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to lock upload: %w", err))
+	}
+	if !locked {
+		return nil, errors.NewConflictError(fmt.Sprintf("Only completed uploads can be rolled back; this upload is %s", upload.Status))
+	}
+	upload.Status = model.LogUploadRollingBack
+
+	jobUpload := *upload
+	job, err := s.jobs.Enqueue(ctx, worker.Spec{
+		Type:      JobTypeRollback,
+		CreatedBy: userID,
+		Task: func(ctx context.Context, progress *worker.Progress) (interface{}, error) {
+			var result *RollbackResult
+			err := s.repo.WithWriteLock(ctx, func(ctx context.Context) error {
+				var err error
+				result, err = s.repo.RollbackUpload(ctx, &jobUpload, mode, userID)
+				return err
+			})
+			return result, err
+		},
+		OnFinish: func(ctx context.Context, job *model.Job) {
+			if job.Status != model.JobCompleted {
+				s.unlockUpload(ctx, id)
+			}
+		},
+	})
+	if err != nil {
+		s.unlockUpload(ctx, id)
+
+		if err == worker.ErrQueueFull || err == worker.ErrShuttingDown {
+			return nil, errors.NewConflictError("The job queue is full, please try again later")
+		}
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to queue rollback: %w", err))
+	}
+
+	upload.RollbackJobID = &job.ID
+	if err := s.repo.SetLogUploadRollbackJob(ctx, id, job.ID); err != nil {
+		logger.FromContext(ctx).WithModule("oss").WithError(err).
+			WithFields("log_upload_id", id, "job_id", job.ID).
+			Error("Failed to link upload to its rollback job")
+	}
+
+	return &RollbackStarted{Upload: upload, Job: job}, nil
+}
+
+// RecoverJob cleans up after an import or rollback job whose process
+// stopped before the job finished: a partial import is discarded and its
+// upload marked failed, an upload left rolling back is unlocked. A rollback
+// runs in one transaction, so nothing of it was applied.
+func (s *service) RecoverJob(ctx context.Context, job *model.Job) {
+	upload, err := s.repo.FindLogUploadByJob(ctx, job.ID)
+	if err != nil {
+		logger.FromContext(ctx).WithModule("oss").WithError(err).
+			WithFields("job_id", job.ID).
+			Error("Failed to find the upload of a recovered job")
+		return
+	}
+	if upload == nil {
+		return
+	}
+
+	switch {
+	case job.Type == JobTypeImport && (upload.Status == model.LogUploadQueued || upload.Status == model.LogUploadProcessing):
+		s.failUpload(ctx, upload, job)
+	case job.Type == JobTypeRollback && upload.Status == model.LogUploadRollingBack:
+		s.unlockUpload(ctx, upload.ID)
+	}
+}
+
+func (s *service) unlockUpload(ctx context.Context, id int) {
+	if _, err := s.repo.SetLogUploadStatus(ctx, id, model.LogUploadRollingBack, model.LogUploadCompleted); err != nil {
+		logger.FromContext(ctx).WithModule("oss").WithError(err).
+			WithFields("log_upload_id", id).
+			Error("Failed to unlock upload after an unfinished rollback")
+	}
+}
+
+func (s *service) findUpload(ctx context.Context, id int) (*model.LogUpload, error) {
+	upload, err := s.repo.FindLogUpload(ctx, id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find upload: %w", err))
+	}
+	if upload == nil {
+		return nil, errors.NewNotFoundError("Upload")
+	}
+	return upload, nil
+}
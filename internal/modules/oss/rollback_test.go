@@ -0,0 +1,50 @@
+package oss
+
+import (
+	"kswi-backend/internal/model"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRestorableRows(t *testing.T) {
+	uploadID, laterUpload := 5, 6
+	finishedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	before, after := finishedAt.Add(-time.Minute), finishedAt.Add(time.Minute)
+
+	rows := []model.OssBase{
+		{ID: 1, LogUploadID: &uploadID},
+		{ID: 2, LogUploadID: &uploadID, UpdatedAt: &before},
+		{ID: 3, LogUploadID: &uploadID, UpdatedAt: &finishedAt},
+		{ID: 4, LogUploadID: &uploadID, UpdatedAt: &after},
+		{ID: 5, LogUploadID: &laterUpload},
+		{ID: 6},
+	}
+
+	tests := []struct {
+		name        string
+		editedAfter *time.Time
+		want        []int
+	}{
+		{name: "edits are ignored without a finish time", want: []int{1, 2, 3, 4}},
+		{name: "rows edited after the upload are kept", editedAfter: &finishedAt, want: []int{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for id, row := range restorableRows(rows, uploadID, tt.editedAfter) {
+				if row.ID != id {
+					t.Errorf("row %d indexed as %d", row.ID, id)
+				}
+				got = append(got, id)
+			}
+			sort.Ints(got)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("restorable rows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
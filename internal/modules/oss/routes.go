@@ -2,18 +2,43 @@ package oss
 
 import (
 	"kswi-backend/internal/config"
+	"kswi-backend/internal/middleware"
+	"kswi-backend/internal/shared/cache"
+	"kswi-backend/internal/worker"
 
 	"github.com/gin-gonic/gin"
 )
 
 func RegisterRoutes(r *gin.RouterGroup) {
 	repo := NewRepository(config.GetDB())
-	svc := NewService(repo)
+	svc := NewService(repo, worker.Get(), cache.Get())
 	h := NewHandler(svc)
 
-	routes := r.Group("/oss")
+	if jobs := worker.Get(); jobs != nil {
+		jobs.OnRecover(JobTypeImport, svc.RecoverJob)
+		jobs.OnRecover(JobTypeRollback, svc.RecoverJob)
+	}
+
+	routes := r.Group("/oss", middleware.RequirePermission("oss.read"))
 	{
 		routes.GET("/tree", h.Test)
 		routes.POST("/dt", h.DtDatabase)
+		routes.POST("/facets", h.Facets)
+		routes.POST("/map", h.Map)
+		routes.POST("/geo/backfill", middleware.RequirePermission("oss.edit"), h.GeoBackfill)
+		routes.POST("/export", middleware.RequirePermission("oss.export"), h.Export)
+		routes.POST("/stats/summary", h.StatsSummary)
+		routes.POST("/stats/grouped", h.StatsGrouped)
+		routes.POST("/stats/timeseries", h.TimeSeries)
+		routes.POST("/stats/pivot", h.Pivot)
+		routes.POST("/stats/pivot/export", middleware.RequirePermission("oss.export"), h.PivotExport)
+		routes.POST("/upload", middleware.RequirePermission("oss.import"), h.Upload)
+		routes.GET("/:id/history", h.ProjectHistory)
+		routes.PATCH("/:id", middleware.RequirePermission("oss.edit"), h.UpdateProject)
+
+		uploads := routes.Group("/uploads", middleware.RequirePermission("oss.import"))
+		uploads.GET("", h.ListUploads)
+		uploads.GET("/:id", h.GetUpload)
+		uploads.POST("/:id/rollback", middleware.RequirePermission("oss.rollback"), h.RollbackUpload)
 	}
 }
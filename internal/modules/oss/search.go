@@ -0,0 +1,106 @@
+package oss
+
+import (
+	"kswi-backend/internal/model"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// searchIndex is the FULLTEXT index over searchColumns
+const searchIndex = "ft_oss_base_search"
+
+// searchColumns are the text columns matched by the datatable search, in the
+// order of searchIndex
+var searchColumns = []string{"namaProyek", "perusahaanNama", "nib", "kbliJudul", "perusahaanKota"}
+
+const (
+	// maxSearchTerms caps the words of a search
+	maxSearchTerms = 10
+	// minFulltextTerm is innodb_ft_min_token_size; shorter words are not
+	// indexed and use the LIKE fallback
+	minFulltextTerm = 3
+)
+
+var searchTermPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// fulltextStopwords is the default InnoDB stopword list. A required
+// stopword never matches in boolean mode, so these words use the LIKE
+// fallback.
+var fulltextStopwords = map[string]bool{
+	"a": true, "about": true, "an": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "com": true, "de": true, "en": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"la": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "what": true, "when": true,
+	"where": true, "who": true, "will": true, "with": true, "und": true,
+	"www": true,
+}
+
+// fulltextIndex reports once per repository whether searchIndex exists
+type fulltextIndex struct {
+	once   sync.Once
+	exists bool
+}
+
+func (f *fulltextIndex) available(db *gorm.DB) bool {
+	f.once.Do(func() {
+		f.exists = db.Migrator().HasIndex(&model.OssBase{}, searchIndex)
+	})
+	return f.exists
+}
+
+// searchTerms splits a search into lowercase words, dropping punctuation
+func searchTerms(search string) []string {
+	terms := searchTermPattern.FindAllString(strings.ToLower(search), -1)
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// applySearch restricts query to rows containing every word of search in
+// one of searchColumns. Words the FULLTEXT index can match are looked up as
+// word prefixes through it; the others, or all of them when the index is
+// missing, fall back to a substring LIKE.
+func (r *repository) applySearch(query *gorm.DB, search string) *gorm.DB {
+	terms := searchTerms(search)
+	if len(terms) == 0 {
+		return query
+	}
+
+	var indexed, unindexed []string
+	if r.fulltext.available(r.db) {
+		for _, term := range terms {
+			if len([]rune(term)) >= minFulltextTerm && !fulltextStopwords[term] {
+				indexed = append(indexed, "+"+term+"*")
+			} else {
+				unindexed = append(unindexed, term)
+			}
+		}
+	} else {
+		unindexed = terms
+	}
+
+	if len(indexed) > 0 {
+		columns := "`" + strings.Join(searchColumns, "`, `") + "`"
+		query = query.Where("MATCH("+columns+") AGAINST (? IN BOOLEAN MODE)", strings.Join(indexed, " "))
+	}
+
+	like := make([]string, len(searchColumns))
+	for i, column := range searchColumns {
+		like[i] = "`" + column + "` LIKE ?"
+	}
+	condition := "(" + strings.Join(like, " OR ") + ")"
+
+	for _, term := range unindexed {
+		args := make([]interface{}, len(searchColumns))
+		for i := range args {
+			args[i] = "%" + term + "%"
+		}
+		query = query.Where(condition, args...)
+	}
+	return query
+}
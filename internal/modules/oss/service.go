@@ -1,19 +1,116 @@
 package oss
 
-import "context"
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/cache"
+	"kswi-backend/internal/shared/errors"
+	"kswi-backend/internal/shared/logger"
+	"kswi-backend/internal/shared/pagination"
+	"kswi-backend/internal/worker"
+	"time"
+)
 
 type Service interface {
-	DtDatabase(ctx context.Context, req DtDatabaseRequest) ([]DtDatabaseResponse, int, int, error)
+	DtDatabase(ctx context.Context, req DtDatabaseRequest) (*DtDatabasePage, error)
+	NewExport(ctx context.Context, req ExportRequest) (*Export, error)
+	StatsSummary(ctx context.Context, req StatsFilter) (*StatsTotals, error)
+	StatsGrouped(ctx context.Context, req StatsGroupedRequest) (*StatsGrouped, error)
+	TimeSeries(ctx context.Context, req TimeSeriesRequest) (*TimeSeriesResult, error)
+	Pivot(ctx context.Context, req PivotRequest) (*PivotTable, error)
+	Facets(ctx context.Context, req FacetsRequest) ([]Facet, error)
+	Map(ctx context.Context, req MapRequest) (*FeatureCollection, error)
+	StartGeoBackfill(ctx context.Context, all bool, userID int) (*model.Job, error)
+	StartUpload(ctx context.Context, input UploadInput) (*UploadStarted, error)
+	ListUploads(ctx context.Context, req ListUploadsRequest) ([]UploadSummary, int, error)
+	GetUpload(ctx context.Context, id int) (*UploadSummary, error)
+	StartRollback(ctx context.Context, id int, mode string, userID int) (*RollbackStarted, error)
+	RecoverJob(ctx context.Context, job *model.Job)
+	UpdateProject(ctx context.Context, id int, patch map[string]interface{}, userID int) (*model.OssBase, error)
+	GetProjectHistory(ctx context.Context, id int, req HistoryRequest) ([]model.OssBaseHistory, int, error)
 }
 
 type service struct {
-	repo Repository
+	repo  Repository
+	jobs  *worker.Manager
+	cache cache.Cache
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, jobs *worker.Manager, countCache cache.Cache) Service {
+	return &service{repo: repo, jobs: jobs, cache: countCache}
 }
 
-func (s *service) DtDatabase(ctx context.Context, req DtDatabaseRequest) ([]DtDatabaseResponse, int, int, error) {
-	return s.repo.DtDatabase(ctx, req)
+// countCacheTTL is how long the totals of a datatable request are cached in
+// the cached count mode
+const countCacheTTL = 5 * time.Minute
+
+func (s *service) DtDatabase(ctx context.Context, req DtDatabaseRequest) (*DtDatabasePage, error) {
+	mode := req.CountMode()
+	if mode == pagination.CountNone && req.Mode != pagination.ModeCursor {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "count",
+			Message: "Totals can only be skipped in cursor mode",
+		}})
+	}
+
+	if mode != pagination.CountCached {
+		return s.dtDatabase(ctx, req, mode)
+	}
+
+	key := dtCountCacheKey(req)
+
+	var counts dtCounts
+	found, err := s.cache.Get(ctx, key, &counts)
+	if err != nil {
+		logger.FromContext(ctx).WithModule("oss").WithError(err).Warn("Failed to read cached datatable counts")
+	}
+	if found {
+		page, err := s.dtDatabase(ctx, req, pagination.CountNone)
+		if err != nil {
+			return nil, err
+		}
+		page.Total, page.Filtered = counts.Total, counts.Filtered
+		return page, nil
+	}
+
+	page, err := s.dtDatabase(ctx, req, pagination.CountExact)
+	if err != nil {
+		return nil, err
+	}
+
+	counts = dtCounts{Total: page.Total, Filtered: page.Filtered}
+	if err := s.cache.Set(ctx, key, &counts, countCacheTTL); err != nil {
+		logger.FromContext(ctx).WithModule("oss").WithError(err).Warn("Failed to cache datatable counts")
+	}
+	return page, nil
+}
+
+func (s *service) dtDatabase(ctx context.Context, req DtDatabaseRequest, count string) (*DtDatabasePage, error) {
+	page, err := s.repo.DtDatabase(ctx, req, count)
+	if err != nil {
+		var appErr *errors.AppError
+		if errors.As(err, &appErr) {
+			return nil, err
+		}
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to query datatable: %w", err))
+	}
+	return page, nil
+}
+
+// dtCountCacheKey identifies the rows selected by a request, whatever its
+// page, sort or mode
+func dtCountCacheKey(req DtDatabaseRequest) string {
+	data, _ := json.Marshal(struct {
+		Search    string              `json:"search"`
+		Filters   *pagination.Filters `json:"filters"`
+		StartDate *time.Time          `json:"start_date"`
+		EndDate   *time.Time          `json:"end_date"`
+	}{req.Search, req.Filters, req.StartDate, req.EndDate})
+
+	sum := sha1.Sum(data)
+	return "oss:dt:count:" + hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,202 @@
+package oss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"kswi-backend/internal/shared/errors"
+	"kswi-backend/internal/shared/pagination"
+	"sort"
+	"strings"
+)
+
+// defaultDateColumn is the date column of a statistic's date range when
+// none is chosen
+const defaultDateColumn = "tglTerbitOss"
+
+// defaultStatsLimit is the number of groups returned when no limit is given
+const defaultStatsLimit = 100
+
+// statsDimensions are the columns statistics can be grouped by
+var statsDimensions = map[string]bool{
+	"perusahaanProv":  true,
+	"perusahaanKota":  true,
+	"kbli":            true,
+	"sektorPembina":   true,
+	"perusahaanSkala": true,
+	"statusPM":        true,
+	"resiko":          true,
+	"jenisBadan":      true,
+}
+
+// statsMeasures are the numeric columns summed by the statistics
+var statsMeasures = []string{"invJumlah", "invModalTetap", "invModalKerja", "tenagaKerja"}
+
+// dateColumn resolves the date column of a date range by JSON or DB name
+func dateColumn(name string) (string, error) {
+	if name == "" {
+		return defaultDateColumn, nil
+	}
+
+	field, ok := dtSchema.Field(name)
+	if !ok || field.Type != pagination.FieldDate {
+		return "", errors.NewValidationError([]errors.ValidationError{{
+			Field:   "date_field",
+			Message: fmt.Sprintf("Unknown date column '%s'", name),
+		}})
+	}
+	return field.Column, nil
+}
+
+// dimensionColumn resolves a whitelisted dimension by JSON or DB name
+func dimensionColumn(param, name string) (*pagination.Field, error) {
+	field, ok := dtSchema.Field(name)
+	if !ok || !statsDimensions[field.Column] {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   param,
+			Message: fmt.Sprintf("Must be one of %s", strings.Join(sortedKeys(statsDimensions), ", ")),
+		}})
+	}
+	return field, nil
+}
+
+// StatsSummary returns the project count and measures of the matching rows
+func (s *service) StatsSummary(ctx context.Context, req StatsFilter) (*StatsTotals, error) {
+	rows, err := s.aggregate(ctx, AggregateQuery{Filter: req, Measures: statsMeasures})
+	if err != nil {
+		return nil, err
+	}
+
+	totals := StatsTotals{Measures: emptyMeasures()}
+	if len(rows) > 0 {
+		totals = statsTotals(rows[0])
+	}
+	return &totals, nil
+}
+
+// StatsGrouped returns the project count and measures per value of a dimension
+func (s *service) StatsGrouped(ctx context.Context, req StatsGroupedRequest) (*StatsGrouped, error) {
+	dimension, err := dimensionColumn("group_by", req.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy, err := statsOrder(req.OrderBy, req.OrderDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultStatsLimit
+	}
+
+	// One extra group tells whether the list was cut
+	rows, err := s.aggregate(ctx, AggregateQuery{
+		Filter:   req.StatsFilter,
+		GroupBy:  []string{dimension.Column},
+		Measures: statsMeasures,
+		OrderBy:  orderBy,
+		Limit:    limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := s.StatsSummary(ctx, req.StatsFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StatsGrouped{
+		GroupBy:   dimension.Name,
+		Groups:    make([]StatsGroup, 0, len(rows)),
+		Truncated: len(rows) > limit,
+		Totals:    *summary,
+	}
+	if result.Truncated {
+		rows = rows[:limit]
+	}
+	for _, row := range rows {
+		result.Groups = append(result.Groups, StatsGroup{Key: row.Keys[0], StatsTotals: statsTotals(row)})
+	}
+
+	return result, nil
+}
+
+// statsOrder builds the ORDER BY of grouped statistics. Groups are ordered
+// by projects, descending, unless told otherwise; ties are broken by key.
+func statsOrder(orderBy string, desc *bool) (string, error) {
+	descending := orderBy == "" || orderBy == "projects"
+	if desc != nil {
+		descending = *desc
+	}
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+
+	switch orderBy {
+	case "", "projects":
+		return "projects " + direction + ", g0 ASC", nil
+	case "key":
+		return "g0 " + direction, nil
+	}
+
+	if field, ok := dtSchema.Field(orderBy); ok {
+		for i, measure := range statsMeasures {
+			if field.Column == measure {
+				return fmt.Sprintf("m%d_sum %s, g0 ASC", i, direction), nil
+			}
+		}
+	}
+
+	return "", errors.NewValidationError([]errors.ValidationError{{
+		Field:   "order_by",
+		Message: "Must be key, projects or one of " + strings.Join(statsMeasures, ", "),
+	}})
+}
+
+func (s *service) aggregate(ctx context.Context, q AggregateQuery) ([]AggregateRow, error) {
+	rows, err := s.repo.Aggregate(ctx, q)
+	if err != nil {
+		var appErr *errors.AppError
+		if errors.As(err, &appErr) {
+			return nil, err
+		}
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to aggregate oss_base: %w", err))
+	}
+	return rows, nil
+}
+
+// statsTotals converts an aggregate row over statsMeasures
+func statsTotals(row AggregateRow) StatsTotals {
+	totals := StatsTotals{Projects: row.Projects, Measures: make(map[string]StatsMeasure, len(statsMeasures))}
+	for i, column := range statsMeasures {
+		totals.Measures[measureName(column)] = StatsMeasure{Sum: json.Number(row.Sums[i]), Count: row.Counts[i]}
+	}
+	return totals
+}
+
+func emptyMeasures() map[string]StatsMeasure {
+	measures := make(map[string]StatsMeasure, len(statsMeasures))
+	for _, column := range statsMeasures {
+		measures[measureName(column)] = StatsMeasure{Sum: "0"}
+	}
+	return measures
+}
+
+// measureName is the JSON name of a measured column
+func measureName(column string) string {
+	field, _ := dtSchema.Field(column)
+	return field.Name
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,441 @@
+package oss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/shared/errors"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Time series intervals
+const (
+	IntervalDay     = "day"
+	IntervalWeek    = "week"
+	IntervalMonth   = "month"
+	IntervalQuarter = "quarter"
+	IntervalYear    = "year"
+)
+
+// Time series comparisons
+const (
+	ComparePrevious = "previous"
+	CompareYear     = "year"
+)
+
+const (
+	// maxTimeSeriesBuckets caps the buckets of a series
+	maxTimeSeriesBuckets = 1000
+	// maxTimeSeriesSplits caps the series of a split; smaller ones go to Other
+	maxTimeSeriesSplits = 20
+)
+
+// timeSeriesColumns are the date columns a time series can be built on
+var timeSeriesColumns = map[string]bool{
+	"tglTerbitOss": true,
+	"tglPengajuan": true,
+	"_created_at":  true,
+}
+
+// timeSeriesMeasure is the investment summed per bucket
+const timeSeriesMeasure = "invJumlah"
+
+// bucketTotals accumulates the rows of a bucket
+type bucketTotals struct {
+	projects   int64
+	investment big.Int
+}
+
+func (b *bucketTotals) add(projects int64, investment *big.Int) {
+	b.projects += projects
+	b.investment.Add(&b.investment, investment)
+}
+
+// seriesTotals are the bucket totals of one series, keyed by bucket start
+type seriesTotals struct {
+	key *string
+	// buckets hold the days of the requested range only
+	buckets map[time.Time]*bucketTotals
+	// days are the totals per day, including the days before the range read
+	// for comparisons
+	days     map[time.Time]*bucketTotals
+	projects int64
+}
+
+// TimeSeries buckets the matching rows by day, week, month, quarter or year.
+// Dates are days of the display timezone: rows are grouped by local day in
+// SQL, using the zone's UTC offset at the start of the range, and the days
+// are rolled up into buckets here. Buckets without rows are zero-filled. A
+// first or last bucket cut by the range is compared with the same days of the
+// earlier bucket.
+func (s *service) TimeSeries(ctx context.Context, req TimeSeriesRequest) (*TimeSeriesResult, error) {
+	column, err := timeSeriesColumn(req.DateField)
+	if err != nil {
+		return nil, err
+	}
+
+	var splitBy []string
+	result := &TimeSeriesResult{
+		Interval:  req.Interval,
+		DateField: column,
+		Timezone:  config.Location().String(),
+		Compare:   req.Compare,
+	}
+	if req.SplitBy != "" {
+		dimension, err := dimensionColumn("split_by", req.SplitBy)
+		if err != nil {
+			return nil, err
+		}
+		splitBy = []string{dimension.Column}
+		result.SplitBy = dimension.Name
+	}
+
+	// Dates are handled as UTC midnights standing for local days
+	var from, to time.Time
+	if req.DateFrom != "" {
+		from, _ = time.Parse("2006-01-02", req.DateFrom)
+	} else if req.Compare != "" {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "date_from",
+			Message: "Required when compare is set",
+		}})
+	}
+	if req.DateTo != "" {
+		to, _ = time.Parse("2006-01-02", req.DateTo)
+	}
+	if !from.IsZero() && !to.IsZero() {
+		if to.Before(from) {
+			return nil, errors.NewValidationError([]errors.ValidationError{{
+				Field:   "date_to",
+				Message: "Must not be before date_from",
+			}})
+		}
+		if err := checkBucketCount(req.Interval, from, to); err != nil {
+			return nil, err
+		}
+	}
+
+	filter := req.StatsFilter
+	filter.DateField = column
+	reference := time.Now()
+	if !from.IsZero() {
+		// Read the bucket the first one is compared with as well
+		first := bucketStart(req.Interval, from)
+		switch req.Compare {
+		case ComparePrevious:
+			filter.DateFrom = nextBucket(req.Interval, first, -1).Format("2006-01-02")
+		case CompareYear:
+			filter.DateFrom = bucketStart(req.Interval, first.AddDate(-1, 0, 0)).Format("2006-01-02")
+		}
+		reference = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, config.Location())
+	}
+	_, offset := reference.In(config.Location()).Zone()
+
+	rows, err := s.aggregate(ctx, AggregateQuery{
+		Filter:    filter,
+		DayColumn: column,
+		UTCOffset: offset,
+		GroupBy:   splitBy,
+		Measures:  []string{timeSeriesMeasure},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	series := make(map[string]*seriesTotals)
+	var order []*seriesTotals
+	var first, last time.Time
+	for _, row := range rows {
+		day, err := time.Parse("2006-01-02", *row.Keys[0])
+		if err != nil {
+			return nil, errors.NewInternalError(fmt.Errorf("unexpected day %q: %w", *row.Keys[0], err))
+		}
+		investment, ok := new(big.Int).SetString(row.Sums[0], 10)
+		if !ok {
+			return nil, errors.NewInternalError(fmt.Errorf("unexpected sum %q", row.Sums[0]))
+		}
+
+		var key *string
+		if len(splitBy) > 0 {
+			key = row.Keys[1]
+		}
+		totals, ok := series[groupKey(key)]
+		if !ok {
+			totals = &seriesTotals{key: key, buckets: map[time.Time]*bucketTotals{}, days: map[time.Time]*bucketTotals{}}
+			series[groupKey(key)] = totals
+			order = append(order, totals)
+		}
+
+		addBucket(totals.days, day, row.Projects, investment)
+		if !from.IsZero() && day.Before(from) {
+			continue
+		}
+		addBucket(totals.buckets, bucketStart(req.Interval, day), row.Projects, investment)
+		totals.projects += row.Projects
+
+		if first.IsZero() || day.Before(first) {
+			first = day
+		}
+		if day.After(last) {
+			last = day
+		}
+	}
+
+	if !from.IsZero() {
+		first = from
+	}
+	if !to.IsZero() {
+		last = to
+	}
+	if first.IsZero() || last.IsZero() {
+		result.Series = []TimeSeries{}
+		return result, nil
+	}
+	if err := checkBucketCount(req.Interval, first, last); err != nil {
+		return nil, err
+	}
+
+	var starts []time.Time
+	for start := bucketStart(req.Interval, first); !start.After(last); start = nextBucket(req.Interval, start, 1) {
+		starts = append(starts, start)
+	}
+
+	if len(splitBy) == 0 {
+		totals := order
+		if len(totals) == 0 {
+			totals = []*seriesTotals{{buckets: map[time.Time]*bucketTotals{}, days: map[time.Time]*bucketTotals{}}}
+		}
+		result.Series = []TimeSeries{buildSeries(totals[0], starts, first, last, req.Interval, req.Compare)}
+		return result, nil
+	}
+
+	// The largest series by projects are kept, the rest summed up in Other
+	sort.SliceStable(order, func(i, j int) bool { return order[i].projects > order[j].projects })
+	result.Series = make([]TimeSeries, 0, len(order))
+	var other *seriesTotals
+	for i, totals := range order {
+		if totals.projects == 0 {
+			continue
+		}
+		if i < maxTimeSeriesSplits {
+			result.Series = append(result.Series, buildSeries(totals, starts, first, last, req.Interval, req.Compare))
+			continue
+		}
+
+		if other == nil {
+			other = &seriesTotals{buckets: map[time.Time]*bucketTotals{}, days: map[time.Time]*bucketTotals{}}
+		}
+		for start, b := range totals.buckets {
+			addBucket(other.buckets, start, b.projects, &b.investment)
+		}
+		for day, b := range totals.days {
+			addBucket(other.days, day, b.projects, &b.investment)
+		}
+	}
+	if other != nil {
+		series := buildSeries(other, starts, first, last, req.Interval, req.Compare)
+		result.Other = &series
+	}
+
+	return result, nil
+}
+
+// timeSeriesColumn resolves the date column of a time series
+func timeSeriesColumn(name string) (string, error) {
+	column, err := dateColumn(name)
+	if err != nil {
+		return "", err
+	}
+	if !timeSeriesColumns[column] {
+		return "", errors.NewValidationError([]errors.ValidationError{{
+			Field:   "date_field",
+			Message: "Must be one of tglTerbitOss, tglPengajuan, _created_at",
+		}})
+	}
+	return column, nil
+}
+
+func checkBucketCount(interval string, from, to time.Time) error {
+	count := 0
+	for start := bucketStart(interval, from); !start.After(to); start = nextBucket(interval, start, 1) {
+		if count++; count > maxTimeSeriesBuckets {
+			return errors.NewValidationError([]errors.ValidationError{{
+				Field:   "interval",
+				Message: fmt.Sprintf("The range has more than %d buckets; use a larger interval or a shorter range", maxTimeSeriesBuckets),
+			}})
+		}
+	}
+	return nil
+}
+
+// buildSeries lists the buckets of a series in order, zero-filled. first and
+// last are the days of the range.
+func buildSeries(totals *seriesTotals, starts []time.Time, first, last time.Time, interval, compare string) TimeSeries {
+	series := TimeSeries{Key: totals.key, Points: make([]TimeSeriesPoint, len(starts))}
+
+	for i, start := range starts {
+		b := bucketOrZero(totals.buckets, start)
+		point := TimeSeriesPoint{
+			Period:     bucketPeriod(interval, start),
+			Start:      start.Format("2006-01-02"),
+			Projects:   b.projects,
+			Investment: json.Number(b.investment.String()),
+		}
+
+		if compare != "" {
+			baseStart := nextBucket(interval, start, -1)
+			if compare == CompareYear {
+				baseStart = bucketStart(interval, start.AddDate(-1, 0, 0))
+			}
+
+			// A bucket cut by the range is compared with its days within the
+			// range, moved to the same place in the earlier bucket and kept
+			// within it; a whole bucket with the whole earlier one
+			end := nextBucket(interval, start, 1).AddDate(0, 0, -1)
+			baseFrom := baseStart
+			baseTo := nextBucket(interval, baseStart, 1).AddDate(0, 0, -1)
+			if start.Before(first) || end.After(last) {
+				baseEnd := baseTo
+				baseFrom = baseStart.Add(maxTime(start, first).Sub(start))
+				baseTo = baseStart.Add(minTime(end, last).Sub(start))
+				if baseTo.After(baseEnd) {
+					baseTo = baseEnd
+				}
+			}
+
+			point.Change = bucketChange(bucketPeriod(interval, baseStart), sumDays(totals.days, baseFrom, baseTo), b)
+			point.Change.From = baseFrom.Format("2006-01-02")
+			point.Change.To = baseTo.Format("2006-01-02")
+		}
+
+		series.Points[i] = point
+	}
+
+	return series
+}
+
+func bucketChange(period string, base, current *bucketTotals) *TimeSeriesChange {
+	delta := new(big.Int).Sub(&current.investment, &base.investment)
+
+	change := &TimeSeriesChange{
+		Period:          period,
+		Projects:        base.projects,
+		Investment:      json.Number(base.investment.String()),
+		ProjectsDelta:   current.projects - base.projects,
+		InvestmentDelta: json.Number(delta.String()),
+	}
+	if base.projects != 0 {
+		change.ProjectsPercent = percent(float64(change.ProjectsDelta) / float64(base.projects))
+	}
+	if base.investment.Sign() != 0 {
+		ratio, _ := new(big.Rat).SetFrac(delta, &base.investment).Float64()
+		change.InvestmentPercent = percent(ratio)
+	}
+	return change
+}
+
+// percent turns a ratio into a percentage rounded to two decimals
+func percent(ratio float64) *float64 {
+	p := math.Round(ratio*10000) / 100
+	return &p
+}
+
+func addBucket(buckets map[time.Time]*bucketTotals, start time.Time, projects int64, investment *big.Int) {
+	b, ok := buckets[start]
+	if !ok {
+		b = &bucketTotals{}
+		buckets[start] = b
+	}
+	b.add(projects, investment)
+}
+
+// sumDays totals the days from..to; it is zero when to is before from
+func sumDays(days map[time.Time]*bucketTotals, from, to time.Time) *bucketTotals {
+	totals := &bucketTotals{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if b, ok := days[day]; ok {
+			totals.add(b.projects, &b.investment)
+		}
+	}
+	return totals
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func bucketOrZero(buckets map[time.Time]*bucketTotals, start time.Time) *bucketTotals {
+	if b, ok := buckets[start]; ok {
+		return b
+	}
+	return &bucketTotals{}
+}
+
+// groupKey turns a nullable group key into a map key
+func groupKey(key *string) string {
+	if key == nil {
+		return "\x00"
+	}
+	return "=" + *key
+}
+
+// bucketStart returns the first day of the bucket holding day. Weeks start
+// on Monday.
+func bucketStart(interval string, day time.Time) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case IntervalMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case IntervalQuarter:
+		return time.Date(day.Year(), day.Month()-(day.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case IntervalYear:
+		return time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// nextBucket moves the start of a bucket by n buckets
+func nextBucket(interval string, start time.Time, n int) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7*n)
+	case IntervalMonth:
+		return start.AddDate(0, n, 0)
+	case IntervalQuarter:
+		return start.AddDate(0, 3*n, 0)
+	case IntervalYear:
+		return start.AddDate(n, 0, 0)
+	}
+	return start.AddDate(0, 0, n)
+}
+
+// bucketPeriod names the bucket starting at start
+func bucketPeriod(interval string, start time.Time) string {
+	switch interval {
+	case IntervalWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case IntervalMonth:
+		return start.Format("2006-01")
+	case IntervalQuarter:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())+2)/3)
+	case IntervalYear:
+		return start.Format("2006")
+	}
+	return start.Format("2006-01-02")
+}
@@ -0,0 +1,157 @@
+package oss
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestBucketStart(t *testing.T) {
+	tests := []struct {
+		interval string
+		day      string
+		want     string
+	}{
+		{interval: IntervalDay, day: "2024-03-15", want: "2024-03-15"},
+		{interval: IntervalWeek, day: "2024-03-13", want: "2024-03-11"},
+		{interval: IntervalWeek, day: "2024-03-11", want: "2024-03-11"},
+		{interval: IntervalWeek, day: "2024-03-17", want: "2024-03-11"},
+		{interval: IntervalWeek, day: "2024-01-02", want: "2024-01-01"},
+		{interval: IntervalWeek, day: "2023-01-01", want: "2022-12-26"},
+		{interval: IntervalMonth, day: "2024-02-29", want: "2024-02-01"},
+		{interval: IntervalQuarter, day: "2024-03-31", want: "2024-01-01"},
+		{interval: IntervalQuarter, day: "2024-05-15", want: "2024-04-01"},
+		{interval: IntervalQuarter, day: "2024-12-01", want: "2024-10-01"},
+		{interval: IntervalYear, day: "2024-07-04", want: "2024-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.interval+" "+tt.day, func(t *testing.T) {
+			if got := bucketStart(tt.interval, day(tt.day)).Format("2006-01-02"); got != tt.want {
+				t.Errorf("bucketStart = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextBucket(t *testing.T) {
+	tests := []struct {
+		interval string
+		start    string
+		n        int
+		want     string
+	}{
+		{interval: IntervalDay, start: "2024-02-28", n: 1, want: "2024-02-29"},
+		{interval: IntervalDay, start: "2024-03-01", n: -1, want: "2024-02-29"},
+		{interval: IntervalWeek, start: "2024-12-30", n: 1, want: "2025-01-06"},
+		{interval: IntervalWeek, start: "2024-01-01", n: -1, want: "2023-12-25"},
+		{interval: IntervalMonth, start: "2024-01-01", n: 1, want: "2024-02-01"},
+		{interval: IntervalMonth, start: "2024-01-01", n: -1, want: "2023-12-01"},
+		{interval: IntervalQuarter, start: "2024-10-01", n: 1, want: "2025-01-01"},
+		{interval: IntervalQuarter, start: "2024-01-01", n: -1, want: "2023-10-01"},
+		{interval: IntervalYear, start: "2024-01-01", n: -1, want: "2023-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.interval+" "+tt.start, func(t *testing.T) {
+			if got := nextBucket(tt.interval, day(tt.start), tt.n).Format("2006-01-02"); got != tt.want {
+				t.Errorf("nextBucket(%d) = %s, want %s", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+// dailySeries has one project worth 10 on every day from..to, and the days
+// of first..last in its buckets
+func dailySeries(interval, from, to, first, last string) *seriesTotals {
+	totals := &seriesTotals{buckets: map[time.Time]*bucketTotals{}, days: map[time.Time]*bucketTotals{}}
+	for d := day(from); !d.After(day(to)); d = d.AddDate(0, 0, 1) {
+		addBucket(totals.days, d, 1, big.NewInt(10))
+		if !d.Before(day(first)) && !d.After(day(last)) {
+			addBucket(totals.buckets, bucketStart(interval, d), 1, big.NewInt(10))
+		}
+	}
+	return totals
+}
+
+func TestBuildSeriesCompare(t *testing.T) {
+	type change struct {
+		period, from, to string
+		projects         int64
+	}
+
+	tests := []struct {
+		name        string
+		interval    string
+		compare     string
+		first, last string
+		want        []change
+	}{
+		{
+			name:     "partial first and last months",
+			interval: IntervalMonth, compare: ComparePrevious,
+			first: "2024-01-10", last: "2024-03-15",
+			want: []change{
+				{period: "2023-12", from: "2023-12-10", to: "2023-12-31", projects: 22},
+				{period: "2024-01", from: "2024-01-01", to: "2024-01-31", projects: 31},
+				{period: "2024-02", from: "2024-02-01", to: "2024-02-15", projects: 15},
+			},
+		},
+		{
+			name:     "partial month longer than the earlier one",
+			interval: IntervalMonth, compare: ComparePrevious,
+			first: "2024-03-01", last: "2024-03-30",
+			want: []change{
+				{period: "2024-02", from: "2024-02-01", to: "2024-02-29", projects: 29},
+			},
+		},
+		{
+			name:     "partial first month compared with a year before",
+			interval: IntervalMonth, compare: CompareYear,
+			first: "2024-02-10", last: "2024-02-29",
+			want: []change{
+				{period: "2023-02", from: "2023-02-10", to: "2023-02-28", projects: 19},
+			},
+		},
+		{
+			name:     "whole weeks",
+			interval: IntervalWeek, compare: ComparePrevious,
+			first: "2024-03-04", last: "2024-03-17",
+			want: []change{
+				{period: "2024-W09", from: "2024-02-26", to: "2024-03-03", projects: 7},
+				{period: "2024-W10", from: "2024-03-04", to: "2024-03-10", projects: 7},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals := dailySeries(tt.interval, "2022-01-01", "2024-12-31", tt.first, tt.last)
+			var starts []time.Time
+			for start := bucketStart(tt.interval, day(tt.first)); !start.After(day(tt.last)); start = nextBucket(tt.interval, start, 1) {
+				starts = append(starts, start)
+			}
+
+			series := buildSeries(totals, starts, day(tt.first), day(tt.last), tt.interval, tt.compare)
+			if len(series.Points) != len(tt.want) {
+				t.Fatalf("got %d points, want %d", len(series.Points), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				got := series.Points[i].Change
+				if got.Period != want.period || got.From != want.from || got.To != want.to || got.Projects != want.projects {
+					t.Errorf("point %s compared with %s %s..%s (%d), want %s %s..%s (%d)", series.Points[i].Period,
+						got.Period, got.From, got.To, got.Projects, want.period, want.from, want.to, want.projects)
+				}
+				if string(got.Investment) != big.NewInt(10*want.projects).String() {
+					t.Errorf("point %s compared with investment %s", series.Points[i].Period, got.Investment)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,821 @@
+package oss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/errors"
+	"kswi-backend/internal/shared/logger"
+	"kswi-backend/internal/worker"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// JobTypeImport is the job type of OSS imports
+const JobTypeImport = "oss.import"
+
+// maxReportedErrorRows caps the rejected rows returned in an upload result
+const maxReportedErrorRows = 1000
+
+// requiredColumns must be present in the header of an uploaded sheet
+var requiredColumns = []string{"idProyek"}
+
+// rawDateColumns maps the parsed date columns to the columns keeping the
+// value as it appeared in the file
+var rawDateColumns = map[string]string{
+	"tglDownload":      "tglDownloadExcel",
+	"tglTerbitOss":     "tglTerbitOssExcel",
+	"tglPengajuan":     "tglPengajuanExcel",
+	"lastUpdateProyek": "lastUpdateProyekRaw",
+}
+
+// headerAliases are labels used by the OSS export that differ from the
+// column names, keyed by their normalized form
+var headerAliases = map[string]string{
+	"namaperusahaan":   "perusahaanNama",
+	"npwpperusahaan":   "perusahaanNPWP",
+	"alamatperusahaan": "perusahaanAlamat",
+	"kabkota":          "perusahaanKota",
+	"kabupatenkota":    "perusahaanKota",
+	"provinsi":         "perusahaanProv",
+	"skalausaha":       "perusahaanSkala",
+	"judulkbli":        "kbliJudul",
+	"risiko":           "resiko",
+	"jumlahinvestasi":  "invJumlah",
+	"totalinvestasi":   "invJumlah",
+}
+
+// dateLayouts are the text date formats accepted besides Excel serial dates
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"02/01/2006",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"2/1/2006",
+	"02-01-2006",
+	"02-01-2006 15:04:05",
+	"02 Jan 2006",
+}
+
+var (
+	dotThousands   = regexp.MustCompile(`^\d{1,3}(\.\d{3})+$`)
+	commaThousands = regexp.MustCompile(`^\d{1,3}(,\d{3})+$`)
+)
+
+// ossColumn is an oss_base column that can be loaded from an uploaded sheet
+type ossColumn struct {
+	name     string
+	json     string
+	field    int
+	kind     reflect.Type
+	size     int
+	rawField int
+	rawSize  int
+}
+
+var ossColumns, ossColumnsByHeader = buildOssColumns()
+
+// buildOssColumns reads the loadable columns from the gorm tags of
+// model.OssBase. Bookkeeping columns (prefixed with "_"), the primary key and
+// the raw date siblings are filled by the importer itself.
+func buildOssColumns() ([]*ossColumn, map[string]*ossColumn) {
+	t := reflect.TypeOf(model.OssBase{})
+
+	type tagInfo struct {
+		field int
+		size  int
+	}
+	tags := make(map[string]tagInfo, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, size := parseGormTag(t.Field(i).Tag.Get("gorm"))
+		tags[name] = tagInfo{field: i, size: size}
+	}
+
+	raw := make(map[string]bool, len(rawDateColumns))
+	for _, rawName := range rawDateColumns {
+		raw[rawName] = true
+	}
+
+	var columns []*ossColumn
+	byHeader := make(map[string]*ossColumn)
+	for i := 0; i < t.NumField(); i++ {
+		name, size := parseGormTag(t.Field(i).Tag.Get("gorm"))
+		if name == "" || name == "id" || strings.HasPrefix(name, "_") || raw[name] {
+			continue
+		}
+
+		col := &ossColumn{
+			name:     name,
+			json:     strings.Split(t.Field(i).Tag.Get("json"), ",")[0],
+			field:    i,
+			kind:     t.Field(i).Type.Elem(),
+			size:     size,
+			rawField: -1,
+		}
+		if rawName, ok := rawDateColumns[name]; ok {
+			col.rawField = tags[rawName].field
+			col.rawSize = tags[rawName].size
+			byHeader[normalizeHeader(rawName)] = col
+		}
+
+		columns = append(columns, col)
+		byHeader[normalizeHeader(name)] = col
+	}
+
+	for alias, name := range headerAliases {
+		if col, ok := byHeader[normalizeHeader(name)]; ok {
+			byHeader[alias] = col
+		}
+	}
+
+	return columns, byHeader
+}
+
+func parseGormTag(tag string) (column string, size int) {
+	for _, part := range strings.Split(tag, ";") {
+		switch {
+		case strings.HasPrefix(part, "column:"):
+			column = strings.TrimPrefix(part, "column:")
+		case strings.HasPrefix(part, "size:"):
+			size, _ = strconv.Atoi(strings.TrimPrefix(part, "size:"))
+		}
+	}
+	return column, size
+}
+
+// normalizeHeader lowercases a header and drops everything but letters and
+// digits, so "Tgl. Terbit OSS" and "tglTerbitOss" match
+func normalizeHeader(header string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(header) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// rowParser turns sheet rows into oss_base records
+type rowParser struct {
+	columns []*ossColumn
+	headers []string
+}
+
+// newRowParser maps the header cells to columns. Unknown headers are
+// returned so the caller can report them.
+func newRowParser(header []string) (*rowParser, []string, error) {
+	p := &rowParser{columns: make([]*ossColumn, len(header)), headers: header}
+
+	var ignored []string
+	seen := make(map[string]string)
+	for i, cell := range header {
+		cell = strings.TrimSpace(cell)
+		if cell == "" {
+			continue
+		}
+
+		col, ok := ossColumnsByHeader[normalizeHeader(cell)]
+		if !ok {
+			ignored = append(ignored, cell)
+			continue
+		}
+		if first, dup := seen[col.name]; dup {
+			return nil, nil, fmt.Errorf("columns '%s' and '%s' both map to %s", first, cell, col.name)
+		}
+
+		seen[col.name] = cell
+		p.columns[i] = col
+	}
+
+	for _, name := range requiredColumns {
+		if _, ok := seen[name]; !ok {
+			return nil, nil, fmt.Errorf("required column '%s' is missing", name)
+		}
+	}
+
+	return p, ignored, nil
+}
+
+// parse converts the cells of one row. A row with any invalid cell yields no
+// record, only errors.
+func (p *rowParser) parse(cells []string, rowNumber int) (*model.OssBase, []model.LogUploadError) {
+	var row model.OssBase
+	var errs []model.LogUploadError
+	failed := make(map[string]bool)
+
+	v := reflect.ValueOf(&row).Elem()
+	for i, cell := range cells {
+		if i >= len(p.columns) || p.columns[i] == nil {
+			continue
+		}
+
+		col := p.columns[i]
+		value := strings.TrimSpace(cell)
+		if value == "" {
+			continue
+		}
+
+		if err := col.set(v, value); err != nil {
+			failed[col.name] = true
+			errs = append(errs, model.LogUploadError{
+				RowNumber: rowNumber,
+				Column:    p.headers[i],
+				Value:     truncate(value, 500),
+				Message:   err.Error(),
+			})
+		}
+	}
+
+	for _, name := range requiredColumns {
+		col := ossColumnsByHeader[normalizeHeader(name)]
+		if v.Field(col.field).IsNil() && !failed[name] {
+			errs = append(errs, model.LogUploadError{
+				RowNumber: rowNumber,
+				Column:    name,
+				Message:   "This field is required",
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	locate(&row)
+	return &row, nil
+}
+
+// set parses value according to the column type and stores it in row
+func (c *ossColumn) set(row reflect.Value, value string) error {
+	field := row.Field(c.field)
+
+	switch c.kind {
+	case reflect.TypeOf(""):
+		if c.size > 0 && utf8.RuneCountInString(value) > c.size {
+			return fmt.Errorf("Must be at most %d characters", c.size)
+		}
+		field.Set(reflect.ValueOf(&value))
+
+	case reflect.TypeOf(0):
+		n, err := parseWholeNumber(value)
+		if err != nil || n > math.MaxInt32 {
+			return fmt.Errorf("Must be a whole number")
+		}
+		i := int(n)
+		field.Set(reflect.ValueOf(&i))
+
+	case reflect.TypeOf(uint64(0)):
+		n, err := parseWholeNumber(value)
+		if err != nil {
+			return fmt.Errorf("Must be a non-negative amount")
+		}
+		field.Set(reflect.ValueOf(&n))
+
+	case reflect.TypeOf(time.Time{}):
+		t, err := parseDate(value)
+		if err != nil {
+			return fmt.Errorf("Must be a date (Excel date, YYYY-MM-DD or DD/MM/YYYY)")
+		}
+		field.Set(reflect.ValueOf(&t))
+
+		if c.rawField >= 0 {
+			raw := value
+			if c.rawSize > 0 && utf8.RuneCountInString(raw) > c.rawSize {
+				raw = t.Format("2006-01-02")
+			}
+			row.Field(c.rawField).Set(reflect.ValueOf(&raw))
+		}
+
+	default:
+		return fmt.Errorf("Unsupported column type %s", c.kind)
+	}
+
+	return nil
+}
+
+// parseWholeNumber accepts plain integers, Excel floats such as "1.5E+9" and
+// numbers with thousands separators ("1.500.000" or "1,500,000")
+func parseWholeNumber(value string) (uint64, error) {
+	value = strings.ReplaceAll(value, " ", "")
+
+	switch {
+	case dotThousands.MatchString(value):
+		value = strings.ReplaceAll(value, ".", "")
+	case commaThousands.MatchString(value):
+		value = strings.ReplaceAll(value, ",", "")
+	}
+
+	if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return n, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if f < 0 || f > math.MaxUint64 || f != math.Trunc(f) {
+		return 0, fmt.Errorf("not a whole non-negative number: %s", value)
+	}
+	return uint64(f), nil
+}
+
+// parseDate accepts Excel serial dates and the text layouts in dateLayouts.
+// Dates without a zone are read as UTC.
+func parseDate(value string) (time.Time, error) {
+	if serial, err := strconv.ParseFloat(value, 64); err == nil {
+		// 1 is 1900-01-01 and 2958465 is 9999-12-31
+		if serial < 1 || serial > 2958465 {
+			return time.Time{}, fmt.Errorf("serial date out of range: %s", value)
+		}
+		return excelize.ExcelDateToTime(serial, false)
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date: %s", value)
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+// isEmptyRow reports whether every cell of a row is blank
+func isEmptyRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// StartUpload records an upload of the OSS export stored at input.Path and
+// queues a job importing it. The job owns the file and removes it when done.
+func (s *service) StartUpload(ctx context.Context, input UploadInput) (*UploadStarted, error) {
+	upload := &model.LogUpload{
+		FileName:   input.FileName,
+		FileSize:   input.FileSize,
+		SheetName:  input.Sheet,
+		UploadedBy: input.UserID,
+		MatchKey:   model.MatchIDProyek,
+		Status:     model.LogUploadQueued,
+	}
+	if input.MatchNIB {
+		upload.MatchKey = model.MatchIDProyekNIB
+	}
+	if err := s.repo.CreateLogUpload(ctx, upload); err != nil {
+		_ = os.Remove(input.Path)
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to create upload log: %w", err))
+	}
+
+	// The job works on its own copy; the request keeps upload for the response
+	jobUpload := *upload
+	job, err := s.jobs.Enqueue(ctx, worker.Spec{
+		Type:      JobTypeImport,
+		CreatedBy: input.UserID,
+		Task: func(ctx context.Context, progress *worker.Progress) (interface{}, error) {
+			jobID := progress.JobID()
+			jobUpload.JobID = &jobID
+
+			var result *UploadResult
+			err := s.repo.WithWriteLock(ctx, func(ctx context.Context) error {
+				var err error
+				result, err = s.importUpload(ctx, progress, &jobUpload, input)
+				return err
+			})
+			return result, err
+		},
+		OnFinish: func(ctx context.Context, job *model.Job) {
+			s.finishUpload(ctx, &jobUpload, input, job)
+		},
+	})
+	if err != nil {
+		_ = os.Remove(input.Path)
+		s.markUpload(ctx, upload, model.LogUploadFailed, err.Error())
+
+		if err == worker.ErrQueueFull || err == worker.ErrShuttingDown {
+			return nil, errors.NewConflictError("The import queue is full, please try again later")
+		}
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to queue import: %w", err))
+	}
+
+	upload.JobID = &job.ID
+	if err := s.repo.SetLogUploadJob(ctx, upload.ID, job.ID); err != nil {
+		logger.FromContext(ctx).WithModule("oss").WithError(err).
+			WithFields("log_upload_id", upload.ID, "job_id", job.ID).
+			Error("Failed to link upload to its job")
+	}
+
+	return &UploadStarted{Upload: upload, Job: job}, nil
+}
+
+// importUpload streams the rows of an uploaded file into oss_base in batches.
+// Every row is tagged with the upload. Invalid rows are skipped and reported.
+func (s *service) importUpload(ctx context.Context, progress *worker.Progress, upload *model.LogUpload, input UploadInput) (*UploadResult, error) {
+	f, err := excelize.OpenFile(input.Path)
+	if err != nil {
+		return nil, errors.NewValidationErrorWithOriginal([]errors.ValidationError{{
+			Field:   "file",
+			Message: "Must be a valid .xlsx file",
+		}}, err)
+	}
+	defer f.Close()
+
+	sheet := input.Sheet
+	if sheet == "" {
+		sheet = f.GetSheetName(0)
+	} else if index, err := f.GetSheetIndex(sheet); err != nil || index < 0 {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "sheet",
+			Message: fmt.Sprintf("Sheet '%s' does not exist", sheet),
+		}})
+	}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, errors.NewValidationErrorWithOriginal([]errors.ValidationError{{
+			Field:   "file",
+			Message: "Unable to read the sheet",
+		}}, err)
+	}
+	defer rows.Close()
+
+	// The first non-empty row is the header
+	var parser *rowParser
+	var ignored []string
+	rowNumber := 0
+	for parser == nil && rows.Next() {
+		rowNumber++
+		cells, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %w", rowNumber, err)
+		}
+		if isEmptyRow(cells) {
+			continue
+		}
+
+		if parser, ignored, err = newRowParser(cells); err != nil {
+			return nil, errors.NewValidationErrorWithOriginal([]errors.ValidationError{{
+				Field:   "file",
+				Message: fmt.Sprintf("Invalid header row: %s", err.Error()),
+			}}, err)
+		}
+	}
+	if parser == nil {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "file",
+			Message: "The sheet is empty",
+		}})
+	}
+
+	// The sheet dimension gives the row count without reading the sheet twice
+	if lastRow := sheetLastRow(f, sheet); lastRow > rowNumber {
+		progress.SetTotal(lastRow - rowNumber)
+	}
+
+	started := time.Now()
+	upload.SheetName = sheet
+	upload.Status = model.LogUploadProcessing
+	upload.StartedAt = &started
+	if err := s.repo.UpdateLogUpload(ctx, upload); err != nil {
+		return nil, fmt.Errorf("failed to update upload log: %w", err)
+	}
+
+	report := &errorReport{limit: maxReportedErrorRows}
+	batch := make([]model.OssBase, 0, uploadRowBatchSize)
+	var batchErrors []model.LogUploadError
+	batchFailed := 0
+	now := time.Now()
+
+	flush := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		upsert, err := s.prepareBatch(ctx, upload.ID, input.UserID, batch, upload.MatchKey == model.MatchIDProyekNIB)
+		if err != nil {
+			return fmt.Errorf("failed to match rows up to row %d: %w", rowNumber, err)
+		}
+		upsert.Errors = batchErrors
+
+		if err := s.repo.SaveUploadBatch(ctx, upsert); err != nil {
+			return fmt.Errorf("failed to save rows up to row %d: %w", rowNumber, err)
+		}
+
+		upload.SuccessRows += len(batch)
+		upload.InsertedRows += upsert.Inserted
+		upload.UpdatedRows += upsert.Updated
+		upload.UnchangedRows += upsert.Unchanged
+		progress.Add(len(batch)+batchFailed, batchFailed)
+
+		batch = batch[:0]
+		batchErrors = nil
+		batchFailed = 0
+		return nil
+	}
+
+	for rows.Next() {
+		rowNumber++
+		cells, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %w", rowNumber, err)
+		}
+		if isEmptyRow(cells) {
+			continue
+		}
+
+		upload.TotalRows++
+		row, errs := parser.parse(cells, rowNumber)
+		if len(errs) > 0 {
+			for i := range errs {
+				errs[i].LogUploadID = upload.ID
+			}
+			upload.FailedRows++
+			batchFailed++
+			batchErrors = append(batchErrors, errs...)
+			report.add(rowNumber, errs)
+		} else {
+			row.LogUploadID = &upload.ID
+			row.CreatedBy = &input.UserID
+			row.CreatedAt = &now
+			batch = append(batch, *row)
+		}
+
+		if len(batch)+batchFailed >= uploadRowBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := rows.Error(); err != nil {
+		return nil, fmt.Errorf("failed to read the sheet: %w", err)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	finished := time.Now()
+	upload.Status = model.LogUploadCompleted
+	upload.FinishedAt = &finished
+	if err := s.repo.UpdateLogUpload(ctx, upload); err != nil {
+		return nil, fmt.Errorf("failed to update upload log: %w", err)
+	}
+
+	return &UploadResult{
+		Upload:          upload,
+		IgnoredColumns:  ignored,
+		Errors:          report.rows,
+		ErrorsTruncated: report.truncated,
+	}, nil
+}
+
+// UpsertBatch is one batch of an import, split by what happens to each row
+type UpsertBatch struct {
+	Inserts   []model.OssBase
+	Updates   []model.OssBase
+	Snapshots []model.OssBaseSnapshot
+	History   []model.OssBaseHistory
+	Errors    []model.LogUploadError
+
+	Inserted  int
+	Updated   int
+	Unchanged int
+}
+
+// prepareBatch matches parsed rows to existing projects on idProyek (and nib
+// when matchNIB is set). A matched project is only updated when the incoming
+// lastUpdateProyek is newer, and only with the cells the sheet fills in; its
+// current version is snapshotted first so the upload can be rolled back, and
+// the changed fields go to the history. Within the batch, repeated projects
+// keep only their newest row and the others count as unchanged.
+//
+// oss_base has no unique key on idProyek: the shared table may already hold
+// duplicates, which match their newest row. Matching therefore relies on this
+// lookup alone, and imports hold the oss_base write lock so that no other
+// import can insert a project between the match and the write.
+func (s *service) prepareBatch(ctx context.Context, uploadID, userID int, rows []model.OssBase, matchNIB bool) (*UpsertBatch, error) {
+	batch := &UpsertBatch{}
+	now := time.Now()
+
+	chosen := make(map[string]int, len(rows))
+	var order []string
+	var ids []string
+	for i := range rows {
+		key := projectKey(&rows[i], matchNIB)
+		j, seen := chosen[key]
+		if !seen {
+			chosen[key] = i
+			order = append(order, key)
+			ids = append(ids, *rows[i].IdProyek)
+			continue
+		}
+
+		batch.Unchanged++
+		if !isNewer(rows[j].LastUpdateProyek, rows[i].LastUpdateProyek) {
+			chosen[key] = i
+		}
+	}
+
+	existing, err := s.repo.FindProjects(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows come ordered by id, so a duplicated project resolves to its newest row
+	current := make(map[string]*model.OssBase, len(existing))
+	for i := range existing {
+		current[projectKey(&existing[i], matchNIB)] = &existing[i]
+	}
+
+	for _, key := range order {
+		row := rows[chosen[key]]
+		old, ok := current[key]
+
+		switch {
+		case !ok:
+			batch.Inserts = append(batch.Inserts, row)
+			batch.Inserted++
+
+		case isNewer(row.LastUpdateProyek, old.LastUpdateProyek):
+			// A row this upload already wrote has its pre-upload version saved
+			if old.LogUploadID == nil || *old.LogUploadID != uploadID {
+				data, err := json.Marshal(old)
+				if err != nil {
+					return nil, err
+				}
+				batch.Snapshots = append(batch.Snapshots, model.OssBaseSnapshot{
+					OssBaseID:   old.ID,
+					LogUploadID: uploadID,
+					Data:        data,
+				})
+			}
+
+			merged := mergeRow(old, &row)
+			merged.LogUploadID = row.LogUploadID
+			merged.UpdatedAt = &now
+			merged.UpdatedBy = &userID
+			batch.Updates = append(batch.Updates, merged)
+			batch.History = append(batch.History, newHistory(old.ID, diffRows(old, &merged),
+				model.OssChangeImport, &uploadID, &userID, now)...)
+			batch.Updated++
+
+		default:
+			batch.Unchanged++
+		}
+	}
+
+	return batch, nil
+}
+
+// mergeRow applies the cells of an imported row to a copy of the existing
+// row. Columns the sheet lacks or leaves blank are parsed as nil and keep
+// their current value, as do the bookkeeping columns.
+func mergeRow(old, incoming *model.OssBase) model.OssBase {
+	merged := *old
+	m := reflect.ValueOf(&merged).Elem()
+	in := reflect.ValueOf(incoming).Elem()
+	for _, col := range ossColumns {
+		value := in.Field(col.field)
+		if value.IsNil() {
+			continue
+		}
+		m.Field(col.field).Set(value)
+		if col.rawField >= 0 {
+			m.Field(col.rawField).Set(in.Field(col.rawField))
+		}
+	}
+
+	locate(&merged)
+	return merged
+}
+
+// projectKey identifies a project for matching
+func projectKey(row *model.OssBase, matchNIB bool) string {
+	key := *row.IdProyek
+	if matchNIB {
+		key += "\x00"
+		if row.NIB != nil {
+			key += *row.NIB
+		}
+	}
+	return key
+}
+
+// isNewer reports whether incoming is a later lastUpdateProyek than current.
+// An unknown incoming date is never newer.
+func isNewer(incoming, current *time.Time) bool {
+	return incoming != nil && (current == nil || incoming.After(*current))
+}
+
+// finishUpload removes the uploaded file once its job ended. An import that
+// did not complete is undone so that no partial upload stays in oss_base.
+func (s *service) finishUpload(ctx context.Context, upload *model.LogUpload, input UploadInput, job *model.Job) {
+	if err := os.Remove(input.Path); err != nil && !os.IsNotExist(err) {
+		logger.FromContext(ctx).WithModule("oss").WithError(err).
+			WithFields("path", input.Path).
+			Warn("Failed to remove uploaded file")
+	}
+
+	if job.Status == model.JobCompleted {
+		return
+	}
+	s.failUpload(ctx, upload, job)
+}
+
+// failUpload removes the rows of an upload whose job did not complete and
+// stores the job's outcome on it
+func (s *service) failUpload(ctx context.Context, upload *model.LogUpload, job *model.Job) {
+	if err := s.repo.DiscardUpload(ctx, upload.ID); err != nil {
+		logger.FromContext(ctx).WithModule("oss").WithError(err).
+			WithFields("log_upload_id", upload.ID).
+			Error("Failed to undo the rows of an unfinished upload")
+	}
+
+	status, message := model.LogUploadFailed, ""
+	if job.Status == model.JobCancelled {
+		status, message = model.LogUploadCancelled, "Cancelled"
+	}
+	if job.Error != nil {
+		message = *job.Error
+	}
+
+	upload.SuccessRows = 0
+	upload.InsertedRows = 0
+	upload.UpdatedRows = 0
+	upload.UnchangedRows = 0
+	s.markUpload(ctx, upload, status, message)
+}
+
+// markUpload stores a final status on an upload
+func (s *service) markUpload(ctx context.Context, upload *model.LogUpload, status, message string) {
+	finished := time.Now()
+	upload.Status = status
+	upload.FinishedAt = &finished
+	if message != "" {
+		upload.ErrorMessage = &message
+	}
+
+	if err := s.repo.UpdateLogUpload(ctx, upload); err != nil {
+		logger.FromContext(ctx).WithModule("oss").WithError(err).
+			WithFields("log_upload_id", upload.ID).
+			Error("Failed to update upload status")
+	}
+}
+
+// sheetLastRow returns the last row of the sheet's used range, or 0 when the
+// file does not record it
+func sheetLastRow(f *excelize.File, sheet string) int {
+	dimension, err := f.GetSheetDimension(sheet)
+	if err != nil {
+		return 0
+	}
+
+	parts := strings.Split(dimension, ":")
+	_, row, err := excelize.CellNameToCoordinates(parts[len(parts)-1])
+	if err != nil {
+		return 0
+	}
+	return row
+}
+
+// errorReport collects the rejected rows returned to the client, up to limit rows
+type errorReport struct {
+	limit     int
+	rows      []UploadRowError
+	truncated bool
+}
+
+func (r *errorReport) add(rowNumber int, errs []model.LogUploadError) {
+	if len(r.rows) >= r.limit {
+		r.truncated = true
+		return
+	}
+
+	row := UploadRowError{Row: rowNumber}
+	for _, e := range errs {
+		row.Errors = append(row.Errors, UploadCellError{
+			Column:  e.Column,
+			Value:   e.Value,
+			Message: e.Message,
+		})
+	}
+	r.rows = append(r.rows, row)
+}
@@ -0,0 +1,150 @@
+package oss
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWholeNumber(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint64
+		wantErr bool
+	}{
+		{value: "1500000", want: 1500000},
+		{value: "1.500.000", want: 1500000},
+		{value: "1,500,000", want: 1500000},
+		{value: "1 500 000", want: 1500000},
+		{value: "1.5E+9", want: 1500000000},
+		{value: "12.000", want: 12000},
+		{value: "0", want: 0},
+		{value: "1.5", wantErr: true},
+		{value: "-3", wantErr: true},
+		{value: "1,50", wantErr: true},
+		{value: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseWholeNumber(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseWholeNumber(%q) = %d, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseWholeNumber(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "45292", want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "45292.5", want: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{value: "2024-03-15", want: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-03-15 08:30:00", want: time.Date(2024, 3, 15, 8, 30, 0, 0, time.UTC)},
+		{value: "15/03/2024", want: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "15/03/2024 08:30", want: time.Date(2024, 3, 15, 8, 30, 0, 0, time.UTC)},
+		{value: "0", wantErr: true},
+		{value: "3000000", wantErr: true},
+		{value: "03/15/2024", wantErr: true},
+		{value: "kemarin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDate(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseDate(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("parseDate(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRowParser(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      []string
+		wantIgnored []string
+		wantErr     bool
+	}{
+		{name: "known headers", header: []string{"idProyek", "Nama Proyek", "", "tenaga_kerja"}},
+		{name: "unknown headers are ignored", header: []string{"ID Proyek", "Catatan"}, wantIgnored: []string{"Catatan"}},
+		{name: "required column missing", header: []string{"namaProyek"}, wantErr: true},
+		{name: "two headers of one column", header: []string{"idProyek", "namaProyek", "Nama Proyek"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ignored, err := newRowParser(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ignored, tt.wantIgnored) {
+				t.Errorf("ignored = %v, want %v", ignored, tt.wantIgnored)
+			}
+		})
+	}
+}
+
+func TestRowParserParse(t *testing.T) {
+	p, _, err := newRowParser([]string{"idProyek", "namaProyek", "tenagaKerja", "invJumlah", "tglTerbitOss"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		cells      []string
+		wantErrors []string
+	}{
+		{name: "valid row", cells: []string{"P1", " Pabrik ", "12", "1.500.000", "15/03/2024"}},
+		{name: "short row", cells: []string{"P1"}},
+		{name: "blank required cell", cells: []string{" ", "Pabrik"}, wantErrors: []string{"idProyek"}},
+		{name: "invalid cells", cells: []string{"P1", "Pabrik", "dua", "-5", "besok"}, wantErrors: []string{"tenagaKerja", "invJumlah", "tglTerbitOss"}},
+		{name: "invalid required cell", cells: []string{strings.Repeat("x", 151)}, wantErrors: []string{"idProyek"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, errs := p.parse(tt.cells, 7)
+
+			var columns []string
+			for _, e := range errs {
+				if e.RowNumber != 7 {
+					t.Errorf("error of column %s has row %d, want 7", e.Column, e.RowNumber)
+				}
+				columns = append(columns, e.Column)
+			}
+			if !reflect.DeepEqual(columns, tt.wantErrors) {
+				t.Errorf("errors in %v, want %v", columns, tt.wantErrors)
+			}
+			if (row == nil) != (len(tt.wantErrors) > 0) {
+				t.Errorf("row = %+v, want a row only without errors", row)
+			}
+		})
+	}
+
+	row, _ := p.parse([]string{"P1", " Pabrik ", "12", "1.500.000", "15/03/2024"}, 2)
+	if *row.IdProyek != "P1" || *row.NamaProyek != "Pabrik" || *row.TenagaKerja != 12 || *row.InvJumlah != 1500000 {
+		t.Errorf("parsed row = %v, %v, %v, %v", *row.IdProyek, *row.NamaProyek, *row.TenagaKerja, *row.InvJumlah)
+	}
+	if !row.TglTerbitOss.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) || *row.TglTerbitOssExcel != "15/03/2024" {
+		t.Errorf("parsed date = %v (%s)", row.TglTerbitOss, *row.TglTerbitOssExcel)
+	}
+}
@@ -0,0 +1,150 @@
+package oss
+
+import (
+	"context"
+	"kswi-backend/internal/model"
+	"testing"
+	"time"
+)
+
+// projectRepo serves FindProjects from memory; other methods are not used
+type projectRepo struct {
+	Repository
+	projects []model.OssBase
+}
+
+func (r *projectRepo) FindProjects(ctx context.Context, idProyek []string) ([]model.OssBase, error) {
+	return r.projects, nil
+}
+
+func strPtr(s string) *string { return &s }
+
+func intPtr(i int) *int { return &i }
+
+func datePtr(s string) *time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return &t
+}
+
+func TestMergeRow(t *testing.T) {
+	old := &model.OssBase{
+		ID:               7,
+		IdProyek:         strPtr("P1"),
+		NamaProyek:       strPtr("Pabrik"),
+		PerusahaanNama:   strPtr("PT Lama"),
+		PerusahaanLat:    strPtr("-6.2"),
+		PerusahaanLon:    strPtr("106.8"),
+		TenagaKerja:      intPtr(10),
+		LastUpdateProyek: datePtr("2024-01-01"),
+		CreatedBy:        intPtr(1),
+		InputManual:      intPtr(1),
+	}
+	locate(old)
+
+	incoming := &model.OssBase{
+		IdProyek:            strPtr("P1"),
+		PerusahaanNama:      strPtr("PT Baru"),
+		LastUpdateProyek:    datePtr("2024-02-01"),
+		LastUpdateProyekRaw: strPtr("01/02/2024"),
+		CreatedBy:           intPtr(2),
+	}
+
+	merged := mergeRow(old, incoming)
+
+	if merged.ID != 7 || *merged.CreatedBy != 1 || *merged.InputManual != 1 {
+		t.Errorf("bookkeeping columns changed: id %d, created by %d, input manual %d", merged.ID, *merged.CreatedBy, *merged.InputManual)
+	}
+	if *merged.PerusahaanNama != "PT Baru" {
+		t.Errorf("perusahaanNama = %q, want the imported value", *merged.PerusahaanNama)
+	}
+	if !merged.LastUpdateProyek.Equal(*incoming.LastUpdateProyek) || merged.LastUpdateProyekRaw == nil || *merged.LastUpdateProyekRaw != "01/02/2024" {
+		t.Errorf("lastUpdateProyek = %v (%v), want the imported date and raw value", merged.LastUpdateProyek, merged.LastUpdateProyekRaw)
+	}
+	if merged.NamaProyek == nil || *merged.NamaProyek != "Pabrik" || merged.TenagaKerja == nil || *merged.TenagaKerja != 10 {
+		t.Errorf("columns missing from the sheet were not kept: %v, %v", merged.NamaProyek, merged.TenagaKerja)
+	}
+	if merged.GeoStatus == nil || *merged.GeoStatus != model.OssGeoValid || merged.GeoLat == nil {
+		t.Errorf("coordinates kept from the old row were not located: %v", merged.GeoStatus)
+	}
+	if *old.PerusahaanNama != "PT Lama" {
+		t.Error("the old row was modified")
+	}
+	if changes := diffRows(old, &merged); len(changes) != 2 {
+		t.Errorf("diff has %d changes, want perusahaanNama and lastUpdateProyek: %+v", len(changes), changes)
+	}
+}
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming *time.Time
+		current  *time.Time
+		want     bool
+	}{
+		{name: "later", incoming: datePtr("2024-02-01"), current: datePtr("2024-01-01"), want: true},
+		{name: "same", incoming: datePtr("2024-01-01"), current: datePtr("2024-01-01"), want: false},
+		{name: "earlier", incoming: datePtr("2023-12-31"), current: datePtr("2024-01-01"), want: false},
+		{name: "current unknown", incoming: datePtr("2024-01-01"), want: true},
+		{name: "incoming unknown", current: datePtr("2024-01-01"), want: false},
+		{name: "both unknown", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNewer(tt.incoming, tt.current); got != tt.want {
+				t.Errorf("isNewer = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareBatch(t *testing.T) {
+	uploadID, userID := 5, 3
+	existing := []model.OssBase{
+		{ID: 1, IdProyek: strPtr("OLD"), NamaProyek: strPtr("Lama"), LastUpdateProyek: datePtr("2024-01-01")},
+		{ID: 2, IdProyek: strPtr("SAME"), NamaProyek: strPtr("Tetap"), LastUpdateProyek: datePtr("2024-01-01")},
+		{ID: 3, IdProyek: strPtr("UNDATED"), NamaProyek: strPtr("Tetap"), LastUpdateProyek: datePtr("2024-01-01")},
+	}
+	rows := []model.OssBase{
+		{IdProyek: strPtr("OLD"), PerusahaanNama: strPtr("PT A"), LastUpdateProyek: datePtr("2024-03-01"), LogUploadID: &uploadID},
+		{IdProyek: strPtr("SAME"), NamaProyek: strPtr("Baru"), LastUpdateProyek: datePtr("2024-01-01"), LogUploadID: &uploadID},
+		{IdProyek: strPtr("UNDATED"), NamaProyek: strPtr("Baru"), LogUploadID: &uploadID},
+		{IdProyek: strPtr("NEW"), LastUpdateProyek: datePtr("2024-01-01"), LogUploadID: &uploadID},
+		{IdProyek: strPtr("NEW"), LastUpdateProyek: datePtr("2024-02-01"), LogUploadID: &uploadID},
+		{IdProyek: strPtr("NEW"), LastUpdateProyek: datePtr("2023-01-01"), LogUploadID: &uploadID},
+	}
+
+	s := &service{repo: &projectRepo{projects: existing}}
+	batch, err := s.prepareBatch(context.Background(), uploadID, userID, rows, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if batch.Inserted != 1 || batch.Updated != 1 || batch.Unchanged != 4 {
+		t.Errorf("inserted %d, updated %d, unchanged %d; want 1, 1, 4", batch.Inserted, batch.Updated, batch.Unchanged)
+	}
+
+	if len(batch.Inserts) != 1 || !batch.Inserts[0].LastUpdateProyek.Equal(*datePtr("2024-02-01")) {
+		t.Errorf("inserts = %+v, want the newest NEW row", batch.Inserts)
+	}
+
+	if len(batch.Updates) != 1 {
+		t.Fatalf("updates = %+v, want OLD only", batch.Updates)
+	}
+	updated := batch.Updates[0]
+	if updated.ID != 1 || *updated.PerusahaanNama != "PT A" || updated.NamaProyek == nil || *updated.NamaProyek != "Lama" {
+		t.Errorf("update of OLD = id %d, %v, %v; want the old row with the imported company", updated.ID, updated.PerusahaanNama, updated.NamaProyek)
+	}
+	if *updated.LogUploadID != uploadID || *updated.UpdatedBy != userID {
+		t.Errorf("update of OLD is not stamped with the upload and user")
+	}
+
+	if len(batch.Snapshots) != 1 || batch.Snapshots[0].OssBaseID != 1 {
+		t.Errorf("snapshots = %+v, want OLD only", batch.Snapshots)
+	}
+	for _, entry := range batch.History {
+		if entry.OssBaseID != 1 || entry.Field == "namaProyek" {
+			t.Errorf("unexpected history entry %s of row %d", entry.Field, entry.OssBaseID)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package rbac
+
+type RoleResponse struct {
+	ID          uint     `json:"id"`
+	Code        string   `json:"code"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type AssignRolesRequest struct {
+	RoleIDs []uint `json:"role_ids" binding:"required"`
+}
+
+type UserRolesResponse struct {
+	UserID      int            `json:"user_id"`
+	Roles       []RoleResponse `json:"roles"`
+	Permissions []string       `json:"permissions"`
+}
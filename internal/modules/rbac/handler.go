@@ -0,0 +1,148 @@
+package rbac
+
+import (
+	"kswi-backend/internal/middleware"
+	"kswi-backend/internal/shared/api"
+	"kswi-backend/internal/shared/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Description Retrieves every role with the permissions it grants
+// @Tags rbac
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} api.APIResponse{data=[]RoleResponse}
+// @Failure 403 {object} api.APIResponse
+// @Router /api/rbac/roles [get]
+func (h *Handler) ListRoles(c *gin.Context) {
+	roles, err := h.service.ListRoles(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Roles retrieved successfully",
+		Data:    roles,
+	})
+}
+
+// ListPermissions godoc
+// @Summary List permissions
+// @Tags rbac
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} api.APIResponse{data=[]model.Permission}
+// @Failure 403 {object} api.APIResponse
+// @Router /api/rbac/permissions [get]
+func (h *Handler) ListPermissions(c *gin.Context) {
+	permissions, err := h.service.ListPermissions(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "Permissions retrieved successfully",
+		Data:    permissions,
+	})
+}
+
+// GetUserRoles godoc
+// @Summary Get the roles of a user
+// @Tags rbac
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} api.APIResponse{data=UserRolesResponse}
+// @Failure 404 {object} api.APIResponse
+// @Router /api/rbac/users/{id}/roles [get]
+func (h *Handler) GetUserRoles(c *gin.Context) {
+	userID, err := parseUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	roles, err := h.service.GetUserRoles(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "User roles retrieved successfully",
+		Data:    roles,
+	})
+}
+
+// AssignUserRoles godoc
+// @Summary Replace the roles of a user
+// @Tags rbac
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body AssignRolesRequest true "Role IDs"
+// @Success 200 {object} api.APIResponse{data=UserRolesResponse}
+// @Failure 400 {object} api.APIResponse
+// @Failure 404 {object} api.APIResponse
+// @Router /api/rbac/users/{id}/roles [put]
+func (h *Handler) AssignUserRoles(c *gin.Context) {
+	userID, err := parseUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req AssignRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.HandleValidationError(err))
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.NewAuthError("Authentication required"))
+		return
+	}
+
+	roles, err := h.service.AssignUserRoles(c.Request.Context(), userID, &req, claims.UserID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.APIResponse{
+		Success: true,
+		Message: "User roles updated successfully",
+		Data:    roles,
+	})
+}
+
+func parseUserID(c *gin.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "id",
+			Message: "Must be a positive integer",
+		}})
+	}
+	return id, nil
+}
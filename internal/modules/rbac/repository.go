@@ -0,0 +1,136 @@
+package rbac
+
+import (
+	"context"
+	"kswi-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	ListRoles(ctx context.Context) ([]model.Role, error)
+	ListPermissions(ctx context.Context) ([]model.Permission, error)
+	FindRolesByIDs(ctx context.Context, ids []uint) ([]model.Role, error)
+	FindRolesByUserID(ctx context.Context, userID int) ([]model.Role, error)
+	FindPermissionCodesByRoleIDs(ctx context.Context, roleIDs []uint) (map[uint][]string, error)
+	FindPermissionCodesByUserID(ctx context.Context, userID int) ([]string, error)
+	ReplaceUserRoles(ctx context.Context, userID int, roleIDs []uint, assignedBy *int) error
+	UserExists(ctx context.Context, userID int) (bool, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) ListRoles(ctx context.Context) ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.WithContext(ctx).Order("code ASC").Find(&roles).Error
+	return roles, err
+}
+
+func (r *repository) ListPermissions(ctx context.Context) ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := r.db.WithContext(ctx).Order("code ASC").Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *repository) FindRolesByIDs(ctx context.Context, ids []uint) ([]model.Role, error) {
+	var roles []model.Role
+	if len(ids) == 0 {
+		return roles, nil
+	}
+
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("code ASC").Find(&roles).Error
+	return roles, err
+}
+
+func (r *repository) FindRolesByUserID(ctx context.Context, userID int) ([]model.Role, error) {
+	var roles []model.Role
+
+	err := r.db.WithContext(ctx).
+		Table("roles").
+		Select("roles.*").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.code ASC").
+		Find(&roles).Error
+
+	return roles, err
+}
+
+func (r *repository) FindPermissionCodesByRoleIDs(ctx context.Context, roleIDs []uint) (map[uint][]string, error) {
+	result := make(map[uint][]string, len(roleIDs))
+	if len(roleIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		RoleID uint
+		Code   string
+	}
+
+	err := r.db.WithContext(ctx).
+		Table("role_permissions").
+		Select("role_permissions.role_id, permissions.code").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("role_permissions.role_id IN ?", roleIDs).
+		Order("permissions.code ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.RoleID] = append(result[row.RoleID], row.Code)
+	}
+
+	return result, nil
+}
+
+func (r *repository) FindPermissionCodesByUserID(ctx context.Context, userID int) ([]string, error) {
+	var codes []string
+
+	err := r.db.WithContext(ctx).
+		Table("permissions").
+		Distinct("permissions.code").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("permissions.code ASC").
+		Pluck("permissions.code", &codes).Error
+
+	return codes, err
+}
+
+func (r *repository) ReplaceUserRoles(ctx context.Context, userID int, roleIDs []uint, assignedBy *int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserRole{}).Error; err != nil {
+			return err
+		}
+
+		if len(roleIDs) == 0 {
+			return nil
+		}
+
+		userRoles := make([]model.UserRole, 0, len(roleIDs))
+		for _, roleID := range roleIDs {
+			userRoles = append(userRoles, model.UserRole{
+				UserID:    userID,
+				RoleID:    roleID,
+				CreatedBy: assignedBy,
+			})
+		}
+
+		return tx.Create(&userRoles).Error
+	})
+}
+
+func (r *repository) UserExists(ctx context.Context, userID int) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Count(&count).Error
+	return count > 0, err
+}
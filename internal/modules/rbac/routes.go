@@ -0,0 +1,22 @@
+package rbac
+
+import (
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	repo := NewRepository(config.GetDB())
+	svc := NewService(repo)
+	handler := NewHandler(svc)
+
+	rbacRoutes := r.Group("/rbac", middleware.RequirePermission("rbac.manage"))
+	{
+		rbacRoutes.GET("/roles", handler.ListRoles)
+		rbacRoutes.GET("/permissions", handler.ListPermissions)
+		rbacRoutes.GET("/users/:id/roles", handler.GetUserRoles)
+		rbacRoutes.PUT("/users/:id/roles", handler.AssignUserRoles)
+	}
+}
@@ -0,0 +1,174 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/errors"
+	"sort"
+)
+
+type Service interface {
+	ListRoles(ctx context.Context) ([]RoleResponse, error)
+	ListPermissions(ctx context.Context) ([]model.Permission, error)
+	GetUserRoles(ctx context.Context, userID int) (*UserRolesResponse, error)
+	AssignUserRoles(ctx context.Context, userID int, req *AssignRolesRequest, assignedBy uint) (*UserRolesResponse, error)
+	GetUserPermissions(ctx context.Context, userID uint) ([]string, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+// ListRoles returns every role with its permission codes
+func (s *service) ListRoles(ctx context.Context) ([]RoleResponse, error) {
+	roles, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to list roles: %w", err))
+	}
+
+	return s.buildRoleResponses(ctx, roles)
+}
+
+func (s *service) ListPermissions(ctx context.Context) ([]model.Permission, error) {
+	permissions, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to list permissions: %w", err))
+	}
+
+	return permissions, nil
+}
+
+// GetUserRoles returns the roles of a user and the permissions they grant
+func (s *service) GetUserRoles(ctx context.Context, userID int) (*UserRolesResponse, error) {
+	exists, err := s.repo.UserExists(ctx, userID)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find user: %w", err))
+	}
+	if !exists {
+		return nil, errors.NewNotFoundError("User")
+	}
+
+	roles, err := s.repo.FindRolesByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to get user roles: %w", err))
+	}
+
+	roleResponses, err := s.buildRoleResponses(ctx, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserRolesResponse{
+		UserID:      userID,
+		Roles:       roleResponses,
+		Permissions: mergePermissions(roleResponses),
+	}, nil
+}
+
+// AssignUserRoles replaces the roles of a user
+func (s *service) AssignUserRoles(ctx context.Context, userID int, req *AssignRolesRequest, assignedBy uint) (*UserRolesResponse, error) {
+	exists, err := s.repo.UserExists(ctx, userID)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find user: %w", err))
+	}
+	if !exists {
+		return nil, errors.NewNotFoundError("User")
+	}
+
+	roleIDs := uniqueIDs(req.RoleIDs)
+
+	roles, err := s.repo.FindRolesByIDs(ctx, roleIDs)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to find roles: %w", err))
+	}
+	if len(roles) != len(roleIDs) {
+		return nil, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "role_ids",
+			Message: "One or more roles do not exist",
+		}})
+	}
+
+	actor := int(assignedBy)
+	if err := s.repo.ReplaceUserRoles(ctx, userID, roleIDs, &actor); err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to assign roles: %w", err))
+	}
+
+	return s.GetUserRoles(ctx, userID)
+}
+
+// GetUserPermissions returns the permission codes granted to a user through
+// their roles. It implements middleware.PermissionChecker.
+func (s *service) GetUserPermissions(ctx context.Context, userID uint) ([]string, error) {
+	codes, err := s.repo.FindPermissionCodesByUserID(ctx, int(userID))
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to get user permissions: %w", err))
+	}
+
+	return codes, nil
+}
+
+func (s *service) buildRoleResponses(ctx context.Context, roles []model.Role) ([]RoleResponse, error) {
+	roleIDs := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	permissions, err := s.repo.FindPermissionCodesByRoleIDs(ctx, roleIDs)
+	if err != nil {
+		return nil, errors.NewDatabaseError(fmt.Errorf("failed to get role permissions: %w", err))
+	}
+
+	responses := make([]RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		codes := permissions[role.ID]
+		if codes == nil {
+			codes = []string{}
+		}
+
+		responses = append(responses, RoleResponse{
+			ID:          role.ID,
+			Code:        role.Code,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: codes,
+		})
+	}
+
+	return responses, nil
+}
+
+func mergePermissions(roles []RoleResponse) []string {
+	seen := make(map[string]struct{})
+	merged := []string{}
+
+	for _, role := range roles {
+		for _, code := range role.Permissions {
+			if _, ok := seen[code]; !ok {
+				seen[code] = struct{}{}
+				merged = append(merged, code)
+			}
+		}
+	}
+
+	sort.Strings(merged)
+	return merged
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]struct{}, len(ids))
+	unique := make([]uint, 0, len(ids))
+
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
@@ -9,6 +9,7 @@ import (
 type Repository interface {
 	Create(user *model.User) error
 	FindByUsername(username string) (*model.User, error)
+	FindByID(id int) (*model.User, error)
 }
 
 type repository struct {
@@ -28,3 +29,9 @@ func (r *repository) FindByUsername(username string) (*model.User, error) {
 	err := r.db.Where("username = ?", username).First(&user).Error
 	return &user, err
 }
+
+func (r *repository) FindByID(id int) (*model.User, error) {
+	var user model.User
+	err := r.db.First(&user, id).Error
+	return &user, err
+}
@@ -2,6 +2,7 @@ package user
 
 import (
 	"kswi-backend/internal/config"
+	"kswi-backend/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,7 +13,7 @@ func RegisterRoutes(r *gin.RouterGroup) {
 	svc := NewService(repo)
 	handler := NewHandler(svc)
 
-	userRoutes := r.Group("/users")
+	userRoutes := r.Group("/users", middleware.RequirePermission("user.manage"))
 	{
 		userRoutes.POST("", handler.CreateUser)
 		// Later: userRoutes.GET("", handler.GetUsers)
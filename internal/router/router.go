@@ -3,10 +3,13 @@ package router
 import (
 	"kswi-backend/internal/config"
 	"kswi-backend/internal/middleware"
+	"kswi-backend/internal/modules/auth"
+	"kswi-backend/internal/modules/job"
 	"kswi-backend/internal/modules/menu"
 	"kswi-backend/internal/modules/oss"
 	"kswi-backend/internal/modules/people"
 	"kswi-backend/internal/modules/person"
+	"kswi-backend/internal/modules/rbac"
 	"kswi-backend/internal/modules/user"
 	"net/http"
 	"time"
@@ -28,17 +31,27 @@ func SetupRouter() *gin.Engine {
 	// Add error handler middleware
 	r.Use(middleware.ErrorHandler(config.Get()))
 
+	// Resolve route permissions through the role tables
+	middleware.SetPermissionChecker(rbac.NewService(rbac.NewRepository(config.GetDB())))
+
 	// Common routes
 	commonRoutes(r)
 
 	// API routes
 	api := r.Group("/api")
 	{
-		menu.RegisterRoutes(api)
-		oss.RegisterRoutes(api)
-		user.RegisterRoutes(api)
-		person.RegisterRoutes(api)
-		people.RegisterRoutes(api)
+		// Public routes
+		auth.RegisterRoutes(api)
+
+		// Routes below require a valid access token
+		protected := api.Group("", middleware.AuthMiddleware(config.GetJWTManager()))
+		menu.RegisterRoutes(protected)
+		oss.RegisterRoutes(protected)
+		user.RegisterRoutes(protected)
+		person.RegisterRoutes(protected)
+		people.RegisterRoutes(protected)
+		rbac.RegisterRoutes(protected)
+		job.RegisterRoutes(protected)
 	}
 
 	return r
@@ -49,7 +62,7 @@ func corsConfig(r *gin.Engine) {
 	corsConfig := cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Device-ID"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -93,6 +106,12 @@ func commonRoutes(r *gin.Engine) {
 		})
 	})
 
+	// Public keys for verifying the access tokens issued by this service
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, config.GetJWTManager().JWKS())
+	})
+
 	// API info endpoint
 	r.GET("/api", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -100,6 +119,7 @@ func commonRoutes(r *gin.Engine) {
 			"version": "v1",
 			"endpoints": gin.H{
 				"health":    "/health",
+				"login":     "/api/auth/login",
 				"menu_tree": "/api/menu/tree",
 			},
 		})
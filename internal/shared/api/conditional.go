@@ -0,0 +1,66 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag returns a strong entity tag for the given representation
+func ETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified sets the ETag and Last-Modified validators on the response and
+// reports whether the request's conditional headers still match them. When it
+// returns true a 304 has been written and the handler must not write a body.
+// A zero lastModified or empty etag leaves that validator out.
+func NotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("Cache-Control", "private, no-cache")
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since (RFC 9110 13.2.2)
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if etag == "" || !etagMatches(match, etag) {
+			return false
+		}
+		c.Status(http.StatusNotModified)
+		return true
+	}
+
+	if since := c.GetHeader("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		if err != nil || lastModified.Truncate(time.Second).After(t) {
+			return false
+		}
+		c.Status(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+// etagMatches applies the weak comparison used by If-None-Match
+func etagMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+	"kswi-backend/internal/config"
+	"sync"
+	"time"
+)
+
+// Cache stores JSON-encodable values under string keys
+type Cache interface {
+	// Get decodes the value stored under key into dest and reports whether it was found
+	Get(ctx context.Context, key string, dest interface{}) (bool, error)
+	// Set stores value under key for the given duration
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Delete removes the given keys
+	Delete(ctx context.Context, keys ...string) error
+}
+
+var (
+	instance Cache
+	once     sync.Once
+)
+
+// Get returns the shared cache. It is backed by Redis when a connection is
+// available and by an in-memory store otherwise.
+func Get() Cache {
+	once.Do(func() {
+		if config.IsRedisEnabled() {
+			instance = NewRedisCache(config.GetRedis())
+			return
+		}
+
+		config.GetSugaredLogger().Warn("⚠️  Redis not available, using in-memory cache")
+		instance = NewMemoryCache()
+	})
+	return instance
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+// NewMemoryCache creates a cache that lives in process memory. Values are
+// stored encoded so callers never share mutable state through the cache.
+func NewMemoryCache() Cache {
+	return &memoryCache{entries: make(map[string]memoryEntry)}
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	m.mu.Lock()
+	entry, ok := m.entries[key]
+	if ok && time.Now().After(entry.expiresAt) {
+		delete(m.entries, key)
+		ok = false
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(entry.data, dest)
+}
+
+func (m *memoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purgeExpired()
+	m.entries[key] = memoryEntry{data: data, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *memoryCache) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// purgeExpired drops expired entries; the caller must hold the lock
+func (m *memoryCache) purgeExpired() {
+	now := time.Now()
+	for key, entry := range m.entries {
+		if now.After(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+}
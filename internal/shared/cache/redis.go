@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"kswi-backend/internal/config"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const keyPrefix = "cache:"
+
+type redisCache struct {
+	rdb *redis.Client
+}
+
+// NewRedisCache creates a cache stored in Redis as JSON. Reads that fail
+// because Redis is unreachable are treated as misses.
+func NewRedisCache(rdb *redis.Client) Cache {
+	return &redisCache{rdb: rdb}
+}
+
+func (r *redisCache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	data, err := r.rdb.Get(ctx, keyPrefix+key).Bytes()
+	switch {
+	case err == redis.Nil:
+		return false, nil
+	case err != nil:
+		r.warn("read", key, err)
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, dest); err != nil {
+		// Stale layout from an older release; drop it and rebuild
+		r.warn("decode", key, err)
+		_ = r.rdb.Del(ctx, keyPrefix+key).Err()
+		return false, nil
+	}
+	return true, nil
+}
+
+func (r *redisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.rdb.Set(ctx, keyPrefix+key, data, ttl).Err()
+}
+
+func (r *redisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = keyPrefix + key
+	}
+	return r.rdb.Del(ctx, prefixed...).Err()
+}
+
+func (r *redisCache) warn(action, key string, err error) {
+	config.GetSugaredLogger().Warnf("Redis cache failed to %s %s: %v", action, key, err)
+}
@@ -0,0 +1,65 @@
+package denylist
+
+import (
+	"context"
+	"kswi-backend/internal/config"
+	"sync"
+	"time"
+)
+
+// Denylist keeps track of access tokens that were revoked before they expired
+type Denylist interface {
+	// Revoke denies the token with the given jti for the given duration
+	Revoke(ctx context.Context, tokenID string, ttl time.Duration) error
+	// IsRevoked reports whether the token with the given jti was revoked
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+	// RevokeUser denies every token of the user issued at or before the second of the given time
+	RevokeUser(ctx context.Context, userID uint, at time.Time, ttl time.Duration) error
+	// UserRevokedAt returns the time set by the latest RevokeUser call, if still active
+	UserRevokedAt(ctx context.Context, userID uint) (time.Time, bool, error)
+}
+
+var (
+	instance Denylist
+	once     sync.Once
+)
+
+// Get returns the shared denylist. It is backed by Redis when a connection
+// is available and by an in-memory store otherwise.
+func Get() Denylist {
+	once.Do(func() {
+		if config.IsRedisEnabled() {
+			instance = NewRedisDenylist(config.GetRedis())
+			return
+		}
+
+		config.GetSugaredLogger().Warn("⚠️  Redis not available, using in-memory token denylist")
+		instance = NewMemoryDenylist()
+	})
+	return instance
+}
+
+// IsClaimsRevoked reports whether the token described by claims was revoked,
+// either individually or by a revoke-all of its user
+func IsClaimsRevoked(ctx context.Context, d Denylist, claims *config.Claims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := d.IsRevoked(ctx, claims.ID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	revokedAt, ok, err := d.UserRevokedAt(ctx, claims.UserID)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+
+	// iat has second precision and Redis keeps revokedAt in seconds too, so
+	// both are compared in seconds. A token issued in the second of the
+	// revoke is revoked as well, as it may have been issued before it.
+	return !claims.IssuedAt.Time.After(revokedAt.Truncate(time.Second)), nil
+}
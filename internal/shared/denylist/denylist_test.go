@@ -0,0 +1,62 @@
+package denylist
+
+import (
+	"context"
+	"kswi-backend/internal/config"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestIsClaimsRevoked(t *testing.T) {
+	ctx := context.Background()
+	revokedAt := time.Date(2024, 5, 1, 10, 0, 0, 600_000_000, time.UTC)
+
+	tests := []struct {
+		name     string
+		tokenID  string
+		issuedAt *time.Time
+		want     bool
+	}{
+		{name: "revoked by jti", tokenID: "revoked", issuedAt: timePtr(revokedAt.Add(time.Minute)), want: true},
+		{name: "issued before the revoke", tokenID: "a", issuedAt: timePtr(revokedAt.Add(-time.Minute)), want: true},
+		{name: "issued in the second of the revoke", tokenID: "b", issuedAt: timePtr(revokedAt.Truncate(time.Second)), want: true},
+		{name: "issued after the revoke", tokenID: "c", issuedAt: timePtr(revokedAt.Truncate(time.Second).Add(time.Second)), want: false},
+		{name: "without issue time", tokenID: "d", want: true},
+	}
+
+	d := NewMemoryDenylist()
+	if err := d.Revoke(ctx, "revoked", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RevokeUser(ctx, 1, revokedAt, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &config.Claims{UserID: 1}
+			claims.ID = tt.tokenID
+			if tt.issuedAt != nil {
+				claims.IssuedAt = jwt.NewNumericDate(*tt.issuedAt)
+			}
+
+			got, err := IsClaimsRevoked(ctx, d, claims)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsClaimsRevoked = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	other := &config.Claims{UserID: 2}
+	other.IssuedAt = jwt.NewNumericDate(revokedAt.Add(-time.Minute))
+	if got, _ := IsClaimsRevoked(ctx, d, other); got {
+		t.Error("a token of another user was revoked")
+	}
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
@@ -0,0 +1,79 @@
+package denylist
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value     time.Time
+	expiresAt time.Time
+}
+
+type memoryDenylist struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	users  map[uint]memoryEntry
+}
+
+// NewMemoryDenylist creates a denylist that lives in process memory. Entries
+// are lost on restart and are not shared between instances.
+func NewMemoryDenylist() Denylist {
+	return &memoryDenylist{
+		tokens: make(map[string]time.Time),
+		users:  make(map[uint]memoryEntry),
+	}
+}
+
+func (m *memoryDenylist) Revoke(ctx context.Context, tokenID string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purgeExpired()
+	m.tokens[tokenID] = time.Now().Add(ttl)
+	return nil
+}
+
+func (m *memoryDenylist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt, ok := m.tokens[tokenID]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (m *memoryDenylist) RevokeUser(ctx context.Context, userID uint, at time.Time, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purgeExpired()
+	m.users[userID] = memoryEntry{value: at, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *memoryDenylist) UserRevokedAt(ctx context.Context, userID uint) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.users[userID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return time.Time{}, false, nil
+	}
+	return entry.value, true, nil
+}
+
+// purgeExpired drops expired entries; the caller must hold the lock
+func (m *memoryDenylist) purgeExpired() {
+	now := time.Now()
+	for id, expiresAt := range m.tokens {
+		if now.After(expiresAt) {
+			delete(m.tokens, id)
+		}
+	}
+	for id, entry := range m.users {
+		if now.After(entry.expiresAt) {
+			delete(m.users, id)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package denylist
+
+import (
+	"context"
+	"kswi-backend/internal/config"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	tokenKeyPrefix = "auth:denylist:token:"
+	userKeyPrefix  = "auth:denylist:user:"
+)
+
+type redisDenylist struct {
+	rdb      *redis.Client
+	fallback Denylist
+}
+
+// NewRedisDenylist creates a denylist stored in Redis. Writes and reads that
+// fail because Redis is unreachable are served by an in-memory fallback.
+func NewRedisDenylist(rdb *redis.Client) Denylist {
+	return &redisDenylist{
+		rdb:      rdb,
+		fallback: NewMemoryDenylist(),
+	}
+}
+
+func (r *redisDenylist) Revoke(ctx context.Context, tokenID string, ttl time.Duration) error {
+	if err := r.rdb.Set(ctx, tokenKeyPrefix+tokenID, "1", ttl).Err(); err != nil {
+		r.warn("revoke token", err)
+		return r.fallback.Revoke(ctx, tokenID, ttl)
+	}
+	return nil
+}
+
+func (r *redisDenylist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	// Entries written while Redis was down only exist in the fallback
+	if revoked, _ := r.fallback.IsRevoked(ctx, tokenID); revoked {
+		return true, nil
+	}
+
+	n, err := r.rdb.Exists(ctx, tokenKeyPrefix+tokenID).Result()
+	if err != nil {
+		r.warn("check token", err)
+		return false, nil
+	}
+	return n > 0, nil
+}
+
+func (r *redisDenylist) RevokeUser(ctx context.Context, userID uint, at time.Time, ttl time.Duration) error {
+	key := userKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+	if err := r.rdb.Set(ctx, key, at.Unix(), ttl).Err(); err != nil {
+		r.warn("revoke user tokens", err)
+		return r.fallback.RevokeUser(ctx, userID, at, ttl)
+	}
+	return nil
+}
+
+func (r *redisDenylist) UserRevokedAt(ctx context.Context, userID uint) (time.Time, bool, error) {
+	// Entries written while Redis was down only exist in the fallback
+	revokedAt, found, _ := r.fallback.UserRevokedAt(ctx, userID)
+
+	key := userKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+	unix, err := r.rdb.Get(ctx, key).Int64()
+	switch {
+	case err == redis.Nil:
+	case err != nil:
+		r.warn("check user tokens", err)
+	default:
+		if at := time.Unix(unix, 0); !found || at.After(revokedAt) {
+			revokedAt, found = at, true
+		}
+	}
+
+	return revokedAt, found, nil
+}
+
+func (r *redisDenylist) warn(action string, err error) {
+	config.GetSugaredLogger().Warnf("Redis denylist failed to %s, using in-memory fallback: %v", action, err)
+}
@@ -13,6 +13,8 @@ const (
 	TypeDatabase   ErrorType = "DATABASE_ERROR"
 	TypeInternal   ErrorType = "INTERNAL_ERROR"
 	TypeNotFound   ErrorType = "NOT_FOUND"
+	TypeForbidden  ErrorType = "FORBIDDEN"
+	TypeConflict   ErrorType = "CONFLICT"
 )
 
 type AppError struct {
@@ -67,6 +69,10 @@ func (e *AppError) GetStatusCode() int {
 		return http.StatusInternalServerError
 	case TypeNotFound:
 		return http.StatusNotFound
+	case TypeForbidden:
+		return http.StatusForbidden
+	case TypeConflict:
+		return http.StatusConflict
 	default:
 		return http.StatusInternalServerError
 	}
@@ -85,6 +91,22 @@ func NewNotFoundError(resource string) *AppError {
 	return NewAppError(TypeNotFound, resource+" not found", nil)
 }
 
+func NewAuthError(message string) *AppError {
+	return NewAppError(TypeAuth, message, nil)
+}
+
+func NewForbiddenError(message string) *AppError {
+	return NewAppError(TypeForbidden, message, nil)
+}
+
+func NewForbiddenErrorWithDetails(message string, details interface{}) *AppError {
+	return NewAppError(TypeForbidden, message, details)
+}
+
+func NewConflictError(message string) *AppError {
+	return NewAppError(TypeConflict, message, nil)
+}
+
 func NewDatabaseError(err error) *AppError {
 	return NewAppErrorWithOriginal(TypeDatabase, "Database operation failed", err.Error(), err)
 }
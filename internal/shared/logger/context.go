@@ -3,6 +3,7 @@ package logger
 import (
 	"context"
 	"kswi-backend/internal/config"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -60,6 +61,14 @@ func (cl *ContextualLogger) WithUserContext(userID, username string) *Contextual
 	}
 }
 
+// WithClaims adds the authenticated caller from JWT claims
+func (cl *ContextualLogger) WithClaims(claims *config.Claims) *ContextualLogger {
+	if claims == nil {
+		return cl
+	}
+	return cl.WithUserContext(strconv.FormatUint(uint64(claims.UserID), 10), claims.Username)
+}
+
 // WithFields adds custom fields
 func (cl *ContextualLogger) WithFields(keyValuePairs ...interface{}) *ContextualLogger {
 	newFields := append(cl.fields, keyValuePairs...)
@@ -111,6 +120,10 @@ func FromGinContext(c *gin.Context) *ContextualLogger {
 		}
 	}
 
+	if claims, ok := config.ClaimsFromContext(c.Request.Context()); ok {
+		logger = logger.WithClaims(claims)
+	}
+
 	return logger
 }
 
@@ -124,5 +137,9 @@ func FromContext(ctx context.Context) *ContextualLogger {
 		}
 	}
 
+	if claims, ok := config.ClaimsFromContext(ctx); ok {
+		logger = logger.WithClaims(claims)
+	}
+
 	return logger
 }
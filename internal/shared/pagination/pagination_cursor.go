@@ -0,0 +1,159 @@
+package pagination
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"kswi-backend/internal/shared/errors"
+	"strings"
+	"time"
+)
+
+// Pagination modes
+const (
+	// ModeOffset pages with page and per_page
+	ModeOffset = "offset"
+	// ModeCursor pages with opaque cursors holding the sort key of the last
+	// row seen, which stays fast on deep pages
+	ModeCursor = "cursor"
+)
+
+// Count modes, telling how the totals of a page are obtained
+const (
+	CountExact    = "exact"
+	CountEstimate = "estimate"
+	CountCached   = "cached"
+	CountNone     = "none"
+)
+
+// cursorData is the content of a cursor before encoding
+type cursorData struct {
+	// Sort identifies the order the cursor was made for
+	Sort     string            `json:"s"`
+	Values   []json.RawMessage `json:"v"`
+	Backward bool              `json:"b,omitempty"`
+}
+
+// EncodeCursor makes an opaque cursor pointing after the row with the given
+// sort key values, or before it when backward is set
+func (o Order) EncodeCursor(values []interface{}, backward bool) string {
+	data := cursorData{Sort: o.signature(), Values: make([]json.RawMessage, len(values)), Backward: backward}
+	for i, value := range values {
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339Nano)
+		}
+		data.Values[i], _ = json.Marshal(value)
+	}
+
+	encoded, _ := json.Marshal(data)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeCursor reads a cursor made by EncodeCursor for this order
+func (o Order) DecodeCursor(cursor string) ([]interface{}, bool, error) {
+	invalid := errors.NewValidationError([]errors.ValidationError{{
+		Field:   "cursor",
+		Message: "Invalid cursor",
+	}})
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, false, invalid
+	}
+
+	var data cursorData
+	if err := json.Unmarshal(raw, &data); err != nil || len(data.Values) != len(o) {
+		return nil, false, invalid
+	}
+	if data.Sort != o.signature() {
+		return nil, false, errors.NewValidationError([]errors.ValidationError{{
+			Field:   "cursor",
+			Message: "Cursor was made for a different sort",
+		}})
+	}
+
+	values := make([]interface{}, len(o))
+	for i, key := range o {
+		decoder := json.NewDecoder(bytes.NewReader(data.Values[i]))
+		decoder.UseNumber()
+
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, false, invalid
+		}
+		if value == nil {
+			continue
+		}
+
+		if values[i], err = coerce(key.Field.Type, value); err != nil {
+			return nil, false, invalid
+		}
+	}
+
+	return values, data.Backward, nil
+}
+
+func (o Order) signature() string {
+	sum := sha1.Sum([]byte(o.String()))
+	return hex.EncodeToString(sum[:4])
+}
+
+// Reverse returns the order with every direction flipped, used to read the
+// rows before a cursor
+func (o Order) Reverse() Order {
+	reversed := make(Order, len(o))
+	for i, key := range o {
+		reversed[i] = OrderKey{Field: key.Field, Desc: !key.Desc}
+	}
+	return reversed
+}
+
+// Seek returns the condition selecting the rows that come after the row with
+// the given key values in this order. MySQL sorts NULL before any value, so
+// NULL keys are compared with IS NULL / IS NOT NULL.
+func (o Order) Seek(values []interface{}) (string, []interface{}) {
+	var branches []string
+	var args []interface{}
+
+	for i, key := range o {
+		column := "`" + key.Field.Column + "`"
+
+		var after string
+		var afterArgs []interface{}
+		switch {
+		case values[i] == nil && key.Desc:
+			// Nothing sorts after NULL in descending order
+			continue
+		case values[i] == nil:
+			after = column + " IS NOT NULL"
+		case key.Desc:
+			after = "(" + column + " < ? OR " + column + " IS NULL)"
+			afterArgs = []interface{}{values[i]}
+		default:
+			after = column + " > ?"
+			afterArgs = []interface{}{values[i]}
+		}
+
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			previous := "`" + o[j].Field.Column + "`"
+			if values[j] == nil {
+				parts = append(parts, previous+" IS NULL")
+			} else {
+				parts = append(parts, previous+" = ?")
+				args = append(args, values[j])
+			}
+		}
+		parts = append(parts, after)
+		args = append(args, afterArgs...)
+
+		branches = append(branches, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	if len(branches) == 0 {
+		return "1 = 0", nil
+	}
+	return "(" + strings.Join(branches, " OR ") + ")", args
+}
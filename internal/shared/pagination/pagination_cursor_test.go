@@ -0,0 +1,102 @@
+package pagination
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testOrder(nameDesc, idDesc bool) Order {
+	name, _ := testSchema.Field("name")
+	id, _ := testSchema.Field("id")
+	return Order{{Field: name, Desc: nameDesc}, {Field: id, Desc: idDesc}}
+}
+
+func TestOrderSeek(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  Order
+		values []interface{}
+		sql    string
+		args   []interface{}
+	}{
+		{
+			name:   "ascending",
+			order:  testOrder(false, false),
+			values: []interface{}{"b", int64(7)},
+			sql:    "((`nama` > ?) OR (`nama` = ? AND `id` > ?))",
+			args:   []interface{}{"b", "b", int64(7)},
+		},
+		{
+			name:   "ascending after NULL",
+			order:  testOrder(false, false),
+			values: []interface{}{nil, int64(7)},
+			sql:    "((`nama` IS NOT NULL) OR (`nama` IS NULL AND `id` > ?))",
+			args:   []interface{}{int64(7)},
+		},
+		{
+			name:   "descending, NULL comes last",
+			order:  testOrder(true, false),
+			values: []interface{}{"b", int64(7)},
+			sql:    "(((`nama` < ? OR `nama` IS NULL)) OR (`nama` = ? AND `id` > ?))",
+			args:   []interface{}{"b", "b", int64(7)},
+		},
+		{
+			name:   "descending after NULL",
+			order:  testOrder(true, false),
+			values: []interface{}{nil, int64(7)},
+			sql:    "((`nama` IS NULL AND `id` > ?))",
+			args:   []interface{}{int64(7)},
+		},
+		{
+			name:   "descending tiebreaker",
+			order:  testOrder(false, true),
+			values: []interface{}{nil, int64(7)},
+			sql:    "((`nama` IS NOT NULL) OR (`nama` IS NULL AND (`id` < ? OR `id` IS NULL)))",
+			args:   []interface{}{int64(7)},
+		},
+		{
+			name:   "reversed ascending after NULL reads backward",
+			order:  testOrder(false, false).Reverse(),
+			values: []interface{}{nil, int64(7)},
+			sql:    "((`nama` IS NULL AND (`id` < ? OR `id` IS NULL)))",
+			args:   []interface{}{int64(7)},
+		},
+		{
+			name:   "nothing after NULL keys in descending order",
+			order:  testOrder(true, true),
+			values: []interface{}{nil, nil},
+			sql:    "1 = 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := tt.order.Seek(tt.values)
+			if sql != tt.sql {
+				t.Errorf("sql = %q, want %q", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestOrderCursorKeepsNull(t *testing.T) {
+	order := testOrder(true, false)
+
+	values, backward, err := order.DecodeCursor(order.EncodeCursor([]interface{}{nil, int64(7)}, true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !backward {
+		t.Error("backward = false, want true")
+	}
+	if want := []interface{}{nil, int64(7)}; !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %#v, want %#v", values, want)
+	}
+
+	if _, _, err := testOrder(false, false).DecodeCursor(order.EncodeCursor(values, false)); err == nil {
+		t.Error("expected an error for a cursor of another sort")
+	}
+}
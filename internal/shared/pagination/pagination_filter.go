@@ -1,13 +1,25 @@
 package pagination
 
-import "fmt"
-
+// Filter is a node of a filter tree: either a condition on a column, a group
+// of filters joined by AND or OR, or a NOT wrapping another filter. Groups
+// nest to any depth, e.g. (prov = A AND skala = X) OR (prov = B AND resiko = Tinggi):
+//
+//	{"or": [
+//	  {"and": [{"columnKey": "perusahaanProv", "operator": "=", "value": "A"}, ...]},
+//	  {"and": [{"columnKey": "perusahaanProv", "operator": "=", "value": "B"}, ...]}
+//	]}
 type Filter struct {
-	ColumnKey string      `json:"columnKey"`
-	Operator  string      `json:"operator"`
-	Value     interface{} `json:"value"`
+	ColumnKey string      `json:"columnKey,omitempty"`
+	Operator  string      `json:"operator,omitempty"`
+	Value     interface{} `json:"value,omitempty"`
+
+	And []Filter `json:"and,omitempty"`
+	Or  []Filter `json:"or,omitempty"`
+	Not *Filter  `json:"not,omitempty"`
 }
 
+// Filters is the root of a filter tree. Every filter in And must match, and
+// when Or is set at least one of its filters must match as well.
 type Filters struct {
 	And []Filter `json:"and"`
 	Or  []Filter `json:"or"`
@@ -26,33 +38,3 @@ func ValidateSortField(field string, allowedFields []string) bool {
 	}
 	return false
 }
-
-func BuildWhereClause(filter Filter) (string, interface{}) {
-	column := filter.ColumnKey
-	operator := filter.Operator
-	value := filter.Value
-
-	switch operator {
-	case "=", "!=", "<", ">", "<=", ">=":
-		return fmt.Sprintf("%s %s ?", column, operator), value
-	case "LIKE %_%":
-		return fmt.Sprintf("%s LIKE ?", column), "%" + value.(string) + "%"
-	case "LIKE _%":
-		return fmt.Sprintf("%s LIKE ?", column), value.(string) + "%"
-	case "LIKE %_":
-		return fmt.Sprintf("%s LIKE ?", column), "%" + value.(string)
-	case "IN":
-		return fmt.Sprintf("%s IN (?)", column), value
-	case "NOT IN":
-		return fmt.Sprintf("%s NOT IN (?)", column), value
-	case "IS NULL":
-		return fmt.Sprintf("%s IS NULL", column), nil
-	case "IS NOT NULL":
-		return fmt.Sprintf("%s IS NOT NULL", column), nil
-	case "BETWEEN":
-		if values, ok := value.([]interface{}); ok && len(values) == 2 {
-			return fmt.Sprintf("%s BETWEEN ? AND ?", column), values
-		}
-	}
-	return "", nil
-}
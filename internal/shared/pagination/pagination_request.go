@@ -8,6 +8,23 @@ type PaginationRequest struct {
 	SortBy   string   `json:"sort_by"`
 	SortDesc bool     `json:"sort_desc"`
 	Filters  *Filters `json:"filters"`
+
+	// Sort lists several sort keys in order and takes precedence over SortBy
+	Sort []SortField `json:"sort"`
+
+	// Mode is offset (default) or cursor. In cursor mode Page is ignored and
+	// Cursor, when set, is the next_cursor or prev_cursor of a previous page.
+	Mode   string `json:"mode" binding:"omitempty,oneof=offset cursor"`
+	Cursor string `json:"cursor"`
+	// Count is how totals are obtained: exact (default), estimate, cached,
+	// or none to skip them, which is only allowed in cursor mode
+	Count string `json:"count" binding:"omitempty,oneof=exact estimate cached none"`
+}
+
+// SortField is one key of a multi-column sort
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
 }
 
 // QueryParams returns the query parameters for SQL
@@ -31,6 +48,26 @@ func (r *PaginationRequest) SortParams() (column string, direction string) {
 	return r.SortBy, direction
 }
 
+// SortFields returns the requested sort keys: Sort when given, otherwise
+// SortBy and SortDesc
+func (r *PaginationRequest) SortFields() []SortField {
+	if len(r.Sort) > 0 {
+		return r.Sort
+	}
+	if r.SortBy == "" {
+		return nil
+	}
+	return []SortField{{Field: r.SortBy, Desc: r.SortDesc}}
+}
+
+// CountMode returns the requested count mode, exact by default
+func (r *PaginationRequest) CountMode() string {
+	if r.Count == "" {
+		return CountExact
+	}
+	return r.Count
+}
+
 // WithDefaultSort sets default sort if none provided
 func (r *PaginationRequest) WithDefaultSort(field string, desc bool) {
 	if r.SortBy == "" {
@@ -7,12 +7,19 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// PaginationMeta describes a page. Totals are -1 when they were not counted
+// and approximate when CountMode is estimate or cached. NextCursor and
+// PrevCursor are only set in cursor mode.
 type PaginationMeta struct {
 	CurrentPage   int `json:"current_page"`
 	PerPage       int `json:"per_page"`
 	TotalPages    int `json:"total_pages"`
 	Total         int `json:"total"`
 	TotalFiltered int `json:"total_filtered"`
+
+	CountMode  string  `json:"count_mode,omitempty"`
+	NextCursor *string `json:"next_cursor,omitempty"`
+	PrevCursor *string `json:"prev_cursor,omitempty"`
 }
 
 type PaginationLinks struct {
@@ -28,6 +35,11 @@ type ResponseParam struct {
 	Data     any
 	Total    int
 	Filtered int
+
+	// NextCursor and PrevCursor are the cursors of a page in cursor mode,
+	// empty when there is no such page
+	NextCursor string
+	PrevCursor string
 }
 
 type PaginationResponse struct {
@@ -43,6 +55,9 @@ type PaginationResponseData struct {
 
 func BuildMeta(currentPage, perPage, total, totalFiltered int) PaginationMeta {
 	totalPages := int(math.Ceil(float64(totalFiltered) / float64(perPage)))
+	if totalFiltered < 0 {
+		totalPages = -1
+	}
 
 	return PaginationMeta{
 		CurrentPage:   currentPage,
@@ -92,8 +107,14 @@ func BuildLinks(c *gin.Context, req PaginationRequest, totalPages int) Paginatio
 }
 
 func BuildResponse(d ResponseParam) *PaginationResponse {
+	if d.Req.Mode == ModeCursor {
+		return buildCursorResponse(d)
+	}
 
 	meta := BuildMeta(d.Req.Page, d.Req.PerPage, d.Total, d.Filtered)
+	if d.Req.Count != "" {
+		meta.CountMode = d.Req.Count
+	}
 	links := BuildLinks(d.Ctx, d.Req, meta.TotalPages)
 
 	return &PaginationResponse{
@@ -105,3 +126,24 @@ func BuildResponse(d ResponseParam) *PaginationResponse {
 		},
 	}
 }
+
+// buildCursorResponse builds a page of cursor mode, which has no page
+// numbers and therefore no links
+func buildCursorResponse(d ResponseParam) *PaginationResponse {
+	meta := BuildMeta(0, d.Req.PerPage, d.Total, d.Filtered)
+	meta.CountMode = d.Req.CountMode()
+	if d.NextCursor != "" {
+		meta.NextCursor = &d.NextCursor
+	}
+	if d.PrevCursor != "" {
+		meta.PrevCursor = &d.PrevCursor
+	}
+
+	return &PaginationResponse{
+		Success: true,
+		Data: PaginationResponseData{
+			Data: d.Data,
+			Meta: meta,
+		},
+	}
+}
@@ -0,0 +1,504 @@
+package pagination
+
+import (
+	"encoding/json"
+	"fmt"
+	"kswi-backend/internal/shared/errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType is the type filter values are coerced to
+type FieldType string
+
+const (
+	FieldString FieldType = "string"
+	FieldInt    FieldType = "int"
+	FieldDate   FieldType = "date"
+	FieldBool   FieldType = "bool"
+)
+
+// Filter operators
+const (
+	OpEqual        = "="
+	OpNotEqual     = "!="
+	OpLess         = "<"
+	OpGreater      = ">"
+	OpLessEqual    = "<="
+	OpGreaterEqual = ">="
+	OpContains     = "LIKE %_%"
+	OpStartsWith   = "LIKE _%"
+	OpEndsWith     = "LIKE %_"
+	OpIn           = "IN"
+	OpNotIn        = "NOT IN"
+	OpIsNull       = "IS NULL"
+	OpIsNotNull    = "IS NOT NULL"
+	OpBetween      = "BETWEEN"
+)
+
+// Limits of a filter tree
+const (
+	// maxFilterValues caps the values of an IN or NOT IN filter
+	maxFilterValues = 1000
+	// maxFilterDepth caps the nesting of groups and NOT wrappers
+	maxFilterDepth = 8
+	// maxFilterConditions caps the conditions of a whole tree
+	maxFilterConditions = 200
+	// maxSortFields caps the keys of a multi-column sort
+	maxSortFields = 5
+)
+
+// defaultOperators are the operators allowed on a field that lists none
+var defaultOperators = map[FieldType][]string{
+	FieldString: {OpEqual, OpNotEqual, OpContains, OpStartsWith, OpEndsWith, OpIn, OpNotIn, OpIsNull, OpIsNotNull},
+	FieldInt:    {OpEqual, OpNotEqual, OpLess, OpGreater, OpLessEqual, OpGreaterEqual, OpIn, OpNotIn, OpBetween, OpIsNull, OpIsNotNull},
+	FieldDate:   {OpEqual, OpNotEqual, OpLess, OpGreater, OpLessEqual, OpGreaterEqual, OpBetween, OpIsNull, OpIsNotNull},
+	FieldBool:   {OpEqual, OpNotEqual, OpIsNull, OpIsNotNull},
+}
+
+// dateLayouts are the accepted formats of date filter values
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// Field is a filterable column. Name is the key clients use; the DB column
+// is accepted as well. Operators defaults to every operator suited to Type.
+// Only Sortable fields can be sorted on.
+type Field struct {
+	Name      string
+	Column    string
+	Type      FieldType
+	Operators []string
+	Sortable  bool
+}
+
+// Schema holds the filterable fields of a module and compiles client
+// filters into SQL using only the registered columns
+type Schema struct {
+	fields map[string]*Field
+}
+
+// NewSchema registers fields
+func NewSchema(fields ...Field) *Schema {
+	s := &Schema{fields: make(map[string]*Field, len(fields)*2)}
+	for i := range fields {
+		f := fields[i]
+		if len(f.Operators) == 0 {
+			f.Operators = defaultOperators[f.Type]
+		}
+		s.fields[f.Name] = &f
+		s.fields[f.Column] = &f
+	}
+	return s
+}
+
+// Field finds a field by name or DB column
+func (s *Schema) Field(name string) (*Field, bool) {
+	f, ok := s.fields[name]
+	return f, ok
+}
+
+// Compile turns a filter tree into a WHERE clause: the AND list, then the OR
+// list as one parenthesized condition, with nested groups parenthesized in
+// turn. An empty clause means no condition. Bad filters are returned together
+// as a validation error.
+func (s *Schema) Compile(filters *Filters) (string, []interface{}, error) {
+	if filters == nil {
+		return "", nil, nil
+	}
+
+	c := compiler{schema: s}
+	and := c.list(filters.And, "filters.and", 1)
+	or := c.list(filters.Or, "filters.or", 1)
+
+	if c.conditions > maxFilterConditions {
+		c.fail("filters", "Must have at most %d conditions", maxFilterConditions)
+	}
+	if len(c.details) > 0 {
+		return "", nil, errors.NewValidationError(c.details)
+	}
+
+	var clauses []string
+	var args []interface{}
+	for _, cond := range and {
+		clauses = append(clauses, cond.sql)
+		args = append(args, cond.args...)
+	}
+	if len(or) > 0 {
+		parts := make([]string, len(or))
+		for i, cond := range or {
+			parts[i] = cond.sql
+			args = append(args, cond.args...)
+		}
+		clauses = append(clauses, "("+strings.Join(parts, " OR ")+")")
+	}
+
+	return strings.Join(clauses, " AND "), args, nil
+}
+
+// OrderKey is a compiled sort key
+type OrderKey struct {
+	Field *Field
+	Desc  bool
+}
+
+// Order is a compiled sort, ready for ORDER BY
+type Order []OrderKey
+
+func (o Order) String() string {
+	parts := make([]string, len(o))
+	for i, key := range o {
+		direction := "ASC"
+		if key.Desc {
+			direction = "DESC"
+		}
+		parts[i] = "`" + key.Field.Column + "` " + direction
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Sort compiles the sort keys of a request against the sortable fields. The
+// tiebreaker field, normally the primary key, is appended ascending unless it
+// is already a key, so that rows with equal keys keep a stable order.
+func (s *Schema) Sort(req *PaginationRequest, tiebreaker string) (Order, error) {
+	fields := req.SortFields()
+
+	var details []errors.ValidationError
+	if len(fields) > maxSortFields {
+		details = append(details, errors.ValidationError{
+			Field:   "sort",
+			Message: fmt.Sprintf("Must have at most %d keys", maxSortFields),
+		})
+	}
+
+	order := make(Order, 0, len(fields)+1)
+	seen := make(map[string]bool, len(fields)+1)
+	for i, sort := range fields {
+		path := fmt.Sprintf("sort[%d].field", i)
+		if len(req.Sort) == 0 {
+			path = "sort_by"
+		}
+
+		field, ok := s.Field(sort.Field)
+		if !ok || !field.Sortable {
+			details = append(details, errors.ValidationError{
+				Field:   path,
+				Message: fmt.Sprintf("Unknown or non-sortable column '%s'", sort.Field),
+			})
+			continue
+		}
+		if seen[field.Column] {
+			continue
+		}
+
+		seen[field.Column] = true
+		order = append(order, OrderKey{Field: field, Desc: sort.Desc})
+	}
+
+	if len(details) > 0 {
+		return nil, errors.NewValidationError(details)
+	}
+
+	field, ok := s.Field(tiebreaker)
+	if !ok {
+		return nil, fmt.Errorf("tiebreaker %s is not a registered field", tiebreaker)
+	}
+	if !seen[field.Column] {
+		order = append(order, OrderKey{Field: field})
+	}
+
+	return order, nil
+}
+
+// Without returns the filters with the conditions on one field dropped, as a
+// facet needs to ignore its own selection. Only the root AND entries and the
+// root OR list that refer to that field alone are dropped, so conditions on
+// other fields are never widened. Unknown fields leave the filters as they
+// are.
+func (s *Schema) Without(filters *Filters, name string) *Filters {
+	field, ok := s.Field(name)
+	if filters == nil || !ok {
+		return filters
+	}
+
+	out := &Filters{}
+	for _, filter := range filters.And {
+		if !s.only(filter, field) {
+			out.And = append(out.And, filter)
+		}
+	}
+
+	for _, filter := range filters.Or {
+		if !s.only(filter, field) {
+			out.Or = filters.Or
+			break
+		}
+	}
+
+	return out
+}
+
+// only reports whether every condition of a filter is on field
+func (s *Schema) only(filter Filter, field *Field) bool {
+	switch {
+	case filter.Not != nil:
+		return s.only(*filter.Not, field)
+	case filter.And != nil || filter.Or != nil:
+		children := append(append([]Filter{}, filter.And...), filter.Or...)
+		for _, child := range children {
+			if !s.only(child, field) {
+				return false
+			}
+		}
+		return len(children) > 0
+	}
+
+	f, ok := s.Field(filter.ColumnKey)
+	return ok && f.Column == field.Column
+}
+
+type condition struct {
+	sql  string
+	args []interface{}
+}
+
+type compiler struct {
+	schema     *Schema
+	details    []errors.ValidationError
+	conditions int
+}
+
+func (c *compiler) fail(path, format string, a ...interface{}) {
+	c.details = append(c.details, errors.ValidationError{Field: path, Message: fmt.Sprintf(format, a...)})
+}
+
+func (c *compiler) list(filters []Filter, path string, depth int) []condition {
+	conditions := make([]condition, 0, len(filters))
+	for i, filter := range filters {
+		if cond, ok := c.node(filter, fmt.Sprintf("%s[%d]", path, i), depth); ok {
+			conditions = append(conditions, cond)
+		}
+	}
+	return conditions
+}
+
+// node compiles a condition, a group or a NOT wrapper
+func (c *compiler) node(filter Filter, path string, depth int) (condition, bool) {
+	kinds := 0
+	for _, set := range []bool{filter.ColumnKey != "" || filter.Operator != "", filter.And != nil, filter.Or != nil, filter.Not != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		c.fail(path, "Must be exactly one of a condition (columnKey), and, or, not")
+		return condition{}, false
+	}
+
+	if filter.And == nil && filter.Or == nil && filter.Not == nil {
+		c.conditions++
+		return c.filter(filter, path)
+	}
+
+	if depth >= maxFilterDepth {
+		c.fail(path, "Groups must be nested at most %d levels deep", maxFilterDepth)
+		return condition{}, false
+	}
+
+	if filter.Not != nil {
+		inner, ok := c.node(*filter.Not, path+".not", depth+1)
+		if !ok {
+			return condition{}, false
+		}
+		return condition{sql: "NOT (" + inner.sql + ")", args: inner.args}, true
+	}
+
+	join, children, childPath := " AND ", filter.And, path+".and"
+	if filter.Or != nil {
+		join, children, childPath = " OR ", filter.Or, path+".or"
+	}
+	if len(children) == 0 {
+		c.fail(childPath, "Must not be empty")
+		return condition{}, false
+	}
+
+	before := len(c.details)
+	conditions := c.list(children, childPath, depth+1)
+	if len(c.details) > before {
+		return condition{}, false
+	}
+
+	parts := make([]string, len(conditions))
+	var args []interface{}
+	for i, cond := range conditions {
+		parts[i] = cond.sql
+		args = append(args, cond.args...)
+	}
+	return condition{sql: "(" + strings.Join(parts, join) + ")", args: args}, true
+}
+
+func (c *compiler) filter(filter Filter, path string) (condition, bool) {
+	field, ok := c.schema.Field(filter.ColumnKey)
+	if !ok {
+		c.fail(path+".columnKey", "Unknown or non-filterable column '%s'", filter.ColumnKey)
+		return condition{}, false
+	}
+
+	operator := strings.ToUpper(strings.TrimSpace(filter.Operator))
+	if !containsOperator(field.Operators, operator) {
+		c.fail(path+".operator", "Operator '%s' is not allowed on '%s'", filter.Operator, field.Name)
+		return condition{}, false
+	}
+
+	column := "`" + field.Column + "`"
+	valuePath := path + ".value"
+
+	switch operator {
+	case OpIsNull, OpIsNotNull:
+		return condition{sql: column + " " + operator}, true
+
+	case OpContains, OpStartsWith, OpEndsWith:
+		value, err := coerce(FieldString, filter.Value)
+		if err != nil {
+			c.fail(valuePath, "%s", err)
+			return condition{}, false
+		}
+		pattern := escapeLike(value.(string))
+		switch operator {
+		case OpContains:
+			pattern = "%" + pattern + "%"
+		case OpStartsWith:
+			pattern = pattern + "%"
+		case OpEndsWith:
+			pattern = "%" + pattern
+		}
+		return condition{sql: column + " LIKE ?", args: []interface{}{pattern}}, true
+
+	case OpIn, OpNotIn:
+		values, ok := filter.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			c.fail(valuePath, "Must be a non-empty list")
+			return condition{}, false
+		}
+		if len(values) > maxFilterValues {
+			c.fail(valuePath, "Must have at most %d values", maxFilterValues)
+			return condition{}, false
+		}
+		coerced, ok := c.values(field.Type, values, valuePath)
+		if !ok {
+			return condition{}, false
+		}
+		return condition{sql: column + " " + operator + " ?", args: []interface{}{coerced}}, true
+
+	case OpBetween:
+		values, ok := filter.Value.([]interface{})
+		if !ok || len(values) != 2 {
+			c.fail(valuePath, "Must be a list of two values")
+			return condition{}, false
+		}
+		coerced, ok := c.values(field.Type, values, valuePath)
+		if !ok {
+			return condition{}, false
+		}
+		return condition{sql: column + " BETWEEN ? AND ?", args: coerced}, true
+
+	default:
+		value, err := coerce(field.Type, filter.Value)
+		if err != nil {
+			c.fail(valuePath, "%s", err)
+			return condition{}, false
+		}
+		return condition{sql: column + " " + operator + " ?", args: []interface{}{value}}, true
+	}
+}
+
+func (c *compiler) values(t FieldType, values []interface{}, path string) ([]interface{}, bool) {
+	coerced := make([]interface{}, len(values))
+	ok := true
+	for i, v := range values {
+		value, err := coerce(t, v)
+		if err != nil {
+			c.fail(fmt.Sprintf("%s[%d]", path, i), "%s", err)
+			ok = false
+			continue
+		}
+		coerced[i] = value
+	}
+	return coerced, ok
+}
+
+// coerce converts a decoded JSON value to the Go type of a field
+func coerce(t FieldType, value interface{}) (interface{}, error) {
+	switch t {
+	case FieldString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case json.Number:
+			return v.String(), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+		return nil, fmt.Errorf("Must be a string")
+
+	case FieldInt:
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+				return int64(v), nil
+			}
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				return n, nil
+			}
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, fmt.Errorf("Must be a whole number")
+
+	case FieldDate:
+		if v, ok := value.(string); ok {
+			for _, layout := range dateLayouts {
+				if parsed, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+					return parsed, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("Must be a date (YYYY-MM-DD or RFC 3339)")
+
+	case FieldBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1, nil
+			}
+		}
+		return nil, fmt.Errorf("Must be true or false")
+	}
+
+	return nil, fmt.Errorf("Unsupported field type %s", t)
+}
+
+// escapeLike makes LIKE wildcards in a value match literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func containsOperator(operators []string, operator string) bool {
+	for _, op := range operators {
+		if op == operator {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,338 @@
+package pagination
+
+import (
+	"kswi-backend/internal/shared/errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSchema = NewSchema(
+	Field{Name: "name", Column: "nama", Type: FieldString, Sortable: true},
+	Field{Name: "amount", Column: "jumlah", Type: FieldInt},
+	Field{Name: "created", Column: "_created_at", Type: FieldDate},
+	Field{Name: "active", Column: "aktif", Type: FieldBool},
+	Field{Name: "id", Column: "id", Type: FieldInt, Sortable: true},
+)
+
+func cond(column, operator string, value interface{}) Filter {
+	return Filter{ColumnKey: column, Operator: operator, Value: value}
+}
+
+// validationFields returns the fields of the details of a validation error
+func validationFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	var appErr *errors.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	details, ok := appErr.Details.([]errors.ValidationError)
+	if !ok {
+		t.Fatalf("expected validation details, got %#v", appErr.Details)
+	}
+
+	fields := make([]string, len(details))
+	for i, detail := range details {
+		fields[i] = detail.Field
+	}
+	return fields
+}
+
+// nestNot wraps a filter in n NOT wrappers
+func nestNot(filter Filter, n int) Filter {
+	for i := 0; i < n; i++ {
+		inner := filter
+		filter = Filter{Not: &inner}
+	}
+	return filter
+}
+
+func TestCompile(t *testing.T) {
+	day := func(s string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", s)
+		return parsed
+	}
+
+	tests := []struct {
+		name    string
+		filters *Filters
+		sql     string
+		args    []interface{}
+	}{
+		{
+			name: "nil filters",
+		},
+		{
+			name:    "column by name and by DB column",
+			filters: &Filters{And: []Filter{cond("name", "=", "a"), cond("jumlah", ">", 5.0)}},
+			sql:     "`nama` = ? AND `jumlah` > ?",
+			args:    []interface{}{"a", int64(5)},
+		},
+		{
+			name:    "operator is case and space insensitive",
+			filters: &Filters{And: []Filter{cond("name", " is null ", nil)}},
+			sql:     "`nama` IS NULL",
+		},
+		{
+			name:    "int from a numeric string",
+			filters: &Filters{And: []Filter{cond("amount", "=", " 42 ")}},
+			sql:     "`jumlah` = ?",
+			args:    []interface{}{int64(42)},
+		},
+		{
+			name:    "string from a number",
+			filters: &Filters{And: []Filter{cond("name", "=", 12.5)}},
+			sql:     "`nama` = ?",
+			args:    []interface{}{"12.5"},
+		},
+		{
+			name:    "bool from 0 and 1",
+			filters: &Filters{And: []Filter{cond("active", "=", 1.0), cond("active", "!=", 0.0)}},
+			sql:     "`aktif` = ? AND `aktif` != ?",
+			args:    []interface{}{true, false},
+		},
+		{
+			name:    "LIKE wildcards are escaped",
+			filters: &Filters{And: []Filter{cond("name", OpContains, `50%_\`)}},
+			sql:     "`nama` LIKE ?",
+			args:    []interface{}{`%50\%\_\\%`},
+		},
+		{
+			name:    "starts with and ends with",
+			filters: &Filters{And: []Filter{cond("name", OpStartsWith, "a"), cond("name", OpEndsWith, "z")}},
+			sql:     "`nama` LIKE ? AND `nama` LIKE ?",
+			args:    []interface{}{"a%", "%z"},
+		},
+		{
+			name:    "IN coerces every value",
+			filters: &Filters{And: []Filter{cond("amount", "in", []interface{}{1.0, "2"})}},
+			sql:     "`jumlah` IN ?",
+			args:    []interface{}{[]interface{}{int64(1), int64(2)}},
+		},
+		{
+			name:    "BETWEEN coerces both bounds",
+			filters: &Filters{And: []Filter{cond("created", "between", []interface{}{"2024-01-01", "2024-02-01"})}},
+			sql:     "`_created_at` BETWEEN ? AND ?",
+			args:    []interface{}{day("2024-01-01"), day("2024-02-01")},
+		},
+		{
+			name: "OR list is parenthesized after the AND list",
+			filters: &Filters{
+				And: []Filter{cond("active", "=", true)},
+				Or:  []Filter{cond("name", "=", "a"), cond("name", "=", "b")},
+			},
+			sql:  "`aktif` = ? AND (`nama` = ? OR `nama` = ?)",
+			args: []interface{}{true, "a", "b"},
+		},
+		{
+			name: "nested groups",
+			filters: &Filters{Or: []Filter{
+				{And: []Filter{cond("name", "=", "a"), cond("amount", ">", 1.0)}},
+				{And: []Filter{cond("name", "=", "b"), {Or: []Filter{cond("active", "=", true), cond("amount", "IS NULL", nil)}}}},
+			}},
+			sql:  "((`nama` = ? AND `jumlah` > ?) OR (`nama` = ? AND (`aktif` = ? OR `jumlah` IS NULL)))",
+			args: []interface{}{"a", int64(1), "b", true},
+		},
+		{
+			name:    "NOT wraps a condition",
+			filters: &Filters{And: []Filter{nestNot(cond("name", "=", "a"), 1)}},
+			sql:     "NOT (`nama` = ?)",
+			args:    []interface{}{"a"},
+		},
+		{
+			name:    "NOT wraps a group",
+			filters: &Filters{And: []Filter{{Not: &Filter{Or: []Filter{cond("name", "=", "a"), cond("name", "=", "b")}}}}},
+			sql:     "NOT ((`nama` = ? OR `nama` = ?))",
+			args:    []interface{}{"a", "b"},
+		},
+		{
+			name:    "nesting up to the depth limit",
+			filters: &Filters{And: []Filter{nestNot(cond("active", "=", true), maxFilterDepth-1)}},
+			sql:     strings.Repeat("NOT (", maxFilterDepth-1) + "`aktif` = ?" + strings.Repeat(")", maxFilterDepth-1),
+			args:    []interface{}{true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := testSchema.Compile(tt.filters)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sql != tt.sql {
+				t.Errorf("sql = %q, want %q", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tooMany := make([]Filter, maxFilterConditions+1)
+	for i := range tooMany {
+		tooMany[i] = cond("id", "=", float64(i))
+	}
+
+	tests := []struct {
+		name    string
+		filters *Filters
+		fields  []string
+	}{
+		{
+			name:    "unknown column",
+			filters: &Filters{And: []Filter{cond("password", "=", "x")}},
+			fields:  []string{"filters.and[0].columnKey"},
+		},
+		{
+			name:    "operator not allowed on the type",
+			filters: &Filters{And: []Filter{cond("amount", OpContains, "1")}},
+			fields:  []string{"filters.and[0].operator"},
+		},
+		{
+			name:    "unknown operator",
+			filters: &Filters{Or: []Filter{cond("name", "REGEXP", ".*")}},
+			fields:  []string{"filters.or[0].operator"},
+		},
+		{
+			name:    "value of the wrong type",
+			filters: &Filters{And: []Filter{cond("amount", "=", "abc"), cond("created", ">", 3.0), cond("active", "=", "maybe")}},
+			fields:  []string{"filters.and[0].value", "filters.and[1].value", "filters.and[2].value"},
+		},
+		{
+			name:    "fractional int",
+			filters: &Filters{And: []Filter{cond("amount", "=", 1.5)}},
+			fields:  []string{"filters.and[0].value"},
+		},
+		{
+			name:    "IN without a list",
+			filters: &Filters{And: []Filter{cond("amount", OpIn, 1.0), cond("amount", OpNotIn, []interface{}{})}},
+			fields:  []string{"filters.and[0].value", "filters.and[1].value"},
+		},
+		{
+			name:    "IN with a bad value",
+			filters: &Filters{And: []Filter{cond("amount", OpIn, []interface{}{1.0, "x", 3.0})}},
+			fields:  []string{"filters.and[0].value[1]"},
+		},
+		{
+			name:    "IN with too many values",
+			filters: &Filters{And: []Filter{cond("amount", OpIn, make([]interface{}, maxFilterValues+1))}},
+			fields:  []string{"filters.and[0].value"},
+		},
+		{
+			name:    "BETWEEN without two values",
+			filters: &Filters{And: []Filter{cond("amount", OpBetween, []interface{}{1.0}), cond("amount", OpBetween, 1.0)}},
+			fields:  []string{"filters.and[0].value", "filters.and[1].value"},
+		},
+		{
+			name:    "BETWEEN with a bad bound",
+			filters: &Filters{And: []Filter{cond("created", OpBetween, []interface{}{"2024-01-01", "soon"})}},
+			fields:  []string{"filters.and[0].value[1]"},
+		},
+		{
+			name:    "node with both a condition and a group",
+			filters: &Filters{And: []Filter{{ColumnKey: "name", Operator: "=", Value: "a", And: []Filter{cond("id", "=", 1.0)}}}},
+			fields:  []string{"filters.and[0]"},
+		},
+		{
+			name:    "empty node",
+			filters: &Filters{And: []Filter{{}}},
+			fields:  []string{"filters.and[0]"},
+		},
+		{
+			name:    "empty group",
+			filters: &Filters{Or: []Filter{{Or: []Filter{}}}},
+			fields:  []string{"filters.or[0].or"},
+		},
+		{
+			name:    "errors inside nested groups and NOT",
+			filters: &Filters{And: []Filter{{Or: []Filter{cond("id", "=", 1.0), {Not: &Filter{And: []Filter{cond("nope", "=", 1.0)}}}}}}},
+			fields:  []string{"filters.and[0].or[1].not.and[0].columnKey"},
+		},
+		{
+			name:    "nested beyond the depth limit",
+			filters: &Filters{And: []Filter{nestNot(cond("active", "=", true), maxFilterDepth)}},
+			fields:  []string{"filters.and[0]" + strings.Repeat(".not", maxFilterDepth-1)},
+		},
+		{
+			name:    "too many conditions",
+			filters: &Filters{Or: tooMany},
+			fields:  []string{"filters"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := testSchema.Compile(tt.filters)
+			if err == nil {
+				t.Fatalf("expected an error, got %q %v", sql, args)
+			}
+			if fields := validationFields(t, err); !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("fields = %q, want %q", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestWithout(t *testing.T) {
+	byName := cond("name", "eq", "a")
+	byColumn := cond("nama", "contains", "b")
+	byAmount := cond("amount", "gt", 1)
+
+	tests := []struct {
+		name    string
+		filters *Filters
+		field   string
+		want    *Filters
+	}{
+		{name: "no filters", field: "name", want: nil},
+		{name: "unknown field", filters: &Filters{And: []Filter{byName}}, field: "missing", want: &Filters{And: []Filter{byName}}},
+		{
+			name:    "AND entries on the field by name or column",
+			filters: &Filters{And: []Filter{byName, byAmount, byColumn}},
+			field:   "nama",
+			want:    &Filters{And: []Filter{byAmount}},
+		},
+		{
+			name:    "groups and NOT on the field alone",
+			filters: &Filters{And: []Filter{{Or: []Filter{byName, byColumn}}, {Not: &byName}, byAmount}},
+			field:   "name",
+			want:    &Filters{And: []Filter{byAmount}},
+		},
+		{
+			name:    "groups mixing fields are kept",
+			filters: &Filters{And: []Filter{{Or: []Filter{byName, byAmount}}}},
+			field:   "name",
+			want:    &Filters{And: []Filter{{Or: []Filter{byName, byAmount}}}},
+		},
+		{
+			name:    "OR list on the field alone",
+			filters: &Filters{And: []Filter{byAmount}, Or: []Filter{byName, byColumn}},
+			field:   "name",
+			want:    &Filters{And: []Filter{byAmount}},
+		},
+		{
+			name:    "OR list mixing fields is kept whole",
+			filters: &Filters{Or: []Filter{byName, byAmount}},
+			field:   "name",
+			want:    &Filters{Or: []Filter{byName, byAmount}},
+		},
+		{
+			name:    "empty group is kept",
+			filters: &Filters{And: []Filter{{And: []Filter{}}}},
+			field:   "name",
+			want:    &Filters{And: []Filter{{And: []Filter{}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testSchema.Without(tt.filters, tt.field); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Without = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"context"
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// flushInterval limits how often progress is written to the database
+const flushInterval = time.Second
+
+// Progress lets a task report how far it got. Updates are written to the job
+// at most once per flushInterval; each write also picks up cancel requests
+// made on other instances.
+type Progress struct {
+	db     *gorm.DB
+	jobID  string
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	total     int
+	processed int
+	failed    int
+	lastFlush time.Time
+}
+
+func newProgress(db *gorm.DB, jobID string, cancel context.CancelFunc) *Progress {
+	return &Progress{db: db, jobID: jobID, cancel: cancel, lastFlush: time.Now()}
+}
+
+// JobID returns the ID of the job being run
+func (p *Progress) JobID() string {
+	return p.jobID
+}
+
+// SetTotal sets the number of items the job will process, when known
+func (p *Progress) SetTotal(total int) {
+	p.mu.Lock()
+	p.total = total
+	p.mu.Unlock()
+
+	p.flush()
+}
+
+// Add records processed items, failed ones included
+func (p *Progress) Add(processed, failed int) {
+	p.mu.Lock()
+	p.processed += processed
+	p.failed += failed
+	due := time.Since(p.lastFlush) >= flushInterval
+	p.mu.Unlock()
+
+	if due {
+		p.flush()
+	}
+}
+
+// heartbeat flushes the progress every interval until the returned function
+// is called, so that a job busy without reporting progress is neither taken
+// for lost nor blind to cancel requests
+func (p *Progress) heartbeat(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				p.flush()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (p *Progress) flush() {
+	p.mu.Lock()
+	updates := map[string]interface{}{
+		"total_count":     p.total,
+		"processed_count": p.processed,
+		"failed_count":    p.failed,
+	}
+	p.lastFlush = time.Now()
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := p.db.WithContext(ctx).Model(&model.Job{}).Where("id = ?", p.jobID).Updates(updates).Error; err != nil {
+		config.GetSugaredLogger().Warnf("Failed to update progress of job %s: %v", p.jobID, err)
+		return
+	}
+
+	var job model.Job
+	err := p.db.WithContext(ctx).Select("cancel_requested").Where("id = ?", p.jobID).First(&job).Error
+	if err == nil && job.CancelRequested {
+		p.cancel()
+	}
+}
@@ -0,0 +1,435 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"kswi-backend/internal/config"
+	"kswi-backend/internal/model"
+	"kswi-backend/internal/shared/errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrQueueFull is returned by Enqueue when every worker is busy and the queue
+// has no room left
+var ErrQueueFull = fmt.Errorf("job queue is full")
+
+// ErrShuttingDown is returned by Enqueue once Shutdown has started
+var ErrShuttingDown = fmt.Errorf("job manager is shutting down")
+
+// staleJobAfter is how long a running job may go without a heartbeat before
+// it is considered lost (for example after a crash)
+const staleJobAfter = 10 * time.Minute
+
+// heartbeatInterval is how often a running job is written even when its task
+// reports no progress, such as while it waits for a lock
+const heartbeatInterval = time.Minute
+
+// Task is the work of a job. It must return soon after ctx is cancelled. The
+// result is stored as JSON on the job.
+type Task func(ctx context.Context, progress *Progress) (result interface{}, err error)
+
+// OnFinish is called after a task returned, with the final job status, so the
+// caller can clean up resources the task owned
+type OnFinish func(ctx context.Context, job *model.Job)
+
+// Spec describes a job to enqueue
+type Spec struct {
+	Type      string
+	CreatedBy int
+	Task      Task
+	OnFinish  OnFinish
+}
+
+type queuedJob struct {
+	id   string
+	spec Spec
+}
+
+// Manager runs jobs on a fixed number of workers and records their state in
+// the jobs table
+type Manager struct {
+	db       *gorm.DB
+	queue    chan queuedJob
+	instance string
+	recovers map[string]OnFinish
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	running map[string]context.CancelFunc
+}
+
+var manager *Manager
+
+// Init starts the shared job manager
+func Init() error {
+	cfg := config.Get().Worker
+	logger := config.GetSugaredLogger()
+
+	concurrency := cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	instance := cfg.InstanceID
+	if instance == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("worker.instance_id is not set and the hostname is unknown: %w", err)
+		}
+		instance = hostname
+	}
+
+	m := NewManager(config.GetDB(), concurrency, cfg.QueueSize)
+	m.instance = instance
+
+	manager = m
+	logger.Infof("✅ Job workers started (%d workers)", concurrency)
+	return nil
+}
+
+// Get returns the shared job manager
+func Get() *Manager {
+	return manager
+}
+
+// Recover resolves the jobs left unfinished by the shared job manager, see
+// Manager.Recover
+func Recover(ctx context.Context) error {
+	if manager == nil {
+		return nil
+	}
+	return manager.Recover(ctx)
+}
+
+// Shutdown stops the shared job manager, see Manager.Shutdown
+func Shutdown(ctx context.Context) error {
+	if manager == nil {
+		return nil
+	}
+	return manager.Shutdown(ctx)
+}
+
+// NewManager creates a manager and starts its workers
+func NewManager(db *gorm.DB, concurrency, queueSize int) *Manager {
+	ctx, stop := context.WithCancel(context.Background())
+
+	m := &Manager{
+		db:       db,
+		queue:    make(chan queuedJob, queueSize),
+		ctx:      ctx,
+		stop:     stop,
+		running:  make(map[string]context.CancelFunc),
+		recovers: make(map[string]OnFinish),
+	}
+
+	for i := 0; i < concurrency; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+
+	return m
+}
+
+// Enqueue records a new job and queues it for the workers
+func (m *Manager) Enqueue(ctx context.Context, spec Spec) (*model.Job, error) {
+	job := &model.Job{
+		ID:        uuid.NewString(),
+		Type:      spec.Type,
+		Status:    model.JobQueued,
+		CreatedBy: spec.CreatedBy,
+		Instance:  m.instance,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrShuttingDown
+	}
+
+	if err := m.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, err
+	}
+
+	select {
+	case m.queue <- queuedJob{id: job.ID, spec: spec}:
+		return job, nil
+	default:
+		m.finish(job.ID, spec, model.JobFailed, nil, ErrQueueFull)
+		return nil, ErrQueueFull
+	}
+}
+
+// Find returns the job with the given ID, or nil when it does not exist
+func (m *Manager) Find(ctx context.Context, id string) (*model.Job, error) {
+	var job model.Job
+	err := m.db.WithContext(ctx).Where("id = ?", id).First(&job).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Cancel asks a queued or running job to stop. The flag is stored so that a
+// job running on another instance stops at its next progress update.
+func (m *Manager) Cancel(ctx context.Context, id string) error {
+	err := m.db.WithContext(ctx).Model(&model.Job{}).
+		Where("id = ? AND status IN ?", id, []string{model.JobQueued, model.JobRunning}).
+		Update("cancel_requested", true).Error
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	cancel, ok := m.running[id]
+	m.mu.Unlock()
+	if ok {
+		cancel()
+	}
+
+	return nil
+}
+
+// Shutdown stops accepting jobs and waits for running jobs to finish. When ctx
+// expires first, running jobs are cancelled and waited for; jobs still queued
+// are marked as failed.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	close(m.queue)
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		m.stop()
+		<-done
+		return fmt.Errorf("jobs interrupted: %w", ctx.Err())
+	}
+}
+
+func (m *Manager) work() {
+	defer m.wg.Done()
+
+	for job := range m.queue {
+		if m.ctx.Err() != nil {
+			// Shutting down: drain the queue without starting anything
+			m.finish(job.id, job.spec, model.JobFailed, nil, fmt.Errorf("server shut down before the job started"))
+			continue
+		}
+		m.run(job)
+	}
+}
+
+func (m *Manager) run(job queuedJob) {
+	logger := config.GetSugaredLogger()
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+
+	now := time.Now()
+	res := m.db.Model(&model.Job{}).
+		Where("id = ? AND status = ? AND cancel_requested = ?", job.id, model.JobQueued, false).
+		Updates(map[string]interface{}{"status": model.JobRunning, "started_at": now})
+	if res.Error != nil {
+		m.finish(job.id, job.spec, model.JobFailed, nil, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		// Cancelled while queued
+		m.finish(job.id, job.spec, model.JobCancelled, nil, nil)
+		return
+	}
+
+	m.mu.Lock()
+	m.running[job.id] = cancel
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, job.id)
+		m.mu.Unlock()
+	}()
+
+	progress := newProgress(m.db, job.id, cancel)
+	stopHeartbeat := progress.heartbeat(heartbeatInterval)
+
+	result, err := func() (result interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorf("Job %s (%s) panicked: %v", job.id, job.spec.Type, r)
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		return job.spec.Task(ctx, progress)
+	}()
+
+	stopHeartbeat()
+	progress.flush()
+
+	status := model.JobCompleted
+	switch {
+	case err == nil:
+	case m.ctx.Err() != nil:
+		status, err = model.JobFailed, fmt.Errorf("interrupted by server shutdown")
+	case ctx.Err() != nil:
+		status, err = model.JobCancelled, nil
+	default:
+		status = model.JobFailed
+	}
+
+	m.finish(job.id, job.spec, status, result, err)
+}
+
+// finish stores the final state of a job and runs its OnFinish hook. A job
+// Recover already marked as failed keeps that status; the hook still runs,
+// with the stored job, to clean up after the task.
+func (m *Manager) finish(id string, spec Spec, status string, result interface{}, cause error) {
+	logger := config.GetSugaredLogger()
+
+	updates := map[string]interface{}{
+		"status":      status,
+		"finished_at": time.Now(),
+	}
+
+	if cause != nil {
+		updates["error"] = errorMessage(cause)
+	}
+
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			logger.Errorf("Failed to encode result of job %s: %v", id, err)
+		} else {
+			updates["result"] = data
+		}
+	}
+
+	// Use a fresh context: the job context may already be cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res := m.db.WithContext(ctx).Model(&model.Job{}).
+		Where("id = ? AND status IN ?", id, []string{model.JobQueued, model.JobRunning}).
+		Updates(updates)
+	switch {
+	case res.Error != nil:
+		logger.Errorf("Failed to finish job %s: %v", id, res.Error)
+	case res.RowsAffected == 0:
+		logger.Warnf("⚠️  Job %s (%s) ended as %s but was already resolved", id, spec.Type, status)
+	}
+
+	if spec.OnFinish != nil {
+		job, err := m.Find(ctx, id)
+		if err != nil || job == nil {
+			logger.Errorf("Failed to reload job %s: %v", id, err)
+			return
+		}
+		spec.OnFinish(ctx, job)
+	}
+}
+
+// OnRecover registers the hook run for jobs of a type that Recover marks as
+// failed, in place of the OnFinish hook their lost process never ran
+func (m *Manager) OnRecover(jobType string, fn OnFinish) {
+	m.mu.Lock()
+	m.recovers[jobType] = fn
+	m.mu.Unlock()
+}
+
+// Recover marks as failed the jobs whose process is gone: every queued or
+// running job of this instance, which a fresh process cannot be running yet,
+// and running jobs of other instances whose heartbeat stopped. Queued jobs of
+// other instances are left to them, as nothing tells a live queue from a
+// dead one. Each job gets the hook registered for its type. It must run
+// before this instance takes new jobs.
+func (m *Manager) Recover(ctx context.Context) error {
+	logger := config.GetSugaredLogger()
+
+	var jobs []model.Job
+	err := m.db.WithContext(ctx).
+		Where("status IN ? AND instance = ?", []string{model.JobQueued, model.JobRunning}, m.instance).
+		Or("status = ? AND updated_at < ?", model.JobRunning, time.Now().Add(-staleJobAfter)).
+		Find(&jobs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		message := "interrupted: the server stopped while the job was running"
+		if job.Status == model.JobQueued {
+			message = "interrupted: the server stopped before the job started"
+		}
+
+		now := time.Now()
+		res := m.db.WithContext(ctx).Model(&model.Job{}).
+			Where("id = ? AND status = ?", job.ID, job.Status).
+			Updates(map[string]interface{}{
+				"status":      model.JobFailed,
+				"error":       message,
+				"finished_at": now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// Another instance moved it on meanwhile
+			continue
+		}
+		logger.Warnf("⚠️  Recovered job %s (%s) left %s", job.ID, job.Type, job.Status)
+
+		m.mu.Lock()
+		fn := m.recovers[job.Type]
+		m.mu.Unlock()
+		if fn != nil {
+			job.Status, job.Error, job.FinishedAt = model.JobFailed, &message, &now
+			fn(ctx, &job)
+		}
+	}
+
+	return nil
+}
+
+// errorMessage renders an error for the job record, spelling out validation details
+func errorMessage(err error) string {
+	var appErr *errors.AppError
+	if !errors.As(err, &appErr) {
+		return err.Error()
+	}
+
+	details, ok := appErr.Details.([]errors.ValidationError)
+	if !ok || len(details) == 0 {
+		return appErr.Message
+	}
+
+	parts := make([]string, len(details))
+	for i, d := range details {
+		parts[i] = fmt.Sprintf("%s: %s", d.Field, d.Message)
+	}
+	return fmt.Sprintf("%s (%s)", appErr.Message, strings.Join(parts, "; "))
+}